@@ -0,0 +1,147 @@
+// Package apitest provides an in-memory stand-in for the Nexoan Update and Query APIs.
+// It is intended for tests that exercise api.Client without a running Nexoan instance: it serves a
+// memgraph.Graph over HTTP, and can fail requests on demand and count them.
+//
+// Each Server owns its own graph, so tests that need isolation can start a fresh one:
+//
+//	srv := apitest.NewServer()
+//	defer srv.Close()
+//	client, err := api.NewClient(srv.UpdateURL(), srv.QueryURL())
+package apitest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"orgchart_nexoan/api/memgraph"
+)
+
+// Directions reported for relationships returned by the relations endpoint
+const (
+	DirectionOutgoing = memgraph.DirectionOutgoing
+	DirectionIncoming = memgraph.DirectionIncoming
+)
+
+// Fault makes the server answer matching requests with an error status instead of the normal response
+type Fault struct {
+	// Method and PathPrefix select the requests to fail; empty values match everything
+	Method     string
+	PathPrefix string
+	// Status is the status code returned to the client
+	Status int
+	// Count is the number of matching requests to fail
+	Count int
+	// AfterApply applies the request to the graph before answering with Status, simulating a write
+	// that landed but whose response was lost
+	AfterApply bool
+}
+
+// Server is an httptest-based fake of the Nexoan Update API (/entities) and Query API (/v1/entities)
+type Server struct {
+	*httptest.Server
+
+	graph *memgraph.Graph
+
+	mu       sync.Mutex
+	faults   []*Fault
+	requests map[string]int
+}
+
+// NewServer starts a new fake Nexoan server with an empty graph
+func NewServer() *Server {
+	s := &Server{graph: memgraph.New(), requests: map[string]int{}}
+	s.Server = httptest.NewServer(s.withFaults(s.graph))
+	return s
+}
+
+// UpdateURL returns the Update API endpoint to pass to api.NewClient
+func (s *Server) UpdateURL() string {
+	return s.URL + "/entities"
+}
+
+// QueryURL returns the Query API endpoint to pass to api.NewClient
+func (s *Server) QueryURL() string {
+	return s.URL + "/v1/entities"
+}
+
+// Reset discards every entity and relationship held by the server, its faults and its request counts
+func (s *Server) Reset() {
+	s.graph.Reset()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+	s.requests = map[string]int{}
+}
+
+// InjectFault queues a fault. Faults are consumed in the order they were injected.
+func (s *Server) InjectFault(fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	injected := fault
+	s.faults = append(s.faults, &injected)
+}
+
+// RequestCount returns how many requests the server received for the given method and path prefix.
+// Empty values match everything.
+func (s *Server) RequestCount(method, pathPrefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for key, n := range s.requests {
+		m, path, _ := strings.Cut(key, " ")
+		if (method == "" || method == m) && strings.HasPrefix(path, pathPrefix) {
+			count += n
+		}
+	}
+	return count
+}
+
+// nextFault counts the request and returns the fault it should trigger, if any
+func (s *Server) nextFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[r.Method+" "+r.URL.Path]++
+	for i, fault := range s.faults {
+		if fault.Method != "" && fault.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, fault.PathPrefix) {
+			continue
+		}
+		fault.Count--
+		if fault.Count <= 0 {
+			s.faults = append(s.faults[:i], s.faults[i+1:]...)
+		}
+		return fault
+	}
+	return nil
+}
+
+func (s *Server) withFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := s.nextFault(r)
+		if fault == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if fault.AfterApply {
+			next.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(fault.Status)
+		json.NewEncoder(w).Encode(map[string]string{"error": "injected fault"})
+	})
+}
+
+// EntityCount returns the number of entities in the graph
+func (s *Server) EntityCount() int {
+	return s.graph.EntityCount()
+}
+
+// RelationshipCount returns the number of relationships in the graph
+func (s *Server) RelationshipCount() int {
+	return s.graph.RelationshipCount()
+}
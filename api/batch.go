@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// BatchScope controls how long relationship writes are held back before they are sent
+type BatchScope int
+
+const (
+	// BatchOff sends every relationship write as its own UpdateEntity call
+	BatchOff BatchScope = iota
+	// BatchPerTransaction sends the relationship writes of a transaction when it completes
+	BatchPerTransaction
+	// BatchPerFile sends the relationship writes of a CSV file once the loader is done with its last
+	// transaction, so a load sends at most one batch per file
+	BatchPerFile
+)
+
+// RelationshipError reports a relationship write that failed when a batch was flushed
+type RelationshipError struct {
+	EntityID       string
+	RelationshipID string
+	Err            error
+}
+
+func (e *RelationshipError) Error() string {
+	return fmt.Sprintf("failed to write relationship %s of entity %s: %v", e.RelationshipID, e.EntityID, e.Err)
+}
+
+func (e *RelationshipError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError collects the relationship writes that failed when a batch was flushed.
+// The other writes of the batch were applied.
+type BatchError struct {
+	Failures []*RelationshipError
+}
+
+func (e *BatchError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, failure.Error())
+	}
+	return fmt.Sprintf("%d relationship writes failed: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// Unwrap lets errors.Is and errors.As look at every failed relationship write
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		errs = append(errs, failure)
+	}
+	return errs
+}
+
+// relationshipBatch holds relationship-only UpdateEntity calls back and merges those for the same
+// entity, so they can be sent as one call per entity. Entities are flushed in the order they were
+// first written to and the relationships of an entity keep their order.
+//
+// Relation reads made through the client see the pending writes: they are laid over the
+// relations returned by the Query API (see overlay), so reads do not force a flush.
+type relationshipBatch struct {
+	mu      sync.Mutex
+	scope   BatchScope
+	order   []string
+	pending map[string][]models.RelationshipEntry
+}
+
+func newRelationshipBatch(scope BatchScope) *relationshipBatch {
+	return &relationshipBatch{
+		scope:   scope,
+		pending: make(map[string][]models.RelationshipEntry),
+	}
+}
+
+// SetBatchScope turns batching of relationship writes on or off. Turning it off sends whatever is
+// still pending, so call FlushRelationshipsContext first to see its errors.
+func (c *Client) SetBatchScope(scope BatchScope) error {
+	if scope == BatchOff {
+		err := c.FlushRelationshipsContext(context.Background())
+		c.batch = nil
+		return err
+	}
+	if c.batch == nil {
+		c.batch = newRelationshipBatch(scope)
+		return nil
+	}
+	c.batch.mu.Lock()
+	c.batch.scope = scope
+	c.batch.mu.Unlock()
+	return nil
+}
+
+// BatchScope returns the current batching scope of relationship writes
+func (c *Client) BatchScope() BatchScope {
+	if c.batch == nil {
+		return BatchOff
+	}
+	c.batch.mu.Lock()
+	defer c.batch.mu.Unlock()
+	return c.batch.scope
+}
+
+// FlushRelationships sends the pending relationship writes, one UpdateEntity call per entity
+func (c *Client) FlushRelationships() error {
+	return c.FlushRelationshipsContext(context.Background())
+}
+
+// FlushRelationshipsContext sends the pending relationship writes, one UpdateEntity call per entity.
+// When a merged call fails its relationships are retried one by one, and a BatchError lists those
+// that still failed.
+func (c *Client) FlushRelationshipsContext(ctx context.Context) error {
+	if c.batch == nil {
+		return nil
+	}
+	order, pending := c.batch.take()
+
+	var failures []*RelationshipError
+	for _, entityID := range order {
+		entries := pending[entityID]
+		_, err := c.updateEntity(ctx, entityID, &models.Entity{ID: entityID, Relationships: entries})
+		if err == nil {
+			continue
+		}
+		if len(entries) == 1 {
+			failures = append(failures, &RelationshipError{EntityID: entityID, RelationshipID: relationshipEntryID(entries[0]), Err: err})
+			continue
+		}
+
+		// Find out which relationships failed, skipping any the merged call managed to apply
+		for _, entry := range entries {
+			single := []models.RelationshipEntry{entry}
+			if landed := c.relationshipsLanded(entityID, single); landed != nil {
+				if done, err := landed(ctx); err == nil && done {
+					continue
+				}
+			}
+			if _, err := c.updateEntity(ctx, entityID, &models.Entity{ID: entityID, Relationships: single}); err != nil {
+				failures = append(failures, &RelationshipError{EntityID: entityID, RelationshipID: relationshipEntryID(entry), Err: err})
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &BatchError{Failures: failures}
+	}
+	return nil
+}
+
+// flushBatchAt flushes the pending writes when the given scope ends
+func (c *Client) flushBatchAt(ctx context.Context, scope BatchScope) error {
+	if c.batch == nil || c.BatchScope() != scope {
+		return nil
+	}
+	return c.FlushRelationshipsContext(ctx)
+}
+
+func relationshipEntryID(entry models.RelationshipEntry) string {
+	if entry.Value.ID != "" {
+		return entry.Value.ID
+	}
+	return entry.Key
+}
+
+// stageable reports whether an update only writes relationships and can therefore be batched
+func stageable(entity *models.Entity) bool {
+	if name, ok := entity.Name.Value.(string); entity.Name.Value != nil && (!ok || name != "") {
+		return false
+	}
+	return len(entity.Relationships) > 0 && entity.Kind == (models.Kind{}) && entity.Created == "" &&
+		entity.Terminated == "" && len(entity.Metadata) == 0 && len(entity.Attributes) == 0
+}
+
+// stage records the relationships of a relationship-only update of entityID
+func (b *relationshipBatch) stage(entityID string, entries []models.RelationshipEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.pending[entityID]; !ok {
+		b.order = append(b.order, entityID)
+	}
+	b.pending[entityID] = append(b.pending[entityID], entries...)
+}
+
+// canOverlay reports whether pending writes can be laid over the result of query. A pending termination
+// can make a relationship match an EndTime filter that the Query API did not return it for.
+func (b *relationshipBatch) canOverlay(query *models.Relationship) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.order) == 0 || query == nil || query.EndTime == ""
+}
+
+// overlay applies the pending writes to relations, the Query API's answer to query for entityID.
+// Writes to relationships already in relations update their times; writes that create
+// relationships of entityID, in either direction, add them when they match query.
+func (b *relationshipBatch) overlay(entityID string, query *models.Relationship, relations []models.Relationship) []models.Relationship {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.order) == 0 {
+		return relations
+	}
+
+	type view struct {
+		rel     models.Relationship
+		changed bool
+	}
+	views := make([]*view, 0, len(relations))
+	byID := make(map[string]*view, len(relations))
+	for _, rel := range relations {
+		v := &view{rel: rel}
+		views = append(views, v)
+		byID[rel.ID] = v
+	}
+
+	for _, parentID := range b.order {
+		for _, entry := range b.pending[parentID] {
+			written := entry.Value
+			relationshipID := relationshipEntryID(entry)
+
+			if v, ok := byID[relationshipID]; ok {
+				if written.StartTime != "" {
+					v.rel.StartTime = written.StartTime
+				}
+				if written.EndTime != "" {
+					v.rel.EndTime = written.EndTime
+				}
+				v.changed = true
+				continue
+			}
+
+			if written.RelatedEntityID == "" {
+				// Only updates an existing relationship, which is not one of entityID's
+				continue
+			}
+			var rel models.Relationship
+			switch {
+			case parentID == entityID:
+				rel = models.Relationship{RelatedEntityID: written.RelatedEntityID, Direction: DirectionOutgoing}
+			case written.RelatedEntityID == entityID:
+				rel = models.Relationship{RelatedEntityID: parentID, Direction: DirectionIncoming}
+			default:
+				continue
+			}
+			rel.ID = relationshipID
+			rel.Name = written.Name
+			rel.StartTime = written.StartTime
+			rel.EndTime = written.EndTime
+
+			v := &view{rel: rel, changed: true}
+			views = append(views, v)
+			byID[relationshipID] = v
+		}
+	}
+
+	result := make([]models.Relationship, 0, len(views))
+	for _, v := range views {
+		if v.changed && query != nil && !matchesRelationship(v.rel, *query) {
+			continue
+		}
+		result = append(result, v.rel)
+	}
+	return result
+}
+
+// matchesRelationship applies the non-empty fields of filter to rel the way the Query API does
+func matchesRelationship(rel, filter models.Relationship) bool {
+	if filter.RelatedEntityID != "" && filter.RelatedEntityID != rel.RelatedEntityID {
+		return false
+	}
+	if filter.Name != "" && filter.Name != rel.Name {
+		return false
+	}
+	if filter.ID != "" && filter.ID != rel.ID {
+		return false
+	}
+	if filter.StartTime != "" && filter.StartTime != rel.StartTime {
+		return false
+	}
+	if filter.EndTime != "" && filter.EndTime != rel.EndTime {
+		return false
+	}
+	if filter.Direction != "" && !strings.EqualFold(filter.Direction, rel.Direction) {
+		return false
+	}
+	if filter.ActiveAt != "" {
+		if rel.StartTime > filter.ActiveAt {
+			return false
+		}
+		if rel.EndTime != "" && rel.EndTime <= filter.ActiveAt {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPending reports whether relationship writes are held back; a nil batch holds none
+func (b *relationshipBatch) hasPending() bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.order) > 0
+}
+
+// take empties the batch and returns what was pending
+func (b *relationshipBatch) take() ([]string, map[string][]models.RelationshipEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	order, pending := b.order, b.pending
+	b.order = nil
+	b.pending = make(map[string][]models.RelationshipEntry)
+	return order, pending
+}
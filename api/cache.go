@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// CacheStats reports how often the lookup cache answered a query without calling the API
+type CacheStats struct {
+	Hits          int
+	Misses        int
+	Invalidations int
+}
+
+// lookupCache keeps the results of the lookups every transaction repeats: the government node ID,
+// presidents by name, search-by-ID results and relation lists. Writes made through the client
+// invalidate the affected entries, so the cache stays correct as long as nothing else writes to
+// the same graph while the client is in use.
+type lookupCache struct {
+	mu           sync.Mutex
+	stats        CacheStats
+	governmentID string
+	presidents   map[string]*models.Entity
+	entities     map[string]models.SearchResult
+	// relations is keyed by entity ID and then by the JSON encoding of the relationship filter
+	relations map[string]map[string][]models.Relationship
+}
+
+func newLookupCache() *lookupCache {
+	return &lookupCache{
+		presidents: make(map[string]*models.Entity),
+		entities:   make(map[string]models.SearchResult),
+		relations:  make(map[string]map[string][]models.Relationship),
+	}
+}
+
+// SetCacheEnabled turns the lookup cache on or off. Turning it off drops everything cached so far.
+func (c *Client) SetCacheEnabled(enabled bool) {
+	if !enabled {
+		c.cache = nil
+		return
+	}
+	if c.cache == nil {
+		c.cache = newLookupCache()
+	}
+}
+
+// ClearCache drops every cached lookup but keeps the cache enabled and its statistics
+func (c *Client) ClearCache() {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	c.cache.clear()
+}
+
+// CacheStats returns the hit and miss counts of the lookup cache. It is zero when the cache is disabled.
+func (c *Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	return c.cache.stats
+}
+
+func (lc *lookupCache) clear() {
+	lc.governmentID = ""
+	lc.presidents = make(map[string]*models.Entity)
+	lc.entities = make(map[string]models.SearchResult)
+	lc.relations = make(map[string]map[string][]models.Relationship)
+	lc.stats.Invalidations++
+}
+
+func (lc *lookupCache) count(hit bool) {
+	if hit {
+		lc.stats.Hits++
+	} else {
+		lc.stats.Misses++
+	}
+}
+
+func (lc *lookupCache) getGovernmentID() (string, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.count(lc.governmentID != "")
+	return lc.governmentID, lc.governmentID != ""
+}
+
+func (lc *lookupCache) setGovernmentID(id string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.governmentID = id
+}
+
+func (lc *lookupCache) getPresident(name string) (*models.Entity, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	president, ok := lc.presidents[name]
+	lc.count(ok)
+	if !ok {
+		return nil, false
+	}
+	entity := *president
+	return &entity, true
+}
+
+func (lc *lookupCache) setPresident(name string, president *models.Entity) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	entity := *president
+	lc.presidents[name] = &entity
+}
+
+// isIDLookup reports whether the criteria only ask for an entity by ID, the only searches that are cached
+func isIDLookup(criteria *models.SearchCriteria) bool {
+	return criteria != nil && criteria.ID != "" && criteria.Kind == nil && criteria.Name == "" &&
+		criteria.Created == "" && criteria.Terminated == ""
+}
+
+func (lc *lookupCache) getEntity(id string) ([]models.SearchResult, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	result, ok := lc.entities[id]
+	lc.count(ok)
+	if !ok {
+		return nil, false
+	}
+	return []models.SearchResult{result}, true
+}
+
+// setEntity caches a search-by-ID result. Empty results are not cached because the entity may be created next.
+func (lc *lookupCache) setEntity(id string, results []models.SearchResult) {
+	if len(results) != 1 {
+		return
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.entities[id] = results[0]
+}
+
+func relationsKey(query *models.Relationship) string {
+	key, _ := json.Marshal(query)
+	return string(key)
+}
+
+func (lc *lookupCache) getRelations(entityID string, query *models.Relationship) ([]models.Relationship, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	relations, ok := lc.relations[entityID][relationsKey(query)]
+	lc.count(ok)
+	if !ok {
+		return nil, false
+	}
+	return append([]models.Relationship(nil), relations...), true
+}
+
+func (lc *lookupCache) setRelations(entityID string, query *models.Relationship, relations []models.Relationship) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.relations[entityID] == nil {
+		lc.relations[entityID] = make(map[string][]models.Relationship)
+	}
+	lc.relations[entityID][relationsKey(query)] = append([]models.Relationship(nil), relations...)
+}
+
+// invalidateEntity drops what a create or update of entity may have made stale: its search result,
+// its relation lists and those of every entity on the other side of the written relationships,
+// and any cached relation list that contains one of the written relationship IDs.
+func (lc *lookupCache) invalidateEntity(entity *models.Entity) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.stats.Invalidations++
+
+	delete(lc.entities, entity.ID)
+	if entity.Kind.Minor == "government" {
+		lc.governmentID = ""
+	}
+	for name, president := range lc.presidents {
+		if president.ID == entity.ID {
+			delete(lc.presidents, name)
+		}
+	}
+	if len(entity.Relationships) == 0 {
+		return
+	}
+
+	delete(lc.relations, entity.ID)
+	relationshipIDs := make(map[string]bool)
+	for _, entry := range entity.Relationships {
+		if entry.Value.Name == "AS_PRESIDENT" {
+			lc.presidents = make(map[string]*models.Entity)
+		}
+		if entry.Value.RelatedEntityID != "" {
+			delete(lc.relations, entry.Value.RelatedEntityID)
+		}
+		relationshipIDs[entry.Key] = true
+		relationshipIDs[entry.Value.ID] = true
+	}
+	delete(relationshipIDs, "")
+
+	for entityID, queries := range lc.relations {
+		for key, relations := range queries {
+			for _, rel := range relations {
+				if relationshipIDs[rel.ID] {
+					delete(queries, key)
+					break
+				}
+			}
+		}
+		if len(queries) == 0 {
+			delete(lc.relations, entityID)
+		}
+	}
+}
+
+// invalidateAll drops every cached lookup, used after deletes whose effects are not known locally
+func (lc *lookupCache) invalidateAll() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.clear()
+}
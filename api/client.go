@@ -1,56 +1,119 @@
 package api
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"time"
 
 	"orgchart_nexoan/models"
 )
 
 // Client represents the API client
 type Client struct {
-	updateURL  string
-	queryURL   string
-	httpClient *http.Client
+	updateURL   string
+	queryURL    string
+	httpClient  *http.Client
+	headers     http.Header
+	logger      Logger
+	retryPolicy RetryPolicy
+	cache       *lookupCache
+	batch       *relationshipBatch
+	handlers    *HandlerRegistry
+	journalMode JournalMode
+	// dependencyPolicy says what loading a data directory does with ordering dependencies
+	dependencyPolicy DependencyPolicy
+	// journal records the writes of the load in progress, if it keeps a journal
+	journal *loadJournal
+	// plan records the writes of the load being planned by PlanTransactions
+	plan *planRecorder
+	// tally counts the writes of the manifest step being run
+	tally *loadTally
+	// president overrides the president named by the path of the transactions loaded, see SetPresident
+	president string
 }
 
-// NewClient creates a new API client
-func NewClient(updateURL, queryURL string) *Client {
-	return &Client{
-		updateURL: updateURL,
-		queryURL:  queryURL,
-		httpClient: &http.Client{
-			Timeout: time.Second * 30,
-		},
+// NewClient creates a new API client. Options such as WithBearerToken or WithTLSConfig
+// configure authentication and transport; without them requests time out after 30s. It fails
+// when the options cannot be combined.
+func NewClient(updateURL, queryURL string, opts ...Option) (*Client, error) {
+	options := &clientOptions{headers: make(http.Header)}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	logger := options.logger
+	if logger == nil {
+		logger = defaultLogger()
+	}
+
+	handlers := options.handlers
+	if handlers == nil {
+		handlers = BuiltinHandlers()
 	}
+
+	httpClient, err := options.buildHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	return &Client{
+		updateURL:   updateURL,
+		queryURL:    queryURL,
+		httpClient:  httpClient,
+		headers:     options.headers,
+		logger:      logger,
+		retryPolicy: DefaultRetryPolicy(),
+		handlers:    handlers,
+	}, nil
 }
 
 // CreateEntity creates a new entity
 func (c *Client) CreateEntity(entity *models.Entity) (*models.Entity, error) {
+	return c.CreateEntityContext(context.Background(), entity)
+}
+
+// CreateEntityContext creates a new entity, aborting the request when ctx is done
+func (c *Client) CreateEntityContext(ctx context.Context, entity *models.Entity) (*models.Entity, error) {
 	jsonData, err := json.Marshal(entity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		c.updateURL,
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	// A retried create counts as done when an entity with the same ID is already there
+	landed := func(ctx context.Context) (bool, error) {
+		results, err := c.searchEntities(ctx, &models.SearchCriteria{ID: entity.ID})
+		return len(results) > 0, err
+	}
+	if entity.ID == "" {
+		landed = nil
+	}
+	if c.cache != nil {
+		defer c.cache.invalidateEntity(entity)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, c.updateURL, jsonData, landed)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create entity: %w", err)
 	}
+	if resp == nil {
+		c.journal.recordEntity(entity.ID)
+		c.plan.recordEntity(entity)
+		c.tally.recordEntity()
+		createdEntity := *entity
+		return &createdEntity, nil
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newHTTPError(resp)
 	}
+	c.journal.recordEntity(entity.ID)
+	c.plan.recordEntity(entity)
+	c.tally.recordEntity()
 
 	var createdEntity models.Entity
 	if err := json.NewDecoder(resp.Body).Decode(&createdEntity); err != nil {
@@ -62,6 +125,38 @@ func (c *Client) CreateEntity(entity *models.Entity) (*models.Entity, error) {
 
 // UpdateEntity updates an existing entity
 func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity, error) {
+	return c.UpdateEntityContext(context.Background(), id, entity)
+}
+
+// UpdateEntityContext updates an existing entity, aborting the request when ctx is done.
+// When batching is on, updates that only write relationships are held back until the batch is flushed.
+func (c *Client) UpdateEntityContext(ctx context.Context, id string, entity *models.Entity) (*models.Entity, error) {
+	if c.batch != nil {
+		if stageable(entity) {
+			c.batch.stage(id, entity.Relationships)
+			c.journal.recordRelationships(entity.Relationships)
+			c.plan.recordRelationships(id, entity.Relationships)
+			c.tally.recordRelationships(entity.Relationships)
+			stagedEntity := *entity
+			return &stagedEntity, nil
+		}
+		// Keep the order of writes: anything held back goes out before this update
+		if err := c.FlushRelationshipsContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	updatedEntity, err := c.updateEntity(ctx, id, entity)
+	if err != nil {
+		return nil, err
+	}
+	c.journal.recordRelationships(entity.Relationships)
+	c.plan.recordRelationships(id, entity.Relationships)
+	c.tally.recordRelationships(entity.Relationships)
+	return updatedEntity, nil
+}
+
+// updateEntity sends an update to the Update API, bypassing the relationship batch
+func (c *Client) updateEntity(ctx context.Context, id string, entity *models.Entity) (*models.Entity, error) {
 	jsonData, err := json.Marshal(entity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal entity: %w", err)
@@ -70,24 +165,24 @@ func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity,
 	// URL encode the entity ID to handle special characters like slashes
 	encodedID := url.QueryEscape(id)
 
-	req, err := http.NewRequest(
-		http.MethodPut,
-		fmt.Sprintf("%s/%s", c.updateURL, encodedID),
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if c.cache != nil {
+		invalidated := *entity
+		invalidated.ID = id
+		defer c.cache.invalidateEntity(&invalidated)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(ctx, http.MethodPut, fmt.Sprintf("%s/%s", c.updateURL, encodedID), jsonData, c.relationshipsLanded(id, entity.Relationships))
 	if err != nil {
 		return nil, fmt.Errorf("failed to update entity: %w", err)
 	}
+	if resp == nil {
+		updatedEntity := *entity
+		return &updatedEntity, nil
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newHTTPError(resp)
 	}
 
 	var updatedEntity models.Entity
@@ -100,23 +195,35 @@ func (c *Client) UpdateEntity(id string, entity *models.Entity) (*models.Entity,
 
 // DeleteEntity deletes an entity
 func (c *Client) DeleteEntity(id string) error {
-	req, err := http.NewRequest(
-		http.MethodDelete,
-		fmt.Sprintf("%s/%s", c.updateURL, id),
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+	return c.DeleteEntityContext(context.Background(), id)
+}
+
+// DeleteEntityContext deletes an entity, aborting the request when ctx is done
+func (c *Client) DeleteEntityContext(ctx context.Context, id string) error {
+	if err := c.FlushRelationshipsContext(ctx); err != nil {
+		return err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	// A retried delete counts as done when the entity is gone
+	landed := func(ctx context.Context) (bool, error) {
+		results, err := c.searchEntities(ctx, &models.SearchCriteria{ID: id})
+		return err == nil && len(results) == 0, err
+	}
+	if c.cache != nil {
+		defer c.cache.invalidateAll()
+	}
+
+	resp, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", c.updateURL, url.QueryEscape(id)), nil, landed)
 	if err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
+	if resp == nil {
+		return nil
+	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return newHTTPError(resp)
 	}
 
 	return nil
@@ -124,19 +231,22 @@ func (c *Client) DeleteEntity(id string) error {
 
 // GetRootEntities gets root entity IDs of a given kind
 func (c *Client) GetRootEntities(kind string) ([]string, error) {
+	return c.GetRootEntitiesContext(context.Background(), kind)
+}
+
+// GetRootEntitiesContext gets root entity IDs of a given kind, aborting the request when ctx is done
+func (c *Client) GetRootEntitiesContext(ctx context.Context, kind string) ([]string, error) {
 	params := url.Values{}
 	params.Add("kind", kind)
 
-	resp, err := c.httpClient.Get(
-		fmt.Sprintf("%s/root?%s", c.queryURL, params.Encode()),
-	)
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/root?%s", c.queryURL, params.Encode()), nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get root entities: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newHTTPError(resp)
 	}
 
 	var response models.RootEntitiesResponse
@@ -149,23 +259,44 @@ func (c *Client) GetRootEntities(kind string) ([]string, error) {
 
 // SearchEntities searches for entities based on criteria
 func (c *Client) SearchEntities(criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	return c.SearchEntitiesContext(context.Background(), criteria)
+}
+
+// SearchEntitiesContext searches for entities based on criteria, aborting the request when ctx is done.
+// Searches by ID alone are answered from the lookup cache when it is enabled.
+func (c *Client) SearchEntitiesContext(ctx context.Context, criteria *models.SearchCriteria) ([]models.SearchResult, error) {
+	if c.cache == nil || !isIDLookup(criteria) {
+		return c.searchEntities(ctx, criteria)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search entities: %w", err)
+	}
+	if results, ok := c.cache.getEntity(criteria.ID); ok {
+		return results, nil
+	}
+	results, err := c.searchEntities(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.setEntity(criteria.ID, results)
+	return results, nil
+}
+
+// searchEntities runs a search against the Query API, bypassing the lookup cache
+func (c *Client) searchEntities(ctx context.Context, criteria *models.SearchCriteria) ([]models.SearchResult, error) {
 	jsonData, err := json.Marshal(criteria)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal search criteria: %w", err)
 	}
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/search", c.queryURL),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/search", c.queryURL), jsonData, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search entities: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newHTTPError(resp)
 	}
 
 	// Read the raw response body
@@ -203,16 +334,22 @@ func (c *Client) SearchEntities(criteria *models.SearchCriteria) ([]models.Searc
 
 // GetEntityMetadata gets metadata of an entity
 func (c *Client) GetEntityMetadata(entityID string) (map[string]interface{}, error) {
-	resp, err := c.httpClient.Get(
-		fmt.Sprintf("%s/%s/metadata", c.queryURL, entityID),
-	)
+	return c.GetEntityMetadataContext(context.Background(), entityID)
+}
+
+// GetEntityMetadataContext gets metadata of an entity, aborting the request when ctx is done
+func (c *Client) GetEntityMetadataContext(ctx context.Context, entityID string) (map[string]interface{}, error) {
+	// URL encode the entity ID to handle special characters like slashes
+	encodedID := url.QueryEscape(entityID)
+
+	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/%s/metadata", c.queryURL, encodedID), nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entity metadata: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, newHTTPError(resp)
 	}
 
 	var metadata map[string]interface{}
@@ -225,20 +362,30 @@ func (c *Client) GetEntityMetadata(entityID string) (map[string]interface{}, err
 
 // GetEntityAttribute retrieves a specific attribute of an entity
 func (c *Client) GetEntityAttribute(entityID, attributeName string, startTime, endTime string) (interface{}, error) {
-	url := fmt.Sprintf("%s/%s/attributes/%s", c.queryURL, entityID, attributeName)
+	return c.GetEntityAttributeContext(context.Background(), entityID, attributeName, startTime, endTime)
+}
+
+// GetEntityAttributeContext retrieves a specific attribute of an entity, aborting the request when ctx is done
+func (c *Client) GetEntityAttributeContext(ctx context.Context, entityID, attributeName string, startTime, endTime string) (interface{}, error) {
+	// URL encode the entity ID to handle special characters like slashes
+	attributeURL := fmt.Sprintf("%s/%s/attributes/%s", c.queryURL, url.QueryEscape(entityID), url.PathEscape(attributeName))
 	if startTime != "" {
-		url += fmt.Sprintf("?startTime=%s", startTime)
+		attributeURL += fmt.Sprintf("?startTime=%s", url.QueryEscape(startTime))
 		if endTime != "" {
-			url += fmt.Sprintf("&endTime=%s", endTime)
+			attributeURL += fmt.Sprintf("&endTime=%s", url.QueryEscape(endTime))
 		}
 	}
 
-	resp, err := c.httpClient.Get(url)
+	resp, err := c.do(ctx, http.MethodGet, attributeURL, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get entity attribute: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, newHTTPError(resp)
+	}
+
 	var result interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -249,6 +396,55 @@ func (c *Client) GetEntityAttribute(entityID, attributeName string, startTime, e
 
 // GetRelatedEntities gets related entity IDs based on query parameters
 func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	return c.GetRelatedEntitiesContext(context.Background(), entityID, query)
+}
+
+// GetRelatedEntitiesContext gets related entity IDs based on query parameters, aborting the request when ctx is done.
+// query.Direction may be DirectionIncoming, DirectionOutgoing or DirectionBoth (or empty, meaning both), and
+// query.ActiveAt (RFC3339) keeps the relationships active at that time. Pending batched writes are included
+// in the result. Results are answered from the lookup cache when it is enabled.
+func (c *Client) GetRelatedEntitiesContext(ctx context.Context, entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	query, err := normalizeRelationQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.batch != nil && !c.batch.canOverlay(query) {
+		if err := c.FlushRelationshipsContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	relations, err := c.getCachedRelatedEntities(ctx, entityID, query)
+	if err != nil {
+		return nil, err
+	}
+	if c.batch != nil {
+		relations = c.batch.overlay(entityID, query, relations)
+	}
+	return restrictRelations(relations, query), nil
+}
+
+// getCachedRelatedEntities answers a relation query from the lookup cache when it is enabled
+func (c *Client) getCachedRelatedEntities(ctx context.Context, entityID string, query *models.Relationship) ([]models.Relationship, error) {
+	if c.cache == nil {
+		return c.getRelatedEntities(ctx, entityID, query)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get related entities: %w", err)
+	}
+	if relations, ok := c.cache.getRelations(entityID, query); ok {
+		return relations, nil
+	}
+	relations, err := c.getRelatedEntities(ctx, entityID, query)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.setRelations(entityID, query, relations)
+	return relations, nil
+}
+
+// getRelatedEntities queries the relations of an entity from the Query API, bypassing the lookup cache
+func (c *Client) getRelatedEntities(ctx context.Context, entityID string, query *models.Relationship) ([]models.Relationship, error) {
 	jsonData, err := json.Marshal(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal query: %w", err)
@@ -257,19 +453,14 @@ func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship)
 	// URL encode the entity ID to handle special characters like slashes
 	encodedID := url.QueryEscape(entityID)
 
-	resp, err := c.httpClient.Post(
-		fmt.Sprintf("%s/%s/relations", c.queryURL, encodedID),
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	resp, err := c.do(ctx, http.MethodPost, fmt.Sprintf("%s/%s/relations", c.queryURL, encodedID), jsonData, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get related entities: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, newHTTPError(resp)
 	}
 
 	var relations []models.Relationship
@@ -279,30 +470,3 @@ func (c *Client) GetRelatedEntities(entityID string, query *models.Relationship)
 
 	return relations, nil
 }
-
-// GetAllRelatedEntities gets all related entity IDs without filters
-// func (c *Client) GetAllRelatedEntities(entityID string) ([]models.Relationship, error) {
-// 	// URL encode the entity ID to handle special characters like slashes
-// 	encodedID := url.QueryEscape(entityID)
-
-// 	resp, err := c.httpClient.Post(
-// 		fmt.Sprintf("%s/%s/allrelations", c.queryURL, encodedID),
-// 		"application/json",
-// 		nil,
-// 	)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("failed to get all related entities: %w", err)
-// 	}
-// 	defer resp.Body.Close()
-
-// 	if resp.StatusCode != http.StatusOK {
-// 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
-// 	}
-
-// 	var relations []models.Relationship
-// 	if err := json.NewDecoder(resp.Body).Decode(&relations); err != nil {
-// 		return nil, fmt.Errorf("failed to decode response: %w", err)
-// 	}
-
-// 	return relations, nil
-// }
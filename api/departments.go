@@ -0,0 +1,286 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// MergePolicy says what happens to the people and documents of departments that are merged
+type MergePolicy string
+
+const (
+	// MergeMove ends the relationships of the old department and starts them on the new one
+	MergeMove MergePolicy = "move"
+	// MergeCopy starts the relationships on the new department and leaves the old ones as they are
+	MergeCopy MergePolicy = "copy"
+	// MergeTerminate ends the relationships of the old department
+	MergeTerminate MergePolicy = "terminate"
+	// MergeKeep leaves the relationships of the old department as they are
+	MergeKeep MergePolicy = "keep"
+)
+
+// validatePolicy checks the policy read from the given column; empty means the default policy
+func validatePolicy(field string, policy MergePolicy) error {
+	switch policy {
+	case "", MergeMove, MergeCopy, MergeTerminate, MergeKeep:
+		return nil
+	}
+	return invalidField(field, fmt.Sprintf("unknown merge policy '%s': must be %s, %s, %s or %s",
+		policy, MergeMove, MergeCopy, MergeTerminate, MergeKeep), nil)
+}
+
+// heldDepartment is a department together with the minister holding it on a given date
+type heldDepartment struct {
+	id             string
+	name           string
+	ministerID     string
+	ministerName   string
+	relationshipID string
+}
+
+// getHeldDepartmentContext finds the department with the given name and the minister of presidentName
+// that holds it on dateISO
+func (c *Client) getHeldDepartmentContext(ctx context.Context, name, presidentName, dateISO string) (*heldDepartment, error) {
+	departmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: "department",
+		},
+		Name: name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for department: %w", err)
+	}
+	if len(departmentResults) == 0 {
+		return nil, notFoundf("department '%s' not found", name)
+	}
+	if len(departmentResults) > 1 {
+		return nil, ambiguousf(searchResultIDs(departmentResults), "multiple departments found with name '%s'", name)
+	}
+	departmentID := departmentResults[0].ID
+
+	departmentRelations, err := c.GetRelatedEntitiesContext(ctx, departmentID, &models.Relationship{
+		Name:      "AS_DEPARTMENT",
+		Direction: DirectionIncoming,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department relationships: %w", err)
+	}
+
+	// The department may be held by ministers of other presidents as well
+	for _, rel := range departmentRelations {
+		if !activeAt(rel, dateISO) {
+			continue
+		}
+		ministerResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: rel.RelatedEntityID})
+		if err != nil || len(ministerResults) == 0 {
+			continue
+		}
+		minister, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, ministerResults[0].Name, dateISO)
+		if err != nil || minister.ID != rel.RelatedEntityID {
+			continue
+		}
+		return &heldDepartment{
+			id:             departmentID,
+			name:           name,
+			ministerID:     minister.ID,
+			ministerName:   ministerResults[0].Name,
+			relationshipID: rel.ID,
+		}, nil
+	}
+
+	return nil, notFoundf("no active minister relationship found for department '%s' under president '%s'", name, presidentName)
+}
+
+// startRelationship creates a relationship named name from parentID to childID starting at startISO,
+// on behalf of the transaction with the given key
+func (c *Client) startRelationship(ctx context.Context, key, parentID, childID, name, startISO string) error {
+	uniqueRelationshipID := RelationshipID(key, name, parentID, childID)
+
+	_, err := c.UpdateEntityContext(ctx, parentID, &models.Entity{
+		ID: parentID,
+		Relationships: []models.RelationshipEntry{
+			{
+				Key: uniqueRelationshipID,
+				Value: models.Relationship{
+					RelatedEntityID: childID,
+					StartTime:       startISO,
+					EndTime:         "",
+					ID:              uniqueRelationshipID,
+					Name:            name,
+				},
+			},
+		},
+	})
+	return err
+}
+
+// endRelationship ends the relationship of parentID with the given ID at endISO
+func (c *Client) endRelationship(ctx context.Context, parentID, relationshipID, endISO string) error {
+	_, err := c.UpdateEntityContext(ctx, parentID, &models.Entity{
+		ID: parentID,
+		Relationships: []models.RelationshipEntry{
+			{
+				Key: relationshipID,
+				Value: models.Relationship{
+					EndTime: endISO,
+					ID:      relationshipID,
+				},
+			},
+		},
+	})
+	return err
+}
+
+// handOverRelations applies policy to the relationships named name that fromID holds on dateISO.
+// handedOver records the entities already related to toID, so that an entity related to several
+// merged departments is related to the new one once.
+func (c *Client) handOverRelations(ctx context.Context, key, fromID, toID, name string, policy MergePolicy, dateISO string, handedOver map[string]bool) error {
+	if policy == MergeKeep {
+		return nil
+	}
+
+	relations, err := c.GetRelatedEntitiesContext(ctx, fromID, &models.Relationship{
+		Name:      name,
+		Direction: DirectionOutgoing,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s relationships of %s: %w", name, fromID, err)
+	}
+
+	for _, rel := range relations {
+		if !activeAt(rel, dateISO) {
+			continue
+		}
+		if (policy == MergeMove || policy == MergeCopy) && !handedOver[rel.RelatedEntityID] {
+			if err := c.startRelationship(ctx, key, toID, rel.RelatedEntityID, name, dateISO); err != nil {
+				return fmt.Errorf("failed to create new %s relationship: %w", name, err)
+			}
+			handedOver[rel.RelatedEntityID] = true
+		}
+		if policy == MergeMove || policy == MergeTerminate {
+			if err := c.endRelationship(ctx, fromID, rel.ID, dateISO); err != nil {
+				return fmt.Errorf("failed to terminate old %s relationship: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MergeDepartments merges departments into a new department. The new department is created under
+// the minister named by the transaction's parent, or under the minister of the first old department.
+// Each old department leaves its minister and gets a MERGED_INTO relationship to the new one; its
+// people and documents are handed over according to the transaction's policies.
+func (c *Client) MergeDepartments(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	return c.MergeDepartmentsContext(context.Background(), transaction, entityCounters)
+}
+
+// MergeDepartmentsContext is like MergeDepartments but stops issuing API calls once ctx is done
+func (c *Client) MergeDepartmentsContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeMergeTx(transaction, "department")
+	if err != nil {
+		return 0, err
+	}
+	return c.MergeDepartmentsTx(ctx, tx, entityCounters)
+}
+
+// MergeDepartmentsTx is like MergeDepartmentsContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) MergeDepartmentsTx(ctx context.Context, tx *MergeTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("department"); err != nil {
+		return 0, tx.Source.locate(err)
+	}
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	peoplePolicy := tx.People
+	if peoplePolicy == "" {
+		peoplePolicy = MergeMove
+	}
+	documentPolicy := tx.Documents
+	if documentPolicy == "" {
+		documentPolicy = MergeCopy
+	}
+
+	// A merge applied before has already recorded the lineage
+	key := transactionKey(ChangeMerge, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "department"}, tx.Old[0], "MERGED_INTO", key)
+	if err != nil {
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("merge into department '%s'", tx.New))
+		return entityCounters["department"], nil
+	}
+
+	// Resolve every old department before writing anything
+	oldDepartments := make([]*heldDepartment, 0, len(tx.Old))
+	for _, oldName := range tx.Old {
+		department, err := c.getHeldDepartmentContext(ctx, oldName, presidentName, dateISO)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get old department: %w", err)
+		}
+		oldDepartments = append(oldDepartments, department)
+	}
+
+	ministerName := tx.Parent
+	if ministerName == "" {
+		ministerName = oldDepartments[0].ministerName
+	}
+
+	// 1. Create the new department under the minister
+	newDepartmentCounter, err := c.addOrgEntity(ctx, &AddTx{
+		TransactionID: tx.TransactionID,
+		Parent:        ministerName,
+		ParentType:    "minister",
+		Child:         tx.New,
+		ChildType:     "department",
+		RelType:       "AS_DEPARTMENT",
+		Date:          tx.Date,
+		President:     presidentName,
+	}, key, "department", entityCounters)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create new department: %w", err)
+	}
+
+	newDepartmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: "department",
+		},
+		Name: tx.New,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for new department: %w", err)
+	}
+	if len(newDepartmentResults) != 1 {
+		return 0, notFoundf("new department not found: %s", tx.New)
+	}
+	newDepartmentID := newDepartmentResults[0].ID
+
+	people := map[string]bool{}
+	documents := map[string]bool{}
+	for _, old := range oldDepartments {
+		// 2. End the old department's relationship with its minister
+		if err := c.endRelationship(ctx, old.ministerID, old.relationshipID, dateISO); err != nil {
+			return 0, fmt.Errorf("failed to terminate old department's minister relationship: %w", err)
+		}
+
+		// 3. Hand over its people and documents
+		if err := c.handOverRelations(ctx, key, old.id, newDepartmentID, "AS_APPOINTED", peoplePolicy, dateISO, people); err != nil {
+			return 0, err
+		}
+		if err := c.handOverRelations(ctx, key, old.id, newDepartmentID, "AS_DOCUMENT", documentPolicy, dateISO, documents); err != nil {
+			return 0, err
+		}
+
+		// 4. Record the lineage
+		if err := c.startRelationship(ctx, key, old.id, newDepartmentID, "MERGED_INTO", dateISO); err != nil {
+			return 0, fmt.Errorf("failed to create MERGED_INTO relationship: %w", err)
+		}
+	}
+
+	return newDepartmentCounter, nil
+}
@@ -0,0 +1,495 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// Types of org chart changes, named like the transaction files that make them
+const (
+	ChangeAdd       = "ADD"
+	ChangeTerminate = "TERMINATE"
+	ChangeMove      = "MOVE"
+	ChangeRename    = "RENAME"
+	ChangeMerge     = "MERGE"
+	ChangeSplit     = "SPLIT"
+)
+
+// changeTypeOrder is the order in which changes are listed
+var changeTypeOrder = map[string]int{
+	ChangeAdd:       0,
+	ChangeMove:      1,
+	ChangeRename:    2,
+	ChangeMerge:     3,
+	ChangeSplit:     4,
+	ChangeTerminate: 5,
+}
+
+// kindOrder is the order in which the kinds of changed entities are listed
+var kindOrder = map[string]int{
+	"citizen":    0,
+	"minister":   1,
+	"department": 2,
+}
+
+// OrgChartChange is one difference between two org charts. ADD, TERMINATE and MOVE describe the
+// entity Name placed under Parent (and, for MOVE, taken from OldParent); RENAME, MERGE and SPLIT
+// describe the entities Old replaced by the entities New.
+type OrgChartChange struct {
+	Type           string   `json:"type"`
+	Kind           string   `json:"kind"`
+	ID             string   `json:"id,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	RelType        string   `json:"relType,omitempty"`
+	RelationshipID string   `json:"relationshipId,omitempty"`
+	ParentID       string   `json:"parentId,omitempty"`
+	Parent         string   `json:"parent,omitempty"`
+	ParentKind     string   `json:"parentKind,omitempty"`
+	President      string   `json:"president,omitempty"`
+	OldParentID    string   `json:"oldParentId,omitempty"`
+	OldParent      string   `json:"oldParent,omitempty"`
+	OldPresident   string   `json:"oldPresident,omitempty"`
+	Old            []string `json:"old,omitempty"`
+	New            []string `json:"new,omitempty"`
+	// Mapping names the successor that each department or person of a split entity moved to
+	Mapping map[string]string `json:"mapping,omitempty"`
+	// Date (RFC3339) the change took effect, when it is known
+	Date string `json:"date,omitempty"`
+}
+
+// OrgChartDiff is the list of changes between the org charts on two dates
+type OrgChartDiff struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Changes []OrgChartChange `json:"changes"`
+}
+
+// chartPosition is the place of an entity under a parent in an org chart
+type chartPosition struct {
+	entity     ChartEntity
+	relType    string
+	parent     ChartEntity
+	parentKind string
+	president  string
+}
+
+// chartPositions indexes the positions of every entity in chart by entity ID and parent ID
+func chartPositions(chart *OrgChart) map[string]map[string]chartPosition {
+	positions := map[string]map[string]chartPosition{}
+	add := func(entity ChartEntity, relType string, parent ChartEntity, president string) {
+		if positions[entity.ID] == nil {
+			positions[entity.ID] = map[string]chartPosition{}
+		}
+		positions[entity.ID][parent.ID] = chartPosition{
+			entity:     entity,
+			relType:    relType,
+			parent:     parent,
+			parentKind: parent.Kind,
+			president:  president,
+		}
+	}
+	for _, president := range chart.Presidents {
+		add(president.ChartEntity, "AS_PRESIDENT", chart.Government, "")
+		for _, minister := range president.Ministers {
+			add(minister.ChartEntity, "AS_MINISTER", president.ChartEntity, president.Name)
+			for _, department := range minister.Departments {
+				add(department.ChartEntity, "AS_DEPARTMENT", minister.ChartEntity, president.Name)
+				for _, person := range department.People {
+					add(person, "AS_APPOINTED", department.ChartEntity, president.Name)
+				}
+			}
+			for _, person := range minister.People {
+				add(person, "AS_APPOINTED", minister.ChartEntity, president.Name)
+			}
+		}
+	}
+	return positions
+}
+
+// positionChange describes an entity being added to or terminated from a position
+func positionChange(changeType string, position chartPosition) OrgChartChange {
+	change := OrgChartChange{
+		Type:           changeType,
+		Kind:           position.entity.Kind,
+		ID:             position.entity.ID,
+		Name:           position.entity.Name,
+		RelType:        position.relType,
+		RelationshipID: position.entity.RelationshipID,
+		ParentID:       position.parent.ID,
+		Parent:         position.parent.Name,
+		ParentKind:     position.parentKind,
+		President:      position.president,
+	}
+	if changeType == ChangeAdd {
+		change.Date = position.entity.Since
+	}
+	return change
+}
+
+// DiffOrgCharts compares two org charts and lists the entities added, terminated or moved between
+// them. An entity that left one parent and joined one other parent is reported as a single MOVE.
+// Renames, merges and splits need the lineage relationships; see Client.DiffOrgChart.
+func DiffOrgCharts(from, to *OrgChart) *OrgChartDiff {
+	fromPositions := chartPositions(from)
+	toPositions := chartPositions(to)
+
+	ids := make([]string, 0, len(fromPositions)+len(toPositions))
+	for id := range fromPositions {
+		ids = append(ids, id)
+	}
+	for id := range toPositions {
+		if _, ok := fromPositions[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	diff := &OrgChartDiff{From: from.Date, To: to.Date, Changes: []OrgChartChange{}}
+	for _, id := range ids {
+		var removed, added []chartPosition
+		for parentID, position := range fromPositions[id] {
+			if _, ok := toPositions[id][parentID]; !ok {
+				removed = append(removed, position)
+			}
+		}
+		for parentID, position := range toPositions[id] {
+			if _, ok := fromPositions[id][parentID]; !ok {
+				added = append(added, position)
+			}
+		}
+
+		if len(removed) == 1 && len(added) == 1 && removed[0].relType == added[0].relType {
+			change := positionChange(ChangeMove, added[0])
+			change.Date = added[0].entity.Since
+			change.OldParentID = removed[0].parent.ID
+			change.OldParent = removed[0].parent.Name
+			change.OldPresident = removed[0].president
+			diff.Changes = append(diff.Changes, change)
+			continue
+		}
+		for _, position := range removed {
+			diff.Changes = append(diff.Changes, positionChange(ChangeTerminate, position))
+		}
+		for _, position := range added {
+			diff.Changes = append(diff.Changes, positionChange(ChangeAdd, position))
+		}
+	}
+	diff.sortChanges()
+	return diff
+}
+
+// sortChanges orders the changes by type, kind and name
+func (d *OrgChartDiff) sortChanges() {
+	sort.SliceStable(d.Changes, func(i, j int) bool {
+		a, b := d.Changes[i], d.Changes[j]
+		if a.Type != b.Type {
+			return changeTypeOrder[a.Type] < changeTypeOrder[b.Type]
+		}
+		if a.Kind != b.Kind {
+			return kindOrder[a.Kind] < kindOrder[b.Kind]
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if strings.Join(a.Old, ";") != strings.Join(b.Old, ";") {
+			return strings.Join(a.Old, ";") < strings.Join(b.Old, ";")
+		}
+		return a.Parent < b.Parent
+	})
+}
+
+// DiffOrgChart compares the org charts on fromISO and toISO (RFC3339; an empty toISO means now).
+// Ministers and departments that were renamed, merged or split in between are reported as RENAME,
+// MERGE and SPLIT changes from their RENAMED_TO, MERGED_INTO and SPLIT_INTO relationships, in place
+// of the terminations, additions and moves those made. opts may be nil.
+func (c *Client) DiffOrgChart(fromISO, toISO string, opts *SnapshotOptions) (*OrgChartDiff, error) {
+	return c.DiffOrgChartContext(context.Background(), fromISO, toISO, opts)
+}
+
+// DiffOrgChartContext is like DiffOrgChart but stops issuing API calls once ctx is done
+func (c *Client) DiffOrgChartContext(ctx context.Context, fromISO, toISO string, opts *SnapshotOptions) (*OrgChartDiff, error) {
+	if fromISO == "" || (toISO != "" && toISO < fromISO) {
+		return nil, fmt.Errorf("invalid diff range %s to %s: the start must be set and not after the end", snapshotDate(fromISO), snapshotDate(toISO))
+	}
+	from, err := c.GetOrgChartContext(ctx, fromISO, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build org chart on %s: %w", snapshotDate(fromISO), err)
+	}
+	to, err := c.GetOrgChartContext(ctx, toISO, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build org chart on %s: %w", snapshotDate(toISO), err)
+	}
+	diff := DiffOrgCharts(from, to)
+
+	if err := c.addLineageChanges(ctx, diff); err != nil {
+		return nil, err
+	}
+	if err := c.addTerminationDates(ctx, diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// addLineageChanges replaces the terminations, additions and moves made by renames, merges and
+// splits between the dates of diff with RENAME, MERGE and SPLIT changes
+func (c *Client) addLineageChanges(ctx context.Context, diff *OrgChartDiff) error {
+	type lineageChange struct {
+		change *OrgChartChange
+		oldIDs map[string]bool
+		newIDs map[string]bool
+	}
+	var lineageChanges []*lineageChange
+	merges := map[string]*lineageChange{}
+	names := map[string]string{}
+
+	for _, terminated := range diff.Changes {
+		if terminated.Type != ChangeTerminate || (terminated.Kind != "minister" && terminated.Kind != "department") {
+			continue
+		}
+		relations, err := c.GetAllRelatedEntitiesContext(ctx, terminated.ID, &RelationFilter{
+			Direction: DirectionOutgoing,
+			Names:     LineageRelations,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get lineage of %s: %w", terminated.ID, err)
+		}
+		sort.Slice(relations, func(i, j int) bool {
+			return relations[i].RelatedEntityID < relations[j].RelatedEntityID
+		})
+
+		var split *lineageChange
+		for _, rel := range relations {
+			if rel.StartTime <= diff.From || (diff.To != "" && rel.StartTime > diff.To) {
+				continue
+			}
+			if _, ok := names[rel.RelatedEntityID]; !ok {
+				results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: rel.RelatedEntityID})
+				if err != nil {
+					return fmt.Errorf("failed to search for entity %s: %w", rel.RelatedEntityID, err)
+				}
+				if len(results) == 0 {
+					return notFoundf("entity not found: %s", rel.RelatedEntityID)
+				}
+				names[rel.RelatedEntityID] = results[0].Name
+			}
+			names[terminated.ID] = terminated.Name
+
+			switch rel.Name {
+			case "RENAMED_TO":
+				lineageChanges = append(lineageChanges, &lineageChange{
+					change: &OrgChartChange{Type: ChangeRename, Kind: terminated.Kind, Date: rel.StartTime},
+					oldIDs: map[string]bool{terminated.ID: true},
+					newIDs: map[string]bool{rel.RelatedEntityID: true},
+				})
+			case "MERGED_INTO":
+				merge, ok := merges[rel.RelatedEntityID]
+				if !ok {
+					merge = &lineageChange{
+						change: &OrgChartChange{Type: ChangeMerge, Kind: terminated.Kind, Date: rel.StartTime},
+						oldIDs: map[string]bool{},
+						newIDs: map[string]bool{rel.RelatedEntityID: true},
+					}
+					merges[rel.RelatedEntityID] = merge
+					lineageChanges = append(lineageChanges, merge)
+				}
+				merge.oldIDs[terminated.ID] = true
+			case "SPLIT_INTO":
+				if split == nil {
+					split = &lineageChange{
+						change: &OrgChartChange{Type: ChangeSplit, Kind: terminated.Kind, Date: rel.StartTime},
+						oldIDs: map[string]bool{terminated.ID: true},
+						newIDs: map[string]bool{},
+					}
+					lineageChanges = append(lineageChanges, split)
+				}
+				split.newIDs[rel.RelatedEntityID] = true
+			}
+		}
+	}
+	if len(lineageChanges) == 0 {
+		return nil
+	}
+
+	sortedNames := func(ids map[string]bool) []string {
+		result := make([]string, 0, len(ids))
+		for id := range ids {
+			result = append(result, names[id])
+		}
+		sort.Strings(result)
+		return result
+	}
+
+	// Drop the changes that the renames, merges and splits account for
+	replaced := map[int]bool{}
+	for _, lineage := range lineageChanges {
+		lineage.change.Old = sortedNames(lineage.oldIDs)
+		lineage.change.New = sortedNames(lineage.newIDs)
+		for i, change := range diff.Changes {
+			switch {
+			case change.Type == ChangeTerminate && lineage.oldIDs[change.ID],
+				change.Type == ChangeAdd && lineage.newIDs[change.ID]:
+				replaced[i] = true
+			case change.Type == ChangeMove && lineage.oldIDs[change.OldParentID] && lineage.newIDs[change.ParentID]:
+				replaced[i] = true
+				if lineage.change.Type == ChangeSplit {
+					if lineage.change.Mapping == nil {
+						lineage.change.Mapping = map[string]string{}
+					}
+					lineage.change.Mapping[change.Name] = change.Parent
+				}
+			}
+		}
+	}
+
+	changes := make([]OrgChartChange, 0, len(diff.Changes))
+	for i, change := range diff.Changes {
+		if !replaced[i] {
+			changes = append(changes, change)
+		}
+	}
+	for _, lineage := range lineageChanges {
+		changes = append(changes, *lineage.change)
+	}
+	diff.Changes = changes
+	diff.sortChanges()
+	return nil
+}
+
+// addTerminationDates sets the date of each termination from the end of its relationship
+func (c *Client) addTerminationDates(ctx context.Context, diff *OrgChartDiff) error {
+	for i := range diff.Changes {
+		change := &diff.Changes[i]
+		if change.Type != ChangeTerminate || change.RelationshipID == "" {
+			continue
+		}
+		relations, err := c.GetRelatedEntitiesContext(ctx, change.ParentID, &models.Relationship{
+			Name:            change.RelType,
+			RelatedEntityID: change.ID,
+			Direction:       DirectionOutgoing,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get %s relationships of %s: %w", change.RelType, change.ParentID, err)
+		}
+		for _, rel := range relations {
+			if rel.ID == change.RelationshipID {
+				change.Date = rel.EndTime
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// WriteText writes the changes one per line
+func (d *OrgChartDiff) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Changes from %s to %s:\n", snapshotDate(d.From), snapshotDate(d.To))
+	if len(d.Changes) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, change := range d.Changes {
+		b.WriteString("  ")
+		switch change.Type {
+		case ChangeAdd:
+			fmt.Fprintf(&b, "ADD %s '%s' under '%s'", change.Kind, change.Name, change.Parent)
+		case ChangeTerminate:
+			fmt.Fprintf(&b, "TERMINATE %s '%s' under '%s'", change.Kind, change.Name, change.Parent)
+		case ChangeMove:
+			fmt.Fprintf(&b, "MOVE %s '%s' from '%s' to '%s'", change.Kind, change.Name, change.OldParent, change.Parent)
+		case ChangeRename:
+			fmt.Fprintf(&b, "RENAME %s '%s' to '%s'", change.Kind, strings.Join(change.Old, "', '"), strings.Join(change.New, "', '"))
+		case ChangeMerge:
+			fmt.Fprintf(&b, "MERGE %s '%s' into '%s'", change.Kind, strings.Join(change.Old, "', '"), strings.Join(change.New, "', '"))
+		case ChangeSplit:
+			fmt.Fprintf(&b, "SPLIT %s '%s' into '%s'", change.Kind, strings.Join(change.Old, "', '"), strings.Join(change.New, "', '"))
+		}
+		if change.Date != "" {
+			fmt.Fprintf(&b, " on %s", shortDate(change.Date))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// csvHeaders are the columns of the transaction files of each type of change
+var csvHeaders = map[string][]string{
+	ChangeAdd:       {"transaction_id", "parent", "parent_type", "child", "child_type", "rel_type", "date"},
+	ChangeTerminate: {"transaction_id", "parent", "parent_type", "child", "child_type", "rel_type", "date"},
+	ChangeMove:      {"transaction_id", "old_parent", "old_president_name", "new_parent", "new_president_name", "child", "type", "date"},
+	ChangeRename:    {"transaction_id", "old", "new", "type", "date"},
+	ChangeMerge:     {"transaction_id", "old", "new", "type", "date"},
+	ChangeSplit:     {"transaction_id", "old", "new", "type", "date", "mapping"},
+}
+
+// ChangeTypes returns the types of the changes in d, in the order they are listed
+func (d *OrgChartDiff) ChangeTypes() []string {
+	var types []string
+	for _, change := range d.Changes {
+		if len(types) == 0 || types[len(types)-1] != change.Type {
+			types = append(types, change.Type)
+		}
+	}
+	return types
+}
+
+// WriteCSV writes the changes of one type as a transaction file of that type, with transaction IDs
+// made of transactionPrefix and a sequence number (for example "diff_tr_01")
+func (d *OrgChartDiff) WriteCSV(w io.Writer, changeType, transactionPrefix string) error {
+	header, ok := csvHeaders[changeType]
+	if !ok {
+		return fmt.Errorf("unknown change type '%s'", changeType)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	sequence := 0
+	for _, change := range d.Changes {
+		if change.Type != changeType {
+			continue
+		}
+		sequence++
+		transactionID := fmt.Sprintf("%s_tr_%02d", transactionPrefix, sequence)
+		date := shortDate(change.Date)
+
+		var row []string
+		switch changeType {
+		case ChangeAdd, ChangeTerminate:
+			row = []string{transactionID, change.Parent, change.ParentKind, change.Name, change.Kind, change.RelType, date}
+		case ChangeMove:
+			row = []string{transactionID, change.OldParent, change.OldPresident, change.Parent, change.President, change.Name, change.Kind, date}
+		case ChangeRename:
+			row = []string{transactionID, strings.Join(change.Old, ";"), strings.Join(change.New, ";"), change.Kind, date}
+		case ChangeMerge:
+			row = []string{transactionID, "[" + strings.Join(change.Old, ";") + "]", strings.Join(change.New, ";"), change.Kind, date}
+		case ChangeSplit:
+			row = []string{transactionID, strings.Join(change.Old, ";"), "[" + strings.Join(change.New, ";") + "]", change.Kind, date, formatMapping(change.Mapping)}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatMapping writes a split mapping in the form the mapping column is read in
+func formatMapping(mapping map[string]string) string {
+	if len(mapping) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(mapping))
+	for name, successor := range mapping {
+		pairs = append(pairs, name+"="+successor)
+	}
+	sort.Strings(pairs)
+	return "[" + strings.Join(pairs, ";") + "]"
+}
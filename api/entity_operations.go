@@ -1,8 +1,9 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"orgchart_nexoan/models"
@@ -10,6 +11,11 @@ import (
 
 // CreateGovernmentNode creates the initial government node
 func (c *Client) CreateGovernmentNode() (*models.Entity, error) {
+	return c.CreateGovernmentNodeContext(context.Background())
+}
+
+// CreateGovernmentNodeContext is like CreateGovernmentNode but stops issuing API calls once ctx is done
+func (c *Client) CreateGovernmentNodeContext(ctx context.Context) (*models.Entity, error) {
 	// Create the government entity
 	governmentEntity := &models.Entity{
 		ID:      "gov_01",
@@ -25,7 +31,7 @@ func (c *Client) CreateGovernmentNode() (*models.Entity, error) {
 	}
 
 	// Create the entity
-	createdEntity, err := c.CreateEntity(governmentEntity)
+	createdEntity, err := c.CreateEntityContext(ctx, governmentEntity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create government entity: %w", err)
 	}
@@ -35,8 +41,22 @@ func (c *Client) CreateGovernmentNode() (*models.Entity, error) {
 
 // GetPresidentByGovernment retrieves a president entity (citizen with AS_PRESIDENT relationship to government) by name
 func (c *Client) GetPresidentByGovernment(presidentName string) (*models.Entity, error) {
+	return c.GetPresidentByGovernmentContext(context.Background(), presidentName)
+}
+
+// GetPresidentByGovernmentContext is like GetPresidentByGovernment but stops issuing API calls once ctx is done
+func (c *Client) GetPresidentByGovernmentContext(ctx context.Context, presidentName string) (*models.Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search for president entity: %w", err)
+	}
+	if c.cache != nil {
+		if president, ok := c.cache.getPresident(presidentName); ok {
+			return president, nil
+		}
+	}
+
 	// Get the president entity ID - presidents are citizens with AS_PRESIDENT relationship to government
-	presidentResults, err := c.SearchEntities(&models.SearchCriteria{
+	presidentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Person",
 			Minor: "citizen",
@@ -48,24 +68,22 @@ func (c *Client) GetPresidentByGovernment(presidentName string) (*models.Entity,
 		return nil, fmt.Errorf("failed to search for president entity: %w", err)
 	}
 	if len(presidentResults) == 0 {
-		return nil, fmt.Errorf("president entity not found: %s", presidentName)
+		return nil, notFoundf("president entity not found: %s", presidentName)
+	}
+
+	// Get government node
+	governmentID, err := c.getGovernmentIDContext(ctx)
+	if errors.Is(err, ErrEntityNotFound) {
+		return nil, notFoundf("president entity not found or not active: %s", presidentName)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	// Find the president by checking if they have AS_PRESIDENT relationship to government
 	for _, president := range presidentResults {
-		// Get government node
-		governmentResults, err := c.SearchEntities(&models.SearchCriteria{
-			Kind: &models.Kind{
-				Major: "Organisation",
-				Minor: "government",
-			},
-		})
-		if err != nil || len(governmentResults) == 0 {
-			continue
-		}
-
 		// Check if this citizen has AS_PRESIDENT relationship to government
-		presidentRelations, err := c.GetRelatedEntities(governmentResults[0].ID, &models.Relationship{
+		presidentRelations, err := c.GetRelatedEntitiesContext(ctx, governmentID, &models.Relationship{
 			Name:            "AS_PRESIDENT",
 			RelatedEntityID: president.ID,
 		})
@@ -75,39 +93,69 @@ func (c *Client) GetPresidentByGovernment(presidentName string) (*models.Entity,
 
 		// If there are any AS_PRESIDENT relationships (active or not), return the president
 		if len(presidentRelations) > 0 {
-			// Convert SearchResult to Entity
-			entity := &models.Entity{
-				ID:         president.ID,
-				Kind:       president.Kind,
-				Created:    president.Created,
-				Terminated: president.Terminated,
-				Name: models.TimeBasedValue{
-					Value: president.Name,
-				},
-				Metadata:      []models.MetadataEntry{},
-				Attributes:    []models.AttributeEntry{},
-				Relationships: []models.RelationshipEntry{},
+			entity, err := c.GetEntityContext(ctx, president.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get president entity: %w", err)
+			}
+			if c.cache != nil {
+				c.cache.setPresident(presidentName, entity)
 			}
 			return entity, nil
 		}
 	}
 
-	return nil, fmt.Errorf("president entity not found or not active: %s", presidentName)
+	return nil, notFoundf("president entity not found or not active: %s", presidentName)
+}
+
+// getGovernmentIDContext returns the ID of the government node, from the lookup cache when possible
+func (c *Client) getGovernmentIDContext(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("failed to search for government entity: %w", err)
+	}
+	if c.cache != nil {
+		if governmentID, ok := c.cache.getGovernmentID(); ok {
+			return governmentID, nil
+		}
+	}
+
+	governmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: "government",
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search for government entity: %w", err)
+	}
+	if len(governmentResults) == 0 {
+		return "", notFoundf("government entity not found")
+	}
+
+	if c.cache != nil {
+		c.cache.setGovernmentID(governmentResults[0].ID)
+	}
+	return governmentResults[0].ID, nil
 }
 
-// GetMinisterByPresident retrieves a minister entity by president name and minister name
+// GetMinisterByPresident retrieves a minister entity by president name and minister name.
+// When dateISO is set, only ministers the president had on that date are considered.
 func (c *Client) GetMinisterByPresident(presidentName, ministerName, dateISO string) (*models.Entity, error) {
+	return c.GetMinisterByPresidentContext(context.Background(), presidentName, ministerName, dateISO)
+}
+
+// GetMinisterByPresidentContext is like GetMinisterByPresident but stops issuing API calls once ctx is done
+func (c *Client) GetMinisterByPresidentContext(ctx context.Context, presidentName, ministerName, dateISO string) (*models.Entity, error) {
 	// Get the president entity using the helper function
-	presidentEntity, err := c.GetPresidentByGovernment(presidentName)
+	presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
 	if err != nil {
 		return nil, err
 	}
 	presidentID := presidentEntity.ID
 
-	// Get all minister relationships for the president
-	presidentRelations, err := c.GetRelatedEntities(presidentID, &models.Relationship{
-		Name: "AS_MINISTER",
-		//ActiveAt: dateISO,
+	// Get the minister relationships of the president active at dateISO
+	presidentRelations, err := c.GetRelatedEntitiesContext(ctx, presidentID, &models.Relationship{
+		Name:     "AS_MINISTER",
+		ActiveAt: dateISO,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get president's relationships: %w", err)
@@ -116,7 +164,7 @@ func (c *Client) GetMinisterByPresident(presidentName, ministerName, dateISO str
 	// Find the minister with the specified name
 	for _, rel := range presidentRelations {
 		// Fetch the related entity (minister)
-		ministerResults, err := c.SearchEntities(&models.SearchCriteria{
+		ministerResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 			ID: rel.RelatedEntityID,
 		})
 		if err != nil || len(ministerResults) == 0 {
@@ -124,38 +172,36 @@ func (c *Client) GetMinisterByPresident(presidentName, ministerName, dateISO str
 		}
 		minister := ministerResults[0]
 		if minister.Kind.Minor == "minister" && minister.Name == ministerName {
-			// Convert SearchResult to Entity
-			entity := &models.Entity{
-				ID:         minister.ID,
-				Kind:       minister.Kind,
-				Created:    minister.Created,
-				Terminated: minister.Terminated,
-				Name: models.TimeBasedValue{
-					Value: minister.Name,
-				},
-				Metadata:      []models.MetadataEntry{},
-				Attributes:    []models.AttributeEntry{},
-				Relationships: []models.RelationshipEntry{},
+			entity, err := c.GetEntityContext(ctx, minister.ID, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get minister entity: %w", err)
 			}
 			return entity, nil
 		}
 	}
 
-	return nil, fmt.Errorf("minister '%s' not found under president '%s'", ministerName, presidentName)
+	return nil, notFoundf("minister '%s' not found under president '%s'", ministerName, presidentName)
 }
 
-// GetActiveMinisterByPresident retrieves an active minister entity by president name and minister name
+// GetActiveMinisterByPresident retrieves the minister with the given name whose relationship with the
+// president was active on dateISO, so that transactions resolve against the minister of their own date
+// even when later gazettes are already loaded. An empty dateISO means the currently active minister.
 // Returns an error if multiple active ministers with the same name are found
 func (c *Client) GetActiveMinisterByPresident(presidentName, ministerName, dateISO string) (*models.Entity, error) {
+	return c.GetActiveMinisterByPresidentContext(context.Background(), presidentName, ministerName, dateISO)
+}
+
+// GetActiveMinisterByPresidentContext is like GetActiveMinisterByPresident but stops issuing API calls once ctx is done
+func (c *Client) GetActiveMinisterByPresidentContext(ctx context.Context, presidentName, ministerName, dateISO string) (*models.Entity, error) {
 	// Get the president entity using the helper function
-	presidentEntity, err := c.GetPresidentByGovernment(presidentName)
+	presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
 	if err != nil {
 		return nil, err
 	}
 	presidentID := presidentEntity.ID
 
 	// Get all minister relationships for the president
-	presidentRelations, err := c.GetRelatedEntities(presidentID, &models.Relationship{
+	presidentRelations, err := c.GetRelatedEntitiesContext(ctx, presidentID, &models.Relationship{
 		Name: "AS_MINISTER",
 	})
 	if err != nil {
@@ -163,15 +209,14 @@ func (c *Client) GetActiveMinisterByPresident(presidentName, ministerName, dateI
 	}
 
 	// Find active ministers with the specified name
-	var activeMinisters []*models.Entity
+	var activeMinisterIDs []string
 	for _, rel := range presidentRelations {
-		// Only consider active relationships (EndTime == "")
-		if rel.EndTime != "" {
+		if !activeAt(rel, dateISO) {
 			continue
 		}
 
 		// Fetch the related entity (minister)
-		ministerResults, err := c.SearchEntities(&models.SearchCriteria{
+		ministerResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 			ID: rel.RelatedEntityID,
 		})
 		if err != nil || len(ministerResults) == 0 {
@@ -179,65 +224,189 @@ func (c *Client) GetActiveMinisterByPresident(presidentName, ministerName, dateI
 		}
 		minister := ministerResults[0]
 		if minister.Kind.Minor == "minister" && minister.Name == ministerName {
-			// Convert SearchResult to Entity
-			entity := &models.Entity{
-				ID:         minister.ID,
-				Kind:       minister.Kind,
-				Created:    minister.Created,
-				Terminated: minister.Terminated,
-				Name: models.TimeBasedValue{
-					Value: minister.Name,
-				},
-				Metadata:      []models.MetadataEntry{},
-				Attributes:    []models.AttributeEntry{},
-				Relationships: []models.RelationshipEntry{},
-			}
-			activeMinisters = append(activeMinisters, entity)
+			activeMinisterIDs = append(activeMinisterIDs, minister.ID)
 		}
 	}
 
 	// Check for multiple active ministers with the same name
-	if len(activeMinisters) > 1 {
-		return nil, fmt.Errorf("multiple active ministers found with name '%s' under president '%s'", ministerName, presidentName)
+	if len(activeMinisterIDs) > 1 {
+		return nil, ambiguousf(activeMinisterIDs, "multiple active ministers found with name '%s' under president '%s'", ministerName, presidentName)
 	}
 
 	// Check if no active minister was found
-	if len(activeMinisters) == 0 {
-		return nil, fmt.Errorf("no active minister found with name '%s' under president '%s'", ministerName, presidentName)
+	if len(activeMinisterIDs) == 0 {
+		return nil, notFoundf("no active minister found with name '%s' under president '%s'", ministerName, presidentName)
+	}
+
+	minister, err := c.GetEntityContext(ctx, activeMinisterIDs[0], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minister entity: %w", err)
+	}
+	return minister, nil
+}
+
+// resolveMinister is GetActiveMinisterByPresidentContext for the minister a transaction names as its
+// parent. Gazettes can name a ministry by the name a later gazette gives it, so when no minister of
+// that name is active on dateISO, the renames into the ministers of that name are followed back to
+// the minister the president had on dateISO.
+func (c *Client) resolveMinister(ctx context.Context, presidentName, ministerName, dateISO string) (*models.Entity, error) {
+	minister, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, ministerName, dateISO)
+	if !errors.Is(err, ErrEntityNotFound) || dateISO == "" {
+		return minister, err
+	}
+
+	presidentEntity, presidentErr := c.GetPresidentByGovernmentContext(ctx, presidentName)
+	if presidentErr != nil {
+		return nil, presidentErr
+	}
+	activeRelations, lineageErr := c.GetRelatedEntitiesContext(ctx, presidentEntity.ID, &models.Relationship{
+		Name:     "AS_MINISTER",
+		ActiveAt: dateISO,
+	})
+	if lineageErr != nil {
+		return nil, fmt.Errorf("failed to get president's relationships: %w", lineageErr)
+	}
+	active := map[string]bool{}
+	for _, rel := range activeRelations {
+		active[rel.RelatedEntityID] = true
+	}
+
+	renamed, lineageErr := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: ministerName,
+	})
+	if lineageErr != nil {
+		return nil, fmt.Errorf("failed to search for minister '%s': %w", ministerName, lineageErr)
+	}
+	var pending []string
+	for _, result := range renamed {
+		pending = append(pending, result.ID)
+	}
+
+	// Only renames after dateISO lead back to a minister that had another name on dateISO
+	var predecessorIDs []string
+	visited := map[string]bool{}
+	for len(pending) > 0 {
+		ministerID := pending[0]
+		pending = pending[1:]
+		if visited[ministerID] {
+			continue
+		}
+		visited[ministerID] = true
+
+		renames, lineageErr := c.GetRelatedEntitiesContext(ctx, ministerID, &models.Relationship{
+			Name:      "RENAMED_TO",
+			Direction: DirectionIncoming,
+		})
+		if lineageErr != nil {
+			return nil, fmt.Errorf("failed to get renames of minister %s: %w", ministerID, lineageErr)
+		}
+		for _, rel := range renames {
+			switch {
+			case rel.StartTime <= dateISO || visited[rel.RelatedEntityID]:
+			case active[rel.RelatedEntityID]:
+				predecessorIDs = append(predecessorIDs, rel.RelatedEntityID)
+				visited[rel.RelatedEntityID] = true
+			default:
+				pending = append(pending, rel.RelatedEntityID)
+			}
+		}
 	}
 
-	return activeMinisters[0], nil
+	if len(predecessorIDs) > 1 {
+		return nil, ambiguousf(predecessorIDs, "multiple ministers renamed to '%s' were active under president '%s'", ministerName, presidentName)
+	}
+	if len(predecessorIDs) == 0 {
+		return nil, err
+	}
+	predecessor, err := c.GetEntityContext(ctx, predecessorIDs[0], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get minister entity: %w", err)
+	}
+	c.logf("Resolved minister '%s' on %s to '%s', which was later renamed to it\n", ministerName, dateISO[:len(dateLayout)], predecessor.Name.Value)
+	return predecessor, nil
+}
+
+// terminationMinisters returns the IDs of the ministers named ministerName that a termination of a
+// relationship of theirs on dateISO applies to: the one resolveMinister finds, and those whose
+// relationship with the president ended on dateISO. A gazette that ends a minister, or renames it,
+// ends the relationships of the minister with it, so its terminations of them are applied already.
+func (c *Client) terminationMinisters(ctx context.Context, presidentName, ministerName, dateISO string) ([]string, error) {
+	minister, err := c.resolveMinister(ctx, presidentName, ministerName, dateISO)
+	if err != nil && !errors.Is(err, ErrEntityNotFound) {
+		return nil, err
+	}
+
+	ministerIDs, endedErr := c.endedMinisters(ctx, presidentName, ministerName, dateISO)
+	if endedErr != nil {
+		return nil, endedErr
+	}
+	if minister != nil {
+		ministerIDs = append([]string{minister.ID}, ministerIDs...)
+	}
+	if len(ministerIDs) == 0 {
+		return nil, err
+	}
+	return ministerIDs, nil
 }
 
 // AddOrgEntity creates a new entity and establishes its relationship with a parent entity.
 // Assumes the parent entity already exists.
 func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
-	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
-	transactionID := transaction["transaction_id"].(string)
+	return c.AddOrgEntityContext(context.Background(), transaction, entityCounters)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// AddOrgEntityContext is like AddOrgEntity but stops issuing API calls once ctx is done
+func (c *Client) AddOrgEntityContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := DecodeAddTx(transaction)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
+		return 0, err
+	}
+	return c.AddOrgEntityTx(ctx, tx, entityCounters)
+}
+
+// AddOrgEntityTx is like AddOrgEntityContext but takes a decoded transaction
+func (c *Client) AddOrgEntityTx(ctx context.Context, tx *AddTx, entityCounters map[string]int) (int, error) {
+	return c.addOrgEntity(ctx, tx, transactionKey(ChangeAdd, tx.TransactionID, tx.Date), tx.ChildType, entityCounters)
+}
+
+// addOrgEntity is AddOrgEntityTx as part of the transaction with the given key, which creates the
+// entity in the given role. Renames, merges and splits create their new entities with it.
+func (c *Client) addOrgEntity(ctx context.Context, tx *AddTx, key, role string, entityCounters map[string]int) (int, error) {
+	if err := tx.Validate(); err != nil {
+		return 0, tx.Source.locate(err)
 	}
-	dateISO := date.Format(time.RFC3339)
 
-	// Generate new entity ID
+	// Extract details from the transaction
+	parent := tx.Parent
+	child := tx.Child
+	parentType := tx.ParentType
+	childType := tx.ChildType
+	relType := tx.RelType
+	dateISO := tx.Date.Format(time.RFC3339)
+
 	if _, exists := entityCounters[childType]; !exists {
-		return 0, fmt.Errorf("unknown child type: %s", childType)
+		return 0, tx.Source.locate(invalidField("child_type", fmt.Sprintf("unknown child type: %s", childType), nil))
 	}
+	entityCounter := entityCounters[childType]
+	childID := EntityID(key, role)
 
-	// Get the part before the first underscore for the prefix
-	prefixPart := strings.Split(transactionID, "_")[0]
-	prefix := fmt.Sprintf("%s_%s", prefixPart, strings.ToLower(childType[:3]))
-	entityCounter := entityCounters[childType] + 1
-	newEntityID := fmt.Sprintf("%s_%d", prefix, entityCounter)
+	// A transaction applied before has already created the child and started its relationship, even
+	// if the parent has ended since
+	existingChild, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: childID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for entity %s: %w", childID, err)
+	}
+	if len(existingChild) > 0 {
+		applied, err := c.startedBy(ctx, childID, relType, key, DirectionIncoming)
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			c.skipApplied(key, fmt.Sprintf("%s '%s' [%s]", childType, child, childID))
+			return entityCounter, nil
+		}
+	}
 
 	// Get the parent entity ID based on the child type
 	var parentID string
@@ -245,7 +414,7 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 	if childType == "minister" {
 		// For ministers, parent should be a president (Person type) - presidents are citizens with AS_PRESIDENT relationship
 		if parentType != "president" && parentType != "citizen" {
-			return 0, fmt.Errorf("minister must be attached to a president, got parent_type: %s", parentType)
+			return 0, tx.Source.locate(invalidField("parent_type", fmt.Sprintf("minister must be attached to a president, got parent_type: %s", parentType), nil))
 		}
 
 		// Removed below: for now if a president creates the same minister again it will create a new entity
@@ -257,7 +426,7 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 		// }
 
 		// Get the president entity
-		presidentEntity, err := c.GetPresidentByGovernment(parent)
+		presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, parent)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get parent president entity: %w", err)
 		}
@@ -266,32 +435,14 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 	} else if childType == "department" {
 		// For departments, parent should be a minister, but we need to verify it's the correct minister
 		if parentType != "minister" {
-			return 0, fmt.Errorf("department must be attached to a minister, got parent_type: %s", parentType)
+			return 0, tx.Source.locate(invalidField("parent_type", fmt.Sprintf("department must be attached to a minister, got parent_type: %s", parentType), nil))
 		}
 
 		// Get president name from transaction
-		presidentName, ok := transaction["president"].(string)
-		if !ok || presidentName == "" {
-			return 0, fmt.Errorf("president name is required and must be a non-empty string when adding a department")
-		}
-
-		// Check if a department with the same name already exists
-		existingDepartmentResults, err := c.SearchEntities(&models.SearchCriteria{
-			Kind: &models.Kind{
-				Major: "Organisation",
-				Minor: "department",
-			},
-			Name: child,
-		})
-		if err != nil {
-			return 0, fmt.Errorf("failed to search for existing department: %w", err)
-		}
-		if len(existingDepartmentResults) > 0 {
-			return 0, fmt.Errorf("department with name '%s' already exists", child)
-		}
+		presidentName := tx.President
 
 		// Use GetMinisterByPresident to ensure we get the correct minister under the correct president
-		ministerEntity, err := c.GetActiveMinisterByPresident(presidentName, parent, dateISO)
+		ministerEntity, err := c.resolveMinister(ctx, presidentName, parent, dateISO)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get parent minister entity: %w", err)
 		}
@@ -312,46 +463,72 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 			Name: parent,
 		}
 
-		searchResults, err := c.SearchEntities(searchCriteria)
+		searchResults, err := c.SearchEntitiesContext(ctx, searchCriteria)
 		if err != nil {
 			return 0, fmt.Errorf("failed to search for parent entity: %w", err)
 		}
 
 		if len(searchResults) == 0 {
-			return 0, fmt.Errorf("parent entity not found: %s", parent)
+			return 0, notFoundf("parent entity not found: %s", parent)
 		}
 
 		parentID = searchResults[0].ID
 	}
 
-	// Create the new child entity
-	childEntity := &models.Entity{
-		ID: newEntityID,
-		Kind: models.Kind{
-			Major: "Organisation",
-			Minor: childType,
-		},
-		Created:    dateISO,
-		Terminated: "",
-		Name: models.TimeBasedValue{
-			StartTime: dateISO,
-			Value:     child,
-		},
-		Metadata:      []models.MetadataEntry{},
-		Attributes:    []models.AttributeEntry{},
-		Relationships: []models.RelationshipEntry{},
+	// The child of a transaction that failed after creating it is reused
+	if len(existingChild) == 0 && childType == "department" {
+		// Check if a department with the same name already exists
+		existingDepartmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+			Kind: &models.Kind{
+				Major: "Organisation",
+				Minor: "department",
+			},
+			Name: child,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to search for existing department: %w", err)
+		}
+		if len(existingDepartmentResults) > 0 {
+			return 0, existsf("department with name '%s' already exists", child)
+		}
 	}
 
-	// Create the child entity
-	createdChild, err := c.CreateEntity(childEntity)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create child entity: %w", err)
+	if len(existingChild) == 0 {
+		// Create the new child entity
+		childEntity := &models.Entity{
+			ID: childID,
+			Kind: models.Kind{
+				Major: "Organisation",
+				Minor: childType,
+			},
+			Created:    dateISO,
+			Terminated: "",
+			Name: models.TimeBasedValue{
+				StartTime: dateISO,
+				Value:     child,
+			},
+			Metadata:      []models.MetadataEntry{},
+			Attributes:    []models.AttributeEntry{},
+			Relationships: []models.RelationshipEntry{},
+		}
+
+		// Create the child entity
+		if _, err := c.CreateEntityContext(ctx, childEntity); err != nil {
+			return 0, fmt.Errorf("failed to create child entity: %w", err)
+		}
+		entityCounter++
 	}
 
 	// Update the parent entity to add the relationship to the child
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", parentID, createdChild.ID, currentTimestamp)
+	uniqueRelationshipID := RelationshipID(key, relType, parentID, childID)
+
+	if childType == "department" {
+		err = c.startMinisterRelationship(ctx, parentID, uniqueRelationshipID, relType, childID, dateISO, "")
+		if err != nil {
+			return 0, fmt.Errorf("failed to update parent entity: %w", err)
+		}
+		return entityCounter, nil
+	}
 
 	parentEntity := &models.Entity{
 		ID:         parentID,
@@ -365,7 +542,7 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 			{
 				Key: uniqueRelationshipID,
 				Value: models.Relationship{
-					RelatedEntityID: createdChild.ID,
+					RelatedEntityID: childID,
 					StartTime:       dateISO,
 					EndTime:         "",
 					ID:              uniqueRelationshipID,
@@ -375,7 +552,7 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 		},
 	}
 
-	_, err = c.UpdateEntity(parentID, parentEntity)
+	_, err = c.UpdateEntityContext(ctx, parentID, parentEntity)
 	if err != nil {
 		return 0, fmt.Errorf("failed to update parent entity: %w", err)
 	}
@@ -385,45 +562,52 @@ func (c *Client) AddOrgEntity(transaction map[string]interface{}, entityCounters
 
 // TerminateOrgEntity terminates a specific relationship between parent and child at a given date
 func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
-	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
+	return c.TerminateOrgEntityContext(context.Background(), transaction)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// TerminateOrgEntityContext is like TerminateOrgEntity but stops issuing API calls once ctx is done
+func (c *Client) TerminateOrgEntityContext(ctx context.Context, transaction map[string]interface{}) error {
+	tx, err := DecodeTerminateTx(transaction)
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
 	}
-	dateISO := date.Format(time.RFC3339)
+	return c.TerminateOrgEntityTx(ctx, tx)
+}
+
+// TerminateOrgEntityTx is like TerminateOrgEntityContext but takes a decoded transaction
+func (c *Client) TerminateOrgEntityTx(ctx context.Context, tx *TerminateTx) error {
+	if err := tx.Validate(); err != nil {
+		return tx.Source.locate(err)
+	}
+
+	// Extract details from the transaction
+	parent := tx.Parent
+	child := tx.Child
+	parentType := tx.ParentType
+	childType := tx.ChildType
+	relType := tx.RelType
+	dateISO := tx.Date.Format(time.RFC3339)
 
-	// Get the parent and child entity IDs based on their types
-	var parentID, childID string
+	// Get the parent entity IDs based on their types. A minister named as the parent can be one that
+	// ended on dateISO when the termination was applied before.
+	var parentIDs []string
 
 	// Handle parent entity retrieval
 	if parentType == "president" {
 		// Parent is a president - use the helper function
-		presidentEntity, err := c.GetPresidentByGovernment(parent)
+		presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, parent)
 		if err != nil {
 			return fmt.Errorf("failed to get parent president entity: %w", err)
 		}
-		parentID = presidentEntity.ID
+		parentIDs = []string{presidentEntity.ID}
 
 	} else if parentType == "minister" {
 		// Parent is a minister, need president context to get the correct minister
-		presidentName, ok := transaction["president"].(string)
-		if !ok || presidentName == "" {
-			return fmt.Errorf("president name is required and must be a non-empty string when terminating minister relationships")
-		}
-
-		ministerEntity, err := c.GetActiveMinisterByPresident(presidentName, parent, dateISO)
+		ministerIDs, err := c.terminationMinisters(ctx, tx.President, parent, dateISO)
 		if err != nil {
 			return fmt.Errorf("failed to get parent minister entity: %w", err)
 		}
-		parentID = ministerEntity.ID
+		parentIDs = ministerIDs
 
 	} else {
 		// For other parent types, use the original logic
@@ -439,195 +623,88 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 			Name: parent,
 		}
 
-		parentResults, err := c.SearchEntities(searchCriteria)
+		parentResults, err := c.SearchEntitiesContext(ctx, searchCriteria)
 		if err != nil {
 			return fmt.Errorf("failed to search for parent entity: %w", err)
 		}
 		if len(parentResults) == 0 {
-			return fmt.Errorf("parent entity not found: %s", parent)
+			return notFoundf("parent entity not found: %s", parent)
 		}
-		parentID = parentResults[0].ID
+		parentIDs = []string{parentResults[0].ID}
 	}
 
-	// Handle child entity retrieval
-	if childType == "minister" {
-		// Child is a minister, parent is the president's name
-		presidentName := parent // parent contains the president's name
-
-		ministerEntity, err := c.GetActiveMinisterByPresident(presidentName, child, dateISO)
-		if err != nil {
-			return fmt.Errorf("failed to get child minister entity: %w", err)
-		}
-		childID = ministerEntity.ID
-
-	} else if childType == "department" {
-		// Child is a department, need to find it under the correct minister
-		presidentName, ok := transaction["president"].(string)
-		if !ok || presidentName == "" {
-			return fmt.Errorf("president name is required and must be a non-empty string when terminating department relationships")
-		}
-
-		// First get the minister that should have this department
-		ministerEntity, err := c.GetActiveMinisterByPresident(presidentName, parent, dateISO)
-		if err != nil {
-			return fmt.Errorf("failed to get minister for department termination: %w", err)
-		}
-
-		// Then find the department under this minister
-		departmentRelations, err := c.GetRelatedEntities(ministerEntity.ID, &models.Relationship{
-			Name: "AS_DEPARTMENT",
-		})
-		if err != nil {
-			return fmt.Errorf("failed to get minister's department relationships: %w", err)
-		}
-
-		// Find the department with the matching name
-		var foundDepartmentID string
-		for _, rel := range departmentRelations {
-			if rel.EndTime == "" { // Only active relationships
-				departmentResults, err := c.SearchEntities(&models.SearchCriteria{ID: rel.RelatedEntityID})
-				if err != nil || len(departmentResults) == 0 {
-					continue
-				}
-				if departmentResults[0].Name == child {
-					foundDepartmentID = rel.RelatedEntityID
-					break
-				}
-			}
-		}
-
-		if foundDepartmentID == "" {
-			return fmt.Errorf("department '%s' not found under minister '%s'", child, parent)
-		}
-		childID = foundDepartmentID
-
-	} else {
-		// For other child types, use the original logic
-		childMajorType := "Organisation"
-		if childType == "citizen" {
-			childMajorType = "Person"
-		}
-
-		searchCriteria := &models.SearchCriteria{
-			Kind: &models.Kind{
-				Major: childMajorType,
-				Minor: childType,
-			},
-			Name: child,
-		}
-		childResults, err := c.SearchEntities(searchCriteria)
-		if err != nil {
-			return fmt.Errorf("failed to search for child entity: %w", err)
-		}
-		if len(childResults) == 0 {
-			return fmt.Errorf("child entity not found: %s", child)
-		}
-		childID = childResults[0].ID
-	}
-
-	//If we're terminating a minister, check for active departments
-	// NOTE: Removing this to allow moving ministers
-	// if childType == "minister" {
-	// 	// Get all relationships for the minister
-	// 	relations, err := c.GetRelatedEntities(childID, &models.Relationship{
-	// 		Name: "AS_DEPARTMENT",
-	// 	})
-	// 	if err != nil {
-	// 		return fmt.Errorf("failed to get minister's relationships: %w", err)
-	// 	}
-
-	// 	// fmt.Println("relations: ", relations)
-
-	// 	// Manually filter only active (EndTime == "") relationships
-	// 	var activeRelations []models.Relationship
-	// 	for _, rel := range relations {
-	// 		if rel.EndTime == "" {
-	// 			activeRelations = append(activeRelations, rel)
-	// 		}
-	// 	}
-
-	// 	// Check for active departments
-	// 	if len(activeRelations) > 0 {
-	// 		return fmt.Errorf("cannot terminate minister with active departments")
-	// 	}
-	// }
-
-	// Get the specific relationship that is still active (no end date) -> this should give us the relationship(s) active for dateISO
-	relations, err := c.GetRelatedEntities(parentID, &models.Relationship{
-		RelatedEntityID: childID,
-		Name:            relType,
+	// Handle child entity retrieval. Ministers of a president can share a name, so every entity with
+	// the child's name is considered; the relationships with the parent tell which one is meant.
+	childMajorType := "Organisation"
+	if childType == "citizen" {
+		childMajorType = "Person"
+	}
+	childResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: childMajorType,
+			Minor: childType,
+		},
+		Name: child,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get relationship: %w", err)
+		return fmt.Errorf("failed to search for child entity: %w", err)
+	}
+	if len(childResults) == 0 {
+		return notFoundf("child entity not found: %s", child)
 	}
 
-	// FIXME: Is it possible to have more than one active relationship? For orgchart case only it won't happen
-	// Find the active relationship (no end time)
-	// Manually filter for active relationship (i.e., EndTime == "")
-	var activeRel *models.Relationship
-	for _, rel := range relations {
-		if rel.EndTime == "" {
-			activeRel = &rel
-			break // stop at the first active one
+	// A minister is terminated with its departments still attached, so that ministers can be moved
+
+	// Get the relationships between parent and child; the one active at dateISO is terminated
+	var relations []models.Relationship
+	parentOf := map[string]string{}
+	for _, parentID := range parentIDs {
+		for _, childResult := range childResults {
+			childRelations, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
+				RelatedEntityID: childResult.ID,
+				Name:            relType,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get relationship: %w", err)
+			}
+			for _, rel := range childRelations {
+				relations = append(relations, rel)
+				parentOf[rel.ID] = parentID
+			}
 		}
 	}
 
+	activeRel, applied := terminationTarget(relations, dateISO)
+	if applied {
+		c.skipApplied(transactionKey(ChangeTerminate, tx.TransactionID, tx.Date), fmt.Sprintf("termination of %s '%s'", childType, child))
+		return nil
+	}
 	if activeRel == nil {
-		return fmt.Errorf("no active relationship found between %s and %s with type %s", parentID, childID, relType)
+		return notFoundf("no active relationship found between '%s' and '%s' with type %s", parent, child, relType)
 	}
+	parentID, childID := parentOf[activeRel.ID], activeRel.RelatedEntityID
 
 	// Update the relationship to set the end date
-	_, err = c.UpdateEntity(parentID, &models.Entity{
-		ID: parentID,
-		Relationships: []models.RelationshipEntry{
-			{
-				Key: activeRel.ID,
-				Value: models.Relationship{
-					EndTime: dateISO,
-					ID:      activeRel.ID,
-				},
-			},
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to terminate relationship: %w", err)
+	if err := c.terminateRelationship(ctx, parentID, *activeRel, dateISO); err != nil {
+		return err
 	}
 
 	// If we're terminating a minister, also terminate any active people assigned to it
 	if childType == "minister" {
 		// Get all active people relationships from the minister
-		ministerPeopleRelations, err := c.GetRelatedEntities(childID, &models.Relationship{
+		ministerPeopleRelations, err := c.GetRelatedEntitiesContext(ctx, childID, &models.Relationship{
 			Name: "AS_APPOINTED",
 		})
 		if err != nil {
 			return fmt.Errorf("failed to get minister's people relationships: %w", err)
 		}
 
-		// Find active people relationships (EndTime == "")
-		var activePeopleRelations []models.Relationship
+		// Terminate each person relationship active at the transaction date
 		for _, rel := range ministerPeopleRelations {
-			if rel.EndTime == "" {
-				activePeopleRelations = append(activePeopleRelations, rel)
-			}
-		}
-
-		// Terminate each active person relationship
-		for _, rel := range activePeopleRelations {
-			terminatePersonRel := &models.Entity{
-				ID: childID,
-				Relationships: []models.RelationshipEntry{
-					{
-						Key: rel.ID,
-						Value: models.Relationship{
-							EndTime: dateISO,
-							ID:      rel.ID,
-						},
-					},
-				},
+			if !activeAt(rel, dateISO) {
+				continue
 			}
-
-			_, err = c.UpdateEntity(childID, terminatePersonRel)
-			if err != nil {
+			if err := c.terminateRelationship(ctx, childID, rel, dateISO); err != nil {
 				return fmt.Errorf("failed to terminate person relationship: %w", err)
 			}
 		}
@@ -639,20 +716,37 @@ func (c *Client) TerminateOrgEntity(transaction map[string]interface{}) error {
 // MoveDepartment moves a department from one minister to another
 // MoveDepartment moves a department to a new minister
 func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
-	// Extract details from the transaction
-	newParent := transaction["new_parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
+	return c.MoveDepartmentContext(context.Background(), transaction)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// MoveDepartmentContext is like MoveDepartment but stops issuing API calls once ctx is done
+func (c *Client) MoveDepartmentContext(ctx context.Context, transaction map[string]interface{}) error {
+	tx, err := decodeMoveTx(transaction, "department")
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
+	}
+	return c.MoveDepartmentTx(ctx, tx)
+}
+
+// MoveDepartmentTx is like MoveDepartmentContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) MoveDepartmentTx(ctx context.Context, tx *MoveTx) error {
+	return c.moveDepartment(ctx, tx, transactionKey(ChangeMove, tx.TransactionID, tx.Date))
+}
+
+// moveDepartment is MoveDepartmentTx as part of the transaction with the given key. Renames and
+// merges of ministers move their departments with it.
+func (c *Client) moveDepartment(ctx context.Context, tx *MoveTx, key string) error {
+	if err := tx.validate("department"); err != nil {
+		return tx.Source.locate(err)
 	}
-	dateISO := date.Format(time.RFC3339)
+
+	// Extract details from the transaction
+	newParent := tx.NewParent
+	child := tx.Child
+	dateISO := tx.Date.Format(time.RFC3339)
 
 	// Search for the department by name
-	departmentResults, err := c.SearchEntities(&models.SearchCriteria{
+	departmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "department",
@@ -663,25 +757,46 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 		return fmt.Errorf("failed to search for department: %w", err)
 	}
 	if len(departmentResults) == 0 {
-		return fmt.Errorf("department '%s' not found", child)
+		return notFoundf("department '%s' not found", child)
 	}
 	if len(departmentResults) > 1 {
-		return fmt.Errorf("multiple departments found with name '%s'", child)
+		return ambiguousf(searchResultIDs(departmentResults), "multiple departments found with name '%s'", child)
 	}
 	departmentID := departmentResults[0].ID
 
 	// Check for active incoming relationships to this department
 	// Get all relationships where this department is the target
-	departmentRelations, err := c.GetRelatedEntities(departmentID, &models.Relationship{
+	departmentRelations, err := c.GetRelatedEntitiesContext(ctx, departmentID, &models.Relationship{
 		Name: "AS_DEPARTMENT",
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get department relationships: %w", err)
 	}
 
-	// Look for active AS_DEPARTMENT relationships coming into this department
+	// A move applied before has already started the new relationship. This is checked before the new
+	// minister is looked up, as a later transaction of the gazette may have renamed or ended it.
 	for _, rel := range departmentRelations {
-		if rel.EndTime == "" {
+		if rel.ID == RelationshipID(key, "AS_DEPARTMENT", rel.RelatedEntityID, departmentID) {
+			c.skipApplied(key, fmt.Sprintf("move of department '%s'", child))
+			return nil
+		}
+	}
+
+	// Get the new minister entity ID by president
+	// We need the president name to get the correct minister
+	newPresidentName := tx.NewPresident
+
+	newMinisterEntity, err := c.resolveMinister(ctx, newPresidentName, newParent, dateISO)
+	if err != nil {
+		return fmt.Errorf("failed to get new minister '%s' under president '%s': %w", newParent, newPresidentName, err)
+	}
+	newMinisterID := newMinisterEntity.ID
+	uniqueRelationshipID := RelationshipID(key, "AS_DEPARTMENT", newMinisterID, departmentID)
+
+	// Look for AS_DEPARTMENT relationships coming into this department that are active at the move date.
+	// Relationships that only start later, from gazettes loaded before this one, are left alone.
+	for _, rel := range departmentRelations {
+		if activeAt(rel, dateISO) {
 			// Found an active relationship - terminate it directly
 			// We have the minister ID (rel.RelatedEntityID) and can terminate the relationship directly
 			terminateRelationship := &models.Entity{
@@ -697,7 +812,7 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 				},
 			}
 
-			_, err = c.UpdateEntity(rel.RelatedEntityID, terminateRelationship)
+			_, err = c.UpdateEntityContext(ctx, rel.RelatedEntityID, terminateRelationship)
 			if err != nil {
 				return fmt.Errorf("failed to terminate old relationship: %w", err)
 			}
@@ -705,41 +820,9 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 		}
 	}
 
-	// Get the new minister entity ID by president
-	// We need the president name to get the correct minister
-	newPresidentName, ok := transaction["new_president_name"].(string)
-	if !ok || newPresidentName == "" {
-		return fmt.Errorf("new_president_name is required and must be a non-empty string")
-	}
-
-	newMinisterEntity, err := c.GetActiveMinisterByPresident(newPresidentName, newParent, dateISO)
-	if err != nil {
-		return fmt.Errorf("failed to get new minister '%s' under president '%s': %w", newParent, newPresidentName, err)
-	}
-	newMinisterID := newMinisterEntity.ID
-
 	// Create new AS_DEPARTMENT relationship from new minister to department
-	// Use transaction ID and timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", newMinisterID, departmentID, currentTimestamp)
-
-	newRelationship := &models.Entity{
-		ID: newMinisterID,
-		Relationships: []models.RelationshipEntry{
-			{
-				Key: uniqueRelationshipID,
-				Value: models.Relationship{
-					RelatedEntityID: departmentID,
-					StartTime:       dateISO,
-					EndTime:         "",
-					ID:              uniqueRelationshipID,
-					Name:            "AS_DEPARTMENT",
-				},
-			},
-		},
-	}
-
-	_, err = c.UpdateEntity(newMinisterID, newRelationship)
+	// When the move is back-filled it lasts until the department's next, already loaded, minister
+	err = c.startMinisterRelationship(ctx, newMinisterID, uniqueRelationshipID, "AS_DEPARTMENT", departmentID, dateISO, nextStartAfter(departmentRelations, dateISO))
 	if err != nil {
 		return fmt.Errorf("failed to create new relationship: %w", err)
 	}
@@ -749,70 +832,86 @@ func (c *Client) MoveDepartment(transaction map[string]interface{}) error {
 
 // RenameMinister renames a minister and transfers all its departments to the new minister
 func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
-	// Extract details from the transaction
-	oldName := transaction["old"].(string)
-	newName := transaction["new"].(string)
-	dateStr := transaction["date"].(string)
-	relType := "AS_MINISTER"
-	transactionID := transaction["transaction_id"]
+	return c.RenameMinisterContext(context.Background(), transaction, entityCounters)
+}
+
+// RenameMinisterContext is like RenameMinister but stops issuing API calls once ctx is done
+func (c *Client) RenameMinisterContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeRenameTx(transaction, "minister")
+	if err != nil {
+		return 0, err
+	}
+	return c.RenameMinisterTx(ctx, tx, entityCounters)
+}
 
-	// Validate president name is provided
-	presidentName, ok := transaction["president"].(string)
-	if !ok || presidentName == "" {
-		return 0, fmt.Errorf("president name is required and must be a non-empty string")
+// RenameMinisterTx is like RenameMinisterContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) RenameMinisterTx(ctx context.Context, tx *RenameTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("minister"); err != nil {
+		return 0, tx.Source.locate(err)
 	}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+	// Extract details from the transaction
+	oldName := tx.Old
+	newName := tx.New
+	relType := "AS_MINISTER"
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	// A rename applied before has already recorded the lineage
+	key := transactionKey(ChangeRename, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "minister"}, oldName, "RENAMED_TO", key)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("rename of minister '%s'", oldName))
+		return entityCounters["minister"], nil
 	}
-	dateISO := date.Format(time.RFC3339)
 
 	// Get the old minister's ID
-	oldMinister, err := c.GetActiveMinisterByPresident(presidentName, oldName, dateISO)
+	oldMinister, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, oldName, dateISO)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get old minister: %w", err)
 	}
 	oldMinisterID := oldMinister.ID
 
 	// Create new minister
-	addEntityTransaction := map[string]interface{}{
-		"parent":         presidentName,
-		"child":          newName,
-		"date":           dateStr,
-		"parent_type":    "president",
-		"child_type":     "minister",
-		"rel_type":       relType,
-		"transaction_id": transactionID,
-		"president":      presidentName,
+	addEntityTransaction := &AddTx{
+		TransactionID: tx.TransactionID,
+		Parent:        presidentName,
+		ParentType:    "president",
+		Child:         newName,
+		ChildType:     "minister",
+		RelType:       relType,
+		Date:          tx.Date,
+		President:     presidentName,
 	}
 
 	// Create the new minister
-	newMinisterCounter, err := c.AddOrgEntity(addEntityTransaction, entityCounters)
+	newMinisterCounter, err := c.addOrgEntity(ctx, addEntityTransaction, key, "minister", entityCounters)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create new minister: %w", err)
 	}
 
 	// Get the new minister's ID
-	newMinister, err := c.GetActiveMinisterByPresident(presidentName, newName, dateISO)
+	newMinister, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, newName, dateISO)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get new minister: %w", err)
 	}
 	newMinisterID := newMinister.ID
 
 	// Get all active departments of the old minister
-	oldRelations, err := c.GetRelatedEntities(oldMinisterID, &models.Relationship{
+	oldRelations, err := c.GetRelatedEntitiesContext(ctx, oldMinisterID, &models.Relationship{
 		Name: "AS_DEPARTMENT",
 	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get old minister's relationships: %w", err)
 	}
 
-	// Manually filter only active relationships (EndTime == "")
+	// Manually filter only active relationships at the transaction date
 	var oldActiveRelations []models.Relationship
 	for _, rel := range oldRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			oldActiveRelations = append(oldActiveRelations, rel)
 		}
 	}
@@ -820,7 +919,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 	// Transfer each active department to the new minister using MoveDepartment
 	for _, rel := range oldActiveRelations {
 		// Get the department name using its ID
-		departmentResults, err := c.SearchEntities(&models.SearchCriteria{
+		departmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 			ID: rel.RelatedEntityID,
 		})
 		if err != nil {
@@ -828,20 +927,22 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 		}
 
 		if len(departmentResults) == 0 {
-			return 0, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
+			return 0, notFoundf("failed to find department with ID: %s", rel.RelatedEntityID)
 		}
 
 		// Use MoveDepartment to move the department from old minister to new minister
-		moveTransaction := map[string]interface{}{
-			"old_parent":         oldName,
-			"new_parent":         newName,
-			"child":              departmentResults[0].Name,
-			"date":               dateStr,
-			"new_president_name": presidentName,
-			"old_president_name": presidentName,
-		}
-
-		err = c.MoveDepartment(moveTransaction)
+		moveTransaction := &MoveTx{
+			TransactionID: tx.TransactionID,
+			OldParent:     oldName,
+			NewParent:     newName,
+			Child:         departmentResults[0].Name,
+			Type:          "department",
+			Date:          tx.Date,
+			OldPresident:  presidentName,
+			NewPresident:  presidentName,
+		}
+
+		err = c.moveDepartment(ctx, moveTransaction, key)
 		if err != nil {
 			return 0, fmt.Errorf("failed to move department: %w", err)
 		}
@@ -849,17 +950,17 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 
 	// Find and move active person connected to old minister to new minister
 	// Get all active people relationships from the old minister
-	oldMinisterPeopleRelations, err := c.GetRelatedEntities(oldMinisterID, &models.Relationship{
+	oldMinisterPeopleRelations, err := c.GetRelatedEntitiesContext(ctx, oldMinisterID, &models.Relationship{
 		Name: "AS_APPOINTED",
 	})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get old minister's people relationships: %w", err)
 	}
 
-	// Find active people relationships (EndTime == "")
+	// Find active people relationships at the transaction date
 	var activePeopleRelations []models.Relationship
 	for _, rel := range oldMinisterPeopleRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			activePeopleRelations = append(activePeopleRelations, rel)
 		}
 	}
@@ -867,8 +968,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 	// Move each active person to the new minister
 	for _, rel := range activePeopleRelations {
 		// Create new relationship between new minister and person
-		currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-		uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", newMinisterID, rel.RelatedEntityID, currentTimestamp)
+		uniqueRelationshipID := RelationshipID(key, "AS_APPOINTED", newMinisterID, rel.RelatedEntityID)
 
 		newPersonRelationship := &models.Entity{
 			ID: newMinisterID,
@@ -886,7 +986,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 			},
 		}
 
-		_, err = c.UpdateEntity(newMinisterID, newPersonRelationship)
+		_, err = c.UpdateEntityContext(ctx, newMinisterID, newPersonRelationship)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create new person relationship: %w", err)
 		}
@@ -905,7 +1005,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 			},
 		}
 
-		_, err = c.UpdateEntity(oldMinisterID, terminateOldRelationship)
+		_, err = c.UpdateEntityContext(ctx, oldMinisterID, terminateOldRelationship)
 		if err != nil {
 			return 0, fmt.Errorf("failed to terminate old person relationship: %w", err)
 		}
@@ -913,14 +1013,14 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 
 	// Terminate the old minister's relationship with the president directly
 	// We need to get the president ID first
-	presidentEntity, err := c.GetPresidentByGovernment(presidentName)
+	presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get president entity: %w", err)
 	}
 	presidentID := presidentEntity.ID
 
 	// Find the active relationship to terminate it
-	presidentRelations, err := c.GetRelatedEntities(presidentID, &models.Relationship{
+	presidentRelations, err := c.GetRelatedEntitiesContext(ctx, presidentID, &models.Relationship{
 		Name:            "AS_MINISTER",
 		RelatedEntityID: oldMinisterID,
 	})
@@ -928,17 +1028,17 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 		return 0, fmt.Errorf("failed to get relationship between president and minister: %w", err)
 	}
 
-	// Find the active relationship (EndTime == "")
+	// Find the active relationship at the transaction date
 	var activeRel *models.Relationship
 	for _, rel := range presidentRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			activeRel = &rel
 			break
 		}
 	}
 
 	if activeRel == nil {
-		return 0, fmt.Errorf("no active relationship found between president and minister")
+		return 0, notFoundf("no active relationship found between president and minister")
 	}
 
 	// Terminate the relationship directly
@@ -955,15 +1055,13 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 		},
 	}
 
-	_, err = c.UpdateEntity(presidentID, terminateRelationship)
+	_, err = c.UpdateEntityContext(ctx, presidentID, terminateRelationship)
 	if err != nil {
 		return 0, fmt.Errorf("failed to terminate old minister's government relationship: %w", err)
 	}
 
 	// Create RENAMED_TO relationship
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", oldMinisterID, newMinisterID, currentTimestamp)
+	uniqueRelationshipID := RelationshipID(key, "RENAMED_TO", oldMinisterID, newMinisterID)
 
 	renameRelationship := &models.Entity{
 		ID: oldMinisterID,
@@ -981,7 +1079,7 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 		},
 	}
 
-	_, err = c.UpdateEntity(oldMinisterID, renameRelationship)
+	_, err = c.UpdateEntityContext(ctx, oldMinisterID, renameRelationship)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create RENAMED_TO relationship: %w", err)
 	}
@@ -991,26 +1089,44 @@ func (c *Client) RenameMinister(transaction map[string]interface{}, entityCounte
 
 // RenameDepartment renames a department and transfers all its people relationships to the new department
 func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	return c.RenameDepartmentContext(context.Background(), transaction, entityCounters)
+}
+
+// RenameDepartmentContext is like RenameDepartment but stops issuing API calls once ctx is done
+func (c *Client) RenameDepartmentContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeRenameTx(transaction, "department")
+	if err != nil {
+		return 0, err
+	}
+	return c.RenameDepartmentTx(ctx, tx, entityCounters)
+}
+
+// RenameDepartmentTx is like RenameDepartmentContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) RenameDepartmentTx(ctx context.Context, tx *RenameTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("department"); err != nil {
+		return 0, tx.Source.locate(err)
+	}
+
 	// Extract details from the transaction
-	oldName := transaction["old"].(string)
-	newName := transaction["new"].(string)
-	dateStr := transaction["date"].(string)
+	oldName := tx.Old
+	newName := tx.New
 	relType := "AS_DEPARTMENT"
-	transactionID := transaction["transaction_id"].(string)
-	presidentName, ok := transaction["president"].(string)
-	if !ok || presidentName == "" {
-		return 0, fmt.Errorf("president name is required and must be a non-empty string when renaming a department")
-	}
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+	// A rename applied before has already recorded the lineage
+	key := transactionKey(ChangeRename, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "department"}, oldName, "RENAMED_TO", key)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("rename of department '%s'", oldName))
+		return entityCounters["department"], nil
 	}
-	dateISO := date.Format(time.RFC3339)
 
 	// Get the old department's ID
-	oldDepartmentResults, err := c.SearchEntities(&models.SearchCriteria{
+	oldDepartmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "department",
@@ -1021,12 +1137,12 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 		return 0, fmt.Errorf("failed to search for old department: %w", err)
 	}
 	if len(oldDepartmentResults) == 0 {
-		return 0, fmt.Errorf("old department not found: %s", oldName)
+		return 0, notFoundf("old department not found: %s", oldName)
 	}
 	oldDepartmentID := oldDepartmentResults[0].ID
 
 	// Check if the new department name already exists
-	existingDepartmentResults, err := c.SearchEntities(&models.SearchCriteria{
+	existingDepartmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Organisation",
 			Minor: "department",
@@ -1046,17 +1162,17 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 		existingDepartmentID := existingDepartment.ID
 
 		// Get all AS_DEPARTMENT relationships for this department
-		existingDepartmentRelations, err := c.GetRelatedEntities(existingDepartmentID, &models.Relationship{
+		existingDepartmentRelations, err := c.GetRelatedEntitiesContext(ctx, existingDepartmentID, &models.Relationship{
 			Name: "AS_DEPARTMENT",
 		})
 		if err != nil {
 			return 0, fmt.Errorf("failed to get existing department relationships: %w", err)
 		}
 
-		// Check if any relationships are still active (EndTime == "")
+		// Check if any relationships are still active at the transaction date
 		hasActiveRelationships := false
 		for _, rel := range existingDepartmentRelations {
-			if rel.EndTime == "" {
+			if activeAt(rel, dateISO) {
 				hasActiveRelationships = true
 				break
 			}
@@ -1064,7 +1180,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 
 		if hasActiveRelationships {
 			// Department exists and has active relationships, cannot proceed
-			return 0, fmt.Errorf("department with name '%s' already exists and has active relationships", newName)
+			return 0, existsf("department with name '%s' already exists and has active relationships", newName)
 		} else {
 			// Department exists but all relationships are terminated, we can reuse it
 			newDepartmentID = existingDepartment.ID
@@ -1078,7 +1194,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 
 	// Get all active relationships coming into this department
 	// The department can have multiple active relationships to different ministers from different presidents
-	departmentRelations, err := c.GetRelatedEntities(oldDepartmentID, &models.Relationship{
+	departmentRelations, err := c.GetRelatedEntitiesContext(ctx, oldDepartmentID, &models.Relationship{
 		Name: "AS_DEPARTMENT",
 	})
 	if err != nil {
@@ -1089,16 +1205,16 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 	var ministerID string
 	var ministerName string
 	for _, rel := range departmentRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			// This is an active relationship, check if the minister is under the correct president
-			ministerResults, err := c.SearchEntities(&models.SearchCriteria{ID: rel.RelatedEntityID})
+			ministerResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: rel.RelatedEntityID})
 			if err != nil || len(ministerResults) == 0 {
 				continue
 			}
 			minister := ministerResults[0]
 
 			// Check if this minister is under the specified president
-			_, err = c.GetActiveMinisterByPresident(presidentName, minister.Name, dateISO)
+			_, err = c.GetActiveMinisterByPresidentContext(ctx, presidentName, minister.Name, dateISO)
 			if err == nil {
 				// Found the minister under the correct president
 				ministerID = minister.ID
@@ -1109,7 +1225,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 	}
 
 	if ministerID == "" {
-		return 0, fmt.Errorf("no active minister relationship found for department '%s' under president '%s'", oldName, presidentName)
+		return 0, notFoundf("no active minister relationship found for department '%s' under president '%s'", oldName, presidentName)
 	}
 
 	// Verify that this minister is under the correct president
@@ -1121,25 +1237,25 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 	// Create new department or reuse existing inactive department
 	if newDepartmentID == "" {
 		// Create new department under the same minister
-		addEntityTransaction := map[string]interface{}{
-			"parent":         ministerName,
-			"child":          newName,
-			"date":           dateStr,
-			"parent_type":    "minister",
-			"child_type":     "department",
-			"rel_type":       relType,
-			"transaction_id": transactionID,
-			"president":      presidentName,
+		addEntityTransaction := &AddTx{
+			TransactionID: tx.TransactionID,
+			Parent:        ministerName,
+			ParentType:    "minister",
+			Child:         newName,
+			ChildType:     "department",
+			RelType:       relType,
+			Date:          tx.Date,
+			President:     presidentName,
 		}
 
 		// Create the new department
-		newDepartmentCounter, err = c.AddOrgEntity(addEntityTransaction, entityCounters)
+		newDepartmentCounter, err = c.addOrgEntity(ctx, addEntityTransaction, key, "department", entityCounters)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create new department: %w", err)
 		}
 
 		// Get the new department's ID
-		newDepartmentResults, err := c.SearchEntities(&models.SearchCriteria{
+		newDepartmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 			Kind: &models.Kind{
 				Major: "Organisation",
 				Minor: "department",
@@ -1150,18 +1266,16 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 			return 0, fmt.Errorf("failed to search for new department: %w", err)
 		}
 		if len(newDepartmentResults) == 0 {
-			return 0, fmt.Errorf("new department not found: %s", newName)
+			return 0, notFoundf("new department not found: %s", newName)
 		}
 		if len(newDepartmentResults) > 1 {
-			return 0, fmt.Errorf("multiple departments found with name '%s'", newName)
+			return 0, ambiguousf(searchResultIDs(newDepartmentResults), "multiple departments found with name '%s'", newName)
 		}
 		newDepartmentID = newDepartmentResults[0].ID
 	} else {
 		// Reusing existing inactive department - create the relationship with the minister
-		// Generate a unique relationship ID
 		newDepartmentCounter = entityCounters["department"]
-		currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-		uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", ministerID, newDepartmentID, currentTimestamp)
+		uniqueRelationshipID := RelationshipID(key, relType, ministerID, newDepartmentID)
 
 		// Create the relationship between minister and the reactivated department
 		reactivateRelationship := &models.Entity{
@@ -1180,7 +1294,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 			},
 		}
 
-		_, err = c.UpdateEntity(ministerID, reactivateRelationship)
+		_, err = c.UpdateEntityContext(ctx, ministerID, reactivateRelationship)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create relationship with reactivated department: %w", err)
 		}
@@ -1188,7 +1302,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 
 	// Terminate the old department's relationship with minister directly
 	// Get the specific existing relationship to this department
-	existingRelations, err := c.GetRelatedEntities(ministerID, &models.Relationship{
+	existingRelations, err := c.GetRelatedEntitiesContext(ctx, ministerID, &models.Relationship{
 		Name:            "AS_DEPARTMENT",
 		RelatedEntityID: oldDepartmentID,
 	})
@@ -1196,17 +1310,17 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 		return 0, fmt.Errorf("failed to get existing relationship: %w", err)
 	}
 
-	// Find the active relationship (no end time)
+	// Find the relationship active at the transaction date
 	var existingRel *models.Relationship
 	for _, rel := range existingRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			existingRel = &rel
 			break
 		}
 	}
 
 	if existingRel == nil {
-		return 0, fmt.Errorf("no active relationship found between minister '%s' and department '%s'", ministerID, oldDepartmentID)
+		return 0, notFoundf("no active relationship found between minister '%s' and department '%s'", ministerID, oldDepartmentID)
 	}
 
 	// Terminate the relationship by updating it with the end time
@@ -1223,15 +1337,13 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 		},
 	}
 
-	_, err = c.UpdateEntity(ministerID, terminateRelationship)
+	_, err = c.UpdateEntityContext(ctx, ministerID, terminateRelationship)
 	if err != nil {
 		return 0, fmt.Errorf("failed to terminate old department's minister relationship: %w", err)
 	}
 
 	// Create RENAMED_TO relationship
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", oldDepartmentID, newDepartmentID, currentTimestamp)
+	uniqueRelationshipID := RelationshipID(key, "RENAMED_TO", oldDepartmentID, newDepartmentID)
 
 	renameRelationship := &models.Entity{
 		ID: oldDepartmentID,
@@ -1249,7 +1361,7 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 		},
 	}
 
-	_, err = c.UpdateEntity(oldDepartmentID, renameRelationship)
+	_, err = c.UpdateEntityContext(ctx, oldDepartmentID, renameRelationship)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create RENAMED_TO relationship: %w", err)
 	}
@@ -1259,51 +1371,60 @@ func (c *Client) RenameDepartment(transaction map[string]interface{}, entityCoun
 
 // MergeMinisters merges multiple ministers into a new minister
 func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
-	// Extract details from the transaction
-	oldMinistersStr := transaction["old"].(string)
-	newMinister := transaction["new"].(string)
-	dateStr := transaction["date"].(string)
-	transactionID := transaction["transaction_id"].(string)
-
-	// Validate president name is provided
-	presidentName, ok := transaction["president"].(string)
-	if !ok || presidentName == "" {
-		return 0, fmt.Errorf("president name is required and must be a non-empty string")
-	}
+	return c.MergeMinistersContext(context.Background(), transaction, entityCounters)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// MergeMinistersContext is like MergeMinisters but stops issuing API calls once ctx is done
+func (c *Client) MergeMinistersContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeMergeTx(transaction, "minister")
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
+		return 0, err
 	}
-	dateISO := date.Format(time.RFC3339)
+	return c.MergeMinistersTx(ctx, tx, entityCounters)
+}
 
-	// Parse old ministers list - use semicolons as separators to avoid comma conflicts
-	trimmedStr := strings.Trim(oldMinistersStr, "[]")
-	oldMinisters := strings.Split(trimmedStr, ";")
-	for i := range oldMinisters {
-		oldMinisters[i] = strings.TrimSpace(oldMinisters[i])
+// MergeMinistersTx is like MergeMinistersContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) MergeMinistersTx(ctx context.Context, tx *MergeTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("minister"); err != nil {
+		return 0, tx.Source.locate(err)
 	}
 
-	// 1. Create new minister using AddEntity
-	addEntityTransaction := map[string]interface{}{
-		"parent":         presidentName,
-		"child":          newMinister,
-		"date":           dateStr,
-		"parent_type":    "president",
-		"child_type":     "minister",
-		"rel_type":       "AS_MINISTER",
-		"transaction_id": transactionID,
-		"president":      presidentName,
+	// Extract details from the transaction
+	oldMinisters := tx.Old
+	newMinister := tx.New
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	// A merge applied before has already recorded the lineage
+	key := transactionKey(ChangeMerge, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "minister"}, oldMinisters[0], "MERGED_INTO", key)
+	if err != nil {
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("merge into minister '%s'", newMinister))
+		return entityCounters["minister"], nil
 	}
 
-	newMinisterCounter, err := c.AddOrgEntity(addEntityTransaction, entityCounters)
+	// 1. Create new minister using AddEntity
+	addEntityTransaction := &AddTx{
+		TransactionID: tx.TransactionID,
+		Parent:        presidentName,
+		ParentType:    "president",
+		Child:         newMinister,
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		Date:          tx.Date,
+		President:     presidentName,
+	}
+
+	newMinisterCounter, err := c.addOrgEntity(ctx, addEntityTransaction, key, "minister", entityCounters)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create new minister: %w", err)
 	}
 
 	// Get the new minister's ID
-	newMinisterEntity, err := c.GetActiveMinisterByPresident(presidentName, newMinister, dateISO)
+	newMinisterEntity, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, newMinister, dateISO)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get new minister: %w", err)
 	}
@@ -1312,68 +1433,70 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 	// For each old minister
 	for _, oldMinister := range oldMinisters {
 		// Get the old minister's ID
-		oldMinisterEntity, err := c.GetActiveMinisterByPresident(presidentName, oldMinister, dateISO)
+		oldMinisterEntity, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, oldMinister, dateISO)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get old minister: %w", err)
 		}
 		oldMinisterID := oldMinisterEntity.ID
 
 		// 1. Move old minister's departments to new minister
-		oldRelations, err := c.GetRelatedEntities(oldMinisterID, &models.Relationship{
+		oldRelations, err := c.GetRelatedEntitiesContext(ctx, oldMinisterID, &models.Relationship{
 			Name: "AS_DEPARTMENT",
 		})
 		if err != nil {
 			return 0, fmt.Errorf("failed to get old minister's relationships: %w", err)
 		}
 
-		// Manually filter only active relationships (EndTime == "")
+		// Manually filter only active relationships at the transaction date
 		var oldActiveRelations []models.Relationship
 		for _, rel := range oldRelations {
-			if rel.EndTime == "" {
+			if activeAt(rel, dateISO) {
 				oldActiveRelations = append(oldActiveRelations, rel)
 			}
 		}
 
 		for _, rel := range oldActiveRelations {
 			// Get the department name using its ID
-			departmentResults, err := c.SearchEntities(&models.SearchCriteria{
+			departmentResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 				ID: rel.RelatedEntityID,
 			})
 			if err != nil {
 				return 0, fmt.Errorf("failed to search for department: %w", err)
 			}
 			if len(departmentResults) == 0 {
-				return 0, fmt.Errorf("failed to find department with ID: %s", rel.RelatedEntityID)
+				return 0, notFoundf("failed to find department with ID: %s", rel.RelatedEntityID)
 			}
 
 			// Move department to new minister
-			moveTransaction := map[string]interface{}{
-				"old_parent":         oldMinister,
-				"new_parent":         newMinister,
-				"child":              departmentResults[0].Name,
-				"date":               dateStr,
-				"new_president_name": presidentName,
-				"old_president_name": presidentName,
+			moveTransaction := &MoveTx{
+				TransactionID: tx.TransactionID,
+				OldParent:     oldMinister,
+				NewParent:     newMinister,
+				Child:         departmentResults[0].Name,
+				Type:          "department",
+				Date:          tx.Date,
+				OldPresident:  presidentName,
+				NewPresident:  presidentName,
 			}
 
-			err = c.MoveDepartment(moveTransaction)
+			err = c.moveDepartment(ctx, moveTransaction, key)
 			if err != nil {
 				return 0, fmt.Errorf("failed to move department: %w", err)
 			}
 		}
 
 		// 2. Terminate any active people assigned to the old minister - assume when merged, the people are no longer assigned to the old ministers
-		oldMinisterPeopleRelations, err := c.GetRelatedEntities(oldMinisterID, &models.Relationship{
+		oldMinisterPeopleRelations, err := c.GetRelatedEntitiesContext(ctx, oldMinisterID, &models.Relationship{
 			Name: "AS_APPOINTED",
 		})
 		if err != nil {
 			return 0, fmt.Errorf("failed to get old minister's people relationships: %w", err)
 		}
 
-		// Find active people relationships (EndTime == "")
+		// Find active people relationships at the transaction date
 		var activePeopleRelations []models.Relationship
 		for _, rel := range oldMinisterPeopleRelations {
-			if rel.EndTime == "" {
+			if activeAt(rel, dateISO) {
 				activePeopleRelations = append(activePeopleRelations, rel)
 			}
 		}
@@ -1393,31 +1516,30 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 				},
 			}
 
-			_, err = c.UpdateEntity(oldMinisterID, terminatePersonRel)
+			_, err = c.UpdateEntityContext(ctx, oldMinisterID, terminatePersonRel)
 			if err != nil {
 				return 0, fmt.Errorf("failed to terminate person relationship: %w", err)
 			}
 		}
 
 		// 3. Terminate gov -> old minister relationship
-		terminateGovTransaction := map[string]interface{}{
-			"parent":      presidentName,
-			"child":       oldMinister,
-			"date":        dateStr,
-			"parent_type": "citizen",
-			"child_type":  "minister",
-			"rel_type":    "AS_MINISTER",
+		terminateGovTransaction := &TerminateTx{
+			TransactionID: tx.TransactionID,
+			Parent:        presidentName,
+			ParentType:    "citizen",
+			Child:         oldMinister,
+			ChildType:     "minister",
+			RelType:       "AS_MINISTER",
+			Date:          tx.Date,
 		}
 
-		err = c.TerminateOrgEntity(terminateGovTransaction)
+		err = c.TerminateOrgEntityTx(ctx, terminateGovTransaction)
 		if err != nil {
 			return 0, fmt.Errorf("failed to terminate old minister's government relationship: %w", err)
 		}
 
 		// 4. Create old minister -> new minister MERGED_INTO relationship
-		// Use transaction ID and current timestamp to ensure unique relationship ID
-		currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-		uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", oldMinisterID, newMinisterID, currentTimestamp)
+		uniqueRelationshipID := RelationshipID(key, "MERGED_INTO", oldMinisterID, newMinisterID)
 
 		mergedIntoRelationship := &models.Entity{
 			ID: oldMinisterID,
@@ -1435,7 +1557,7 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 			},
 		}
 
-		_, err = c.UpdateEntity(oldMinisterID, mergedIntoRelationship)
+		_, err = c.UpdateEntityContext(ctx, oldMinisterID, mergedIntoRelationship)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create MERGED_INTO relationship: %w", err)
 		}
@@ -1447,38 +1569,71 @@ func (c *Client) MergeMinisters(transaction map[string]interface{}, entityCounte
 // AddPersonEntity creates a new person entity and establishes its relationship with a parent entity.
 // Assumes the parent entity already exists.
 func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	return c.AddPersonEntityContext(context.Background(), transaction, entityCounters)
+}
+
+// AddPersonEntityContext is like AddPersonEntity but stops issuing API calls once ctx is done
+func (c *Client) AddPersonEntityContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := DecodeAddTx(transaction)
+	if err != nil {
+		return 0, err
+	}
+	return c.AddPersonEntityTx(ctx, tx, entityCounters)
+}
+
+// AddPersonEntityTx is like AddPersonEntityContext but takes a decoded transaction
+func (c *Client) AddPersonEntityTx(ctx context.Context, tx *AddTx, entityCounters map[string]int) (int, error) {
+	if err := tx.Validate(); err != nil {
+		return 0, tx.Source.locate(err)
+	}
+
 	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
-	transactionID := transaction["transaction_id"].(string)
-
-	// Get president name if parent is a minister -> currently only supports adding people to ministers
-	var presidentName string
-	if parentType == "minister" {
-		var ok bool
-		presidentName, ok = transaction["president"].(string)
-		if !ok || presidentName == "" {
-			return 0, fmt.Errorf("president name is required and must be a non-empty string when adding a person to a minister")
-		}
+	parent := tx.Parent
+	child := tx.Child
+	parentType := tx.ParentType
+	childType := tx.ChildType
+	relType := tx.RelType
+	dateISO := tx.Date.Format(time.RFC3339)
+	key := transactionKey(ChangeAdd, tx.TransactionID, tx.Date)
+
+	// Check if person already exists (search across all person types)
+	personSearchCriteria := &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Person",
+		},
+		Name: child,
 	}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+	personResults, err := c.SearchEntitiesContext(ctx, personSearchCriteria)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
+		return 0, fmt.Errorf("failed to search for person entity: %w", err)
+	}
+
+	if len(personResults) > 1 {
+		return 0, ambiguousf(searchResultIDs(personResults), "multiple entities found for person: %s", child)
+	}
+
+	// A transaction applied before has already appointed the person, even if the parent has ended since
+	if len(personResults) == 1 {
+		applied, err := c.startedBy(ctx, personResults[0].ID, relType, key, DirectionIncoming)
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			c.skipApplied(key, fmt.Sprintf("appointment of '%s' [%s]", child, personResults[0].ID))
+			return entityCounters[childType], nil
+		}
 	}
-	dateISO := date.Format(time.RFC3339)
+
+	// The president is only needed when the parent is a minister -> currently only supports adding people to ministers
+	presidentName := tx.President
 
 	// Get the parent entity ID
 	var parentID string
 
 	if parentType == "minister" {
 		// Parent is a minister, need president context to get the correct minister
-		ministerEntity, err := c.GetActiveMinisterByPresident(presidentName, parent, dateISO)
+		ministerEntity, err := c.resolveMinister(ctx, presidentName, parent, dateISO)
 		if err != nil {
 			return 0, fmt.Errorf("failed to get parent minister entity: %w", err)
 		}
@@ -1493,54 +1648,48 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 			Name: parent,
 		}
 
-		searchResults, err := c.SearchEntities(searchCriteria)
+		searchResults, err := c.SearchEntitiesContext(ctx, searchCriteria)
 		if err != nil {
 			return 0, fmt.Errorf("failed to search for parent entity: %w", err)
 		}
 
 		if len(searchResults) == 0 {
-			return 0, fmt.Errorf("parent entity not found: %s", parent)
+			return 0, notFoundf("parent entity not found: %s", parent)
 		}
 
 		parentID = searchResults[0].ID
 	}
 
-	// Check if person already exists (search across all person types)
-	personSearchCriteria := &models.SearchCriteria{
-		Kind: &models.Kind{
-			Major: "Person",
-		},
-		Name: child,
-	}
-
-	personResults, err := c.SearchEntities(personSearchCriteria)
-	if err != nil {
-		return 0, fmt.Errorf("failed to search for person entity: %w", err)
-	}
-
-	if len(personResults) > 1 {
-		return 0, fmt.Errorf("multiple entities found for person: %s", child)
-	}
-
 	var childID string
 	if len(personResults) == 1 {
 		// Person exists, use existing ID
 		childID = personResults[0].ID
+
+		// A rename of the parent earlier in the gazette may have carried the appointment over already
+		held, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
+			Name:            relType,
+			RelatedEntityID: childID,
+			Direction:       DirectionOutgoing,
+			ActiveAt:        dateISO,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to get relationships between '%s' and '%s': %w", parent, child, err)
+		}
+		if len(held) > 0 {
+			c.logf("Skipping transaction %s: '%s' already holds %s with '%s' on %s\n", key, child, relType, parent, tx.Date.Format(dateLayout))
+			return entityCounters[childType], nil
+		}
 	} else {
 		// Generate new entity ID
 		if _, exists := entityCounters[childType]; !exists {
-			return 0, fmt.Errorf("unknown child type: %s", childType)
+			return 0, tx.Source.locate(invalidField("child_type", fmt.Sprintf("unknown child type: %s", childType), nil))
 		}
 
-		// Get the part before the first underscore for the prefix
-		prefixPart := strings.Split(transactionID, "_")[0]
-		prefix := fmt.Sprintf("%s_%s", prefixPart, strings.ToLower(childType[:3]))
 		entityCounters[childType]++ // Increment the counter
-		newEntityID := fmt.Sprintf("%s_%d", prefix, entityCounters[childType])
 
 		// Create the new child entity
 		childEntity := &models.Entity{
-			ID: newEntityID,
+			ID: EntityID(key, childType),
 			Kind: models.Kind{
 				Major: "Person",
 				Minor: childType,
@@ -1557,7 +1706,7 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		}
 
 		// Create the child entity
-		createdChild, err := c.CreateEntity(childEntity)
+		createdChild, err := c.CreateEntityContext(ctx, childEntity)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create child entity: %w", err)
 		}
@@ -1565,9 +1714,15 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 	}
 
 	// Update the parent entity to add the relationship to the child
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", parentID, childID, currentTimestamp)
+	uniqueRelationshipID := RelationshipID(key, relType, parentID, childID)
+
+	if parentType == "minister" {
+		err = c.startMinisterRelationship(ctx, parentID, uniqueRelationshipID, relType, childID, dateISO, "")
+		if err != nil {
+			return 0, fmt.Errorf("failed to update parent entity: %w", err)
+		}
+		return entityCounters[childType], nil
+	}
 
 	parentEntity := &models.Entity{
 		ID:         parentID,
@@ -1591,7 +1746,7 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 		},
 	}
 
-	_, err = c.UpdateEntity(parentID, parentEntity)
+	_, err = c.UpdateEntityContext(ctx, parentID, parentEntity)
 	if err != nil {
 		return 0, fmt.Errorf("failed to update parent entity: %w", err)
 	}
@@ -1601,30 +1756,34 @@ func (c *Client) AddPersonEntity(transaction map[string]interface{}, entityCount
 
 // TerminatePersonEntity terminates a specific relationship between Person type entity and another entity at a given date
 func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error {
-	// Extract details from the transaction
-	parent := transaction["parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	parentType := transaction["parent_type"].(string)
-	childType := transaction["child_type"].(string)
-	relType := transaction["rel_type"].(string)
-
-	// Get president name if parent is a minister -> currently only supports terminating relationships with ministers
-	var presidentName string
-	if parentType == "minister" {
-		var ok bool
-		presidentName, ok = transaction["president"].(string)
-		if !ok || presidentName == "" {
-			return fmt.Errorf("president name is required and must be a non-empty string when terminating relationships with ministers")
-		}
-	}
+	return c.TerminatePersonEntityContext(context.Background(), transaction)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// TerminatePersonEntityContext is like TerminatePersonEntity but stops issuing API calls once ctx is done
+func (c *Client) TerminatePersonEntityContext(ctx context.Context, transaction map[string]interface{}) error {
+	tx, err := DecodeTerminateTx(transaction)
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
+	}
+	return c.TerminatePersonEntityTx(ctx, tx)
+}
+
+// TerminatePersonEntityTx is like TerminatePersonEntityContext but takes a decoded transaction
+func (c *Client) TerminatePersonEntityTx(ctx context.Context, tx *TerminateTx) error {
+	if err := tx.Validate(); err != nil {
+		return tx.Source.locate(err)
 	}
-	dateISO := date.Format(time.RFC3339)
+
+	// Extract details from the transaction
+	parent := tx.Parent
+	child := tx.Child
+	parentType := tx.ParentType
+	childType := tx.ChildType
+	relType := tx.RelType
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	// The president is only needed when the parent is a minister -> currently only supports terminating relationships with ministers
+	presidentName := tx.President
 
 	// First, find the person (child) entity
 	childSearchCriteria := &models.SearchCriteria{
@@ -1635,72 +1794,25 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 		Name: child,
 	}
 
-	childResults, err := c.SearchEntities(childSearchCriteria)
+	childResults, err := c.SearchEntitiesContext(ctx, childSearchCriteria)
 	if err != nil {
 		return fmt.Errorf("failed to search for child entity: %w", err)
 	}
 	if len(childResults) == 0 {
-		return fmt.Errorf("child entity not found: %s", child)
+		return notFoundf("child entity not found: %s", child)
 	}
 	childID := childResults[0].ID
 
-	// Find the ministry by checking the person's active relationships
-	var parentID string
-	var activeRel *models.Relationship
+	// Find the parent entities. A minister named as the parent can be one that ended on dateISO when
+	// the termination was applied before.
+	var parentIDs []string
 
 	if parentType == "minister" {
-		// Get all active relationships from the person to find the ministry
-		personRelations, err := c.GetRelatedEntities(childID, &models.Relationship{
-			Name: relType,
-		})
+		ministerIDs, err := c.terminationMinisters(ctx, presidentName, parent, dateISO)
 		if err != nil {
-			return fmt.Errorf("failed to get person's relationships: %w", err)
-		}
-
-		// Find the active relationship to the specified ministry
-		for _, rel := range personRelations {
-			if rel.EndTime == "" { // Only active relationships
-				// Get the ministry entity to check if it matches the parent name
-				ministryResults, err := c.SearchEntities(&models.SearchCriteria{
-					ID: rel.RelatedEntityID,
-				})
-				if err != nil || len(ministryResults) == 0 {
-					continue
-				}
-
-				ministry := ministryResults[0]
-				// Check if this ministry is under the correct president and matches the parent name
-				if ministry.Kind.Minor == "minister" && ministry.Name == parent {
-					// Verify this minister is under the specified president
-					_, err = c.GetActiveMinisterByPresident(presidentName, parent, dateISO)
-					if err == nil {
-						parentID = ministry.ID
-						// We found the ministry, now we need to get the relationship from ministry to person
-						// to get the relationship ID for termination
-						ministryRelations, err := c.GetRelatedEntities(ministry.ID, &models.Relationship{
-							Name:            relType,
-							RelatedEntityID: childID,
-						})
-						if err != nil {
-							return fmt.Errorf("failed to get ministry's relationship to person: %w", err)
-						}
-
-						// Find the active relationship
-						for _, mRel := range ministryRelations {
-							if mRel.EndTime == "" {
-								activeRel = &mRel
-								break
-							}
-						}
-						break
-					}
-				}
-			}
-		}
-
-		if parentID == "" {
-			return fmt.Errorf("no active relationship found between person '%s' (ID: %s) and ministry '%s' under president '%s'", child, childID, parent, presidentName)
+			return fmt.Errorf("failed to get parent minister entity: %w", err)
 		}
+		parentIDs = ministerIDs
 	} else {
 		// For other parent types, use the original logic
 		searchCriteria := &models.SearchCriteria{
@@ -1710,64 +1822,44 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 			},
 			Name: parent,
 		}
-		parentResults, err := c.SearchEntities(searchCriteria)
+		parentResults, err := c.SearchEntitiesContext(ctx, searchCriteria)
 		if err != nil {
 			return fmt.Errorf("failed to search for parent entity: %w", err)
 		}
 		if len(parentResults) == 0 {
-			return fmt.Errorf("parent entity not found: %s", parent)
+			return notFoundf("parent entity not found: %s", parent)
 		}
-		parentID = parentResults[0].ID
+		parentIDs = []string{parentResults[0].ID}
 	}
 
-	// If we haven't found the active relationship yet (for non-minister parent types), search for it
-	if activeRel == nil {
-		// Get the specific relationship that is still active (no end date)
-		relations, err := c.GetRelatedEntities(parentID, &models.Relationship{
+	// Get the relationships between parent and child; the one active at dateISO is terminated
+	var relations []models.Relationship
+	parentOf := map[string]string{}
+	for _, parentID := range parentIDs {
+		parentRelations, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
 			RelatedEntityID: childID,
 			Name:            relType,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to get relationship: %w", err)
 		}
-
-		// FIXME: Is it possible to have more than one active relationship? For orgchart case only it won't happen
-		// Manually filter only active relationships (EndTime == "")
-		var activeRelations []models.Relationship
-		for _, rel := range relations {
-			if rel.EndTime == "" {
-				activeRelations = append(activeRelations, rel)
-			}
-		}
-
-		// Find the active relationship (no end time)
-		if len(activeRelations) > 0 {
-			activeRel = &activeRelations[0]
+		for _, rel := range parentRelations {
+			relations = append(relations, rel)
+			parentOf[rel.ID] = parentID
 		}
 	}
 
+	activeRel, applied := terminationTarget(relations, dateISO)
+	if applied {
+		c.skipApplied(transactionKey(ChangeTerminate, tx.TransactionID, tx.Date), fmt.Sprintf("termination of '%s'", child))
+		return nil
+	}
 	if activeRel == nil {
-		return fmt.Errorf("no active relationship found between %s and %s with type %s", parentID, childID, relType)
+		return notFoundf("no active relationship found between person '%s' (ID: %s) and '%s' with type %s", child, childID, parent, relType)
 	}
 
 	// Update the relationship to set the end date
-	_, err = c.UpdateEntity(parentID, &models.Entity{
-		ID: parentID,
-		Relationships: []models.RelationshipEntry{
-			{
-				Key: activeRel.ID,
-				Value: models.Relationship{
-					EndTime: dateISO,
-					ID:      activeRel.ID,
-				},
-			},
-		},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to terminate relationship: %w", err)
-	}
-
-	return nil
+	return c.terminateRelationship(ctx, parentOf[activeRel.ID], *activeRel, dateISO)
 }
 
 // MovePerson moves a person from one portfolio to another (limits functionality to only minister)
@@ -1775,35 +1867,34 @@ func (c *Client) TerminatePersonEntity(transaction map[string]interface{}) error
 //
 //	for moving person from any institution to another
 func (c *Client) MovePerson(transaction map[string]interface{}) error {
-	// Extract details from the transaction
-	newParent := transaction["new_parent"].(string)
-	oldParent := transaction["old_parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
-	relType := "AS_APPOINTED"
-
-	// Validate president name is provided
-	presidentName, ok := transaction["president"].(string)
-	if !ok || presidentName == "" {
-		return fmt.Errorf("president name is required and must be a non-empty string")
-	}
+	return c.MovePersonContext(context.Background(), transaction)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// MovePersonContext is like MovePerson but stops issuing API calls once ctx is done
+func (c *Client) MovePersonContext(ctx context.Context, transaction map[string]interface{}) error {
+	tx, err := decodeMoveTx(transaction, "citizen")
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
 	}
-	dateISO := date.Format(time.RFC3339)
+	return c.MovePersonTx(ctx, tx)
+}
 
-	// Get the new minister (parent) entity ID -> only supports moving person to and from minister
-	newParentEntity, err := c.GetActiveMinisterByPresident(presidentName, newParent, dateISO)
-	if err != nil {
-		return fmt.Errorf("failed to get new parent entity: %w", err)
+// MovePersonTx is like MovePersonContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) MovePersonTx(ctx context.Context, tx *MoveTx) error {
+	if err := tx.validate("citizen"); err != nil {
+		return tx.Source.locate(err)
 	}
-	newParentID := newParentEntity.ID
+
+	// Extract details from the transaction
+	newParent := tx.NewParent
+	oldParent := tx.OldParent
+	child := tx.Child
+	relType := "AS_APPOINTED"
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
 
 	// Get the department (child) entity ID
-	childResults, err := c.SearchEntities(&models.SearchCriteria{
+	childResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
 		Kind: &models.Kind{
 			Major: "Person",
 			Minor: "citizen",
@@ -1814,48 +1905,49 @@ func (c *Client) MovePerson(transaction map[string]interface{}) error {
 		return fmt.Errorf("failed to search for child entity: %w", err)
 	}
 	if len(childResults) == 0 {
-		return fmt.Errorf("child entity not found: %s", child)
+		return notFoundf("child entity not found: %s", child)
 	}
 	childID := childResults[0].ID
 
-	// Create new relationship between new minister and person
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", newParentID, childID, currentTimestamp)
+	// A move applied before has already started the new relationship. This is checked before the new
+	// minister is looked up, as a later transaction of the gazette may have renamed or ended it.
+	key := transactionKey(ChangeMove, tx.TransactionID, tx.Date)
+	applied, err := c.startedBy(ctx, childID, relType, key, DirectionIncoming)
+	if err != nil {
+		return err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("move of '%s'", child))
+		return nil
+	}
 
-	newRelationship := &models.Entity{
-		ID: newParentID,
-		Relationships: []models.RelationshipEntry{
-			{
-				Key: uniqueRelationshipID,
-				Value: models.Relationship{
-					RelatedEntityID: childID,
-					StartTime:       dateISO,
-					EndTime:         "",
-					ID:              uniqueRelationshipID,
-					Name:            relType,
-				},
-			},
-		},
+	// Get the new minister (parent) entity ID -> only supports moving person to and from minister
+	newParentEntity, err := c.resolveMinister(ctx, presidentName, newParent, dateISO)
+	if err != nil {
+		return fmt.Errorf("failed to get new parent entity: %w", err)
 	}
+	newParentID := newParentEntity.ID
+	uniqueRelationshipID := RelationshipID(key, relType, newParentID, childID)
 
-	_, err = c.UpdateEntity(newParentID, newRelationship)
+	// Create new relationship between new minister and person
+	err = c.startMinisterRelationship(ctx, newParentID, uniqueRelationshipID, relType, childID, dateISO, "")
 	if err != nil {
 		return fmt.Errorf("failed to create new relationship: %w", err)
 	}
 
 	// Terminate the old relationship
-	terminateTransaction := map[string]interface{}{
-		"parent":      oldParent,
-		"child":       child,
-		"date":        dateStr,
-		"parent_type": "minister",
-		"child_type":  "citizen",
-		"rel_type":    relType,
-		"president":   presidentName,
-	}
-
-	err = c.TerminatePersonEntity(terminateTransaction)
+	terminateTransaction := &TerminateTx{
+		TransactionID: tx.TransactionID,
+		Parent:        oldParent,
+		ParentType:    "minister",
+		Child:         child,
+		ChildType:     "citizen",
+		RelType:       relType,
+		Date:          tx.Date,
+		President:     presidentName,
+	}
+
+	err = c.TerminatePersonEntityTx(ctx, terminateTransaction)
 	if err != nil {
 		return fmt.Errorf("failed to terminate old relationship: %w", err)
 	}
@@ -1934,44 +2026,74 @@ func (c *Client) MovePerson(transaction map[string]interface{}) error {
 
 // MoveMinister moves a minister from one president to another
 func (c *Client) MoveMinister(transaction map[string]interface{}) error {
-	// Extract details from the transaction
-	newParent := transaction["new_parent"].(string)
-	oldParent := transaction["old_parent"].(string)
-	child := transaction["child"].(string)
-	dateStr := transaction["date"].(string)
+	return c.MoveMinisterContext(context.Background(), transaction)
+}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
+// MoveMinisterContext is like MoveMinister but stops issuing API calls once ctx is done
+func (c *Client) MoveMinisterContext(ctx context.Context, transaction map[string]interface{}) error {
+	tx, err := decodeMoveTx(transaction, "minister")
 	if err != nil {
-		return fmt.Errorf("failed to parse date: %w", err)
+		return err
 	}
-	dateISO := date.Format(time.RFC3339)
+	return c.MoveMinisterTx(ctx, tx)
+}
+
+// MoveMinisterTx is like MoveMinisterContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) MoveMinisterTx(ctx context.Context, tx *MoveTx) error {
+	if err := tx.validate("minister"); err != nil {
+		return tx.Source.locate(err)
+	}
+
+	// Extract details from the transaction
+	newParent := tx.NewParent
+	oldParent := tx.OldParent
+	child := tx.Child
+	dateISO := tx.Date.Format(time.RFC3339)
 
 	// --- Get the new president (parent) entity ID ---
-	newPresidentEntity, err := c.GetPresidentByGovernment(newParent)
+	newPresidentEntity, err := c.GetPresidentByGovernmentContext(ctx, newParent)
 	if err != nil {
 		return fmt.Errorf("failed to get new president entity: %w", err)
 	}
 	newParentID := newPresidentEntity.ID
 
 	// --- Get the old president (parent) entity ID ---
-	oldPresidentEntity, err := c.GetPresidentByGovernment(oldParent)
+	oldPresidentEntity, err := c.GetPresidentByGovernmentContext(ctx, oldParent)
 	if err != nil {
 		return fmt.Errorf("failed to get old president entity: %w", err)
 	}
 	oldParentID := oldPresidentEntity.ID
 
+	// A move applied before has already started the new relationship and ended the old one, so this
+	// is checked before the minister is looked up under the old president
+	key := transactionKey(ChangeMove, tx.TransactionID, tx.Date)
+	ministerResults, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: child,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search for minister '%s': %w", child, err)
+	}
+	for _, result := range ministerResults {
+		applied, err := c.startedBy(ctx, result.ID, "AS_MINISTER", key, DirectionIncoming)
+		if err != nil {
+			return err
+		}
+		if applied {
+			c.skipApplied(key, fmt.Sprintf("move of minister '%s'", child))
+			return nil
+		}
+	}
+
 	// Get the minister (child) entity ID connected to the old president
-	ministerEntity, err := c.GetActiveMinisterByPresident(oldParent, child, dateISO)
+	ministerEntity, err := c.GetActiveMinisterByPresidentContext(ctx, oldParent, child, dateISO)
 	if err != nil {
-		return fmt.Errorf("minister entity '%s' not found or not active under old president '%s' on date %s: %w", child, oldParent, dateStr, err)
+		return fmt.Errorf("minister entity '%s' not found or not active under old president '%s' on date %s: %w", child, oldParent, tx.Date.Format(dateLayout), err)
 	}
 	childID := ministerEntity.ID
+	uniqueRelationshipID := RelationshipID(key, "AS_MINISTER", newParentID, childID)
 
 	// Create new relationship between new president and minister
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", newParentID, childID, currentTimestamp)
 
 	newRelationship := &models.Entity{
 		ID: newParentID,
@@ -1989,13 +2111,13 @@ func (c *Client) MoveMinister(transaction map[string]interface{}) error {
 		},
 	}
 
-	_, err = c.UpdateEntity(newParentID, newRelationship)
+	_, err = c.UpdateEntityContext(ctx, newParentID, newRelationship)
 	if err != nil {
 		return fmt.Errorf("failed to create new relationship: %w", err)
 	}
 
 	// Find the active relationship to terminate it.
-	oldPresidentRelations, err := c.GetRelatedEntities(oldParentID, &models.Relationship{
+	oldPresidentRelations, err := c.GetRelatedEntitiesContext(ctx, oldParentID, &models.Relationship{
 		Name:            "AS_MINISTER",
 		RelatedEntityID: childID,
 	})
@@ -2003,10 +2125,10 @@ func (c *Client) MoveMinister(transaction map[string]interface{}) error {
 		return fmt.Errorf("failed to get relationship between old president and minister: %w", err)
 	}
 
-	// Manually filter for active relationships (EndTime == "")
+	// Manually filter for active relationships at the transaction date
 	var activeRel *models.Relationship
 	for _, rel := range oldPresidentRelations {
-		if rel.EndTime == "" {
+		if activeAt(rel, dateISO) {
 			activeRel = &rel
 			break
 		}
@@ -2015,7 +2137,7 @@ func (c *Client) MoveMinister(transaction map[string]interface{}) error {
 	// Only terminate if there is an active relationship
 	if activeRel != nil {
 		// Terminate the old relationship directly without cascading to people
-		_, err = c.UpdateEntity(oldParentID, &models.Entity{
+		_, err = c.UpdateEntityContext(ctx, oldParentID, &models.Entity{
 			ID: oldParentID,
 			Relationships: []models.RelationshipEntry{
 				{
@@ -2044,43 +2166,31 @@ func (c *Client) MoveMinister(transaction map[string]interface{}) error {
 // The document type is determined by the parent entity type (Organization or Person).
 // Assumes the parent entity already exists.
 func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
-	// Extract details from the transaction with validation
-	parent, ok := transaction["parent"].(string)
-	if !ok || parent == "" {
-		return 0, fmt.Errorf("parent is required and must be a string")
-	}
-
-	child, ok := transaction["child"].(string)
-	if !ok || child == "" {
-		return 0, fmt.Errorf("child is required and must be a string")
-	}
-
-	dateStr, ok := transaction["date"].(string)
-	if !ok || dateStr == "" {
-		return 0, fmt.Errorf("date is required and must be a string")
-	}
-
-	parentType, ok := transaction["parent_type"].(string)
-	if !ok || parentType == "" {
-		return 0, fmt.Errorf("parent_type is required and must be a string")
-	}
+	return c.AddDocumentEntityContext(context.Background(), transaction, entityCounters)
+}
 
-	childType, ok := transaction["child_type"].(string)
-	if !ok || childType == "" {
-		return 0, fmt.Errorf("child_type is required and must be a string")
+// AddDocumentEntityContext is like AddDocumentEntity but stops issuing API calls once ctx is done
+func (c *Client) AddDocumentEntityContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := DecodeDocumentTx(transaction)
+	if err != nil {
+		return 0, err
 	}
+	return c.AddDocumentEntityTx(ctx, tx, entityCounters)
+}
 
-	transactionID, ok := transaction["transaction_id"].(string)
-	if !ok || transactionID == "" {
-		return 0, fmt.Errorf("transaction_id is required and must be a string")
+// AddDocumentEntityTx is like AddDocumentEntityContext but takes a decoded transaction
+func (c *Client) AddDocumentEntityTx(ctx context.Context, tx *DocumentTx, entityCounters map[string]int) (int, error) {
+	if err := tx.Validate(); err != nil {
+		return 0, tx.Source.locate(err)
 	}
 
-	// Parse the date
-	date, err := time.Parse("2006-01-02", strings.TrimSpace(dateStr))
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse date: %w", err)
-	}
-	dateISO := date.Format(time.RFC3339)
+	// Extract details from the transaction
+	parent := tx.Parent
+	child := tx.Child
+	parentType := tx.ParentType
+	childType := tx.ChildType
+	dateISO := tx.Date.Format(time.RFC3339)
+	key := transactionKey(ChangeAdd, tx.TransactionID, tx.Date)
 
 	// Get the parent entity ID (which is always gonna be an organisation)
 	searchCriteria := &models.SearchCriteria{
@@ -2091,13 +2201,13 @@ func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCou
 		},
 	}
 
-	searchResults, err := c.SearchEntities(searchCriteria)
+	searchResults, err := c.SearchEntitiesContext(ctx, searchCriteria)
 	if err != nil {
 		return 0, fmt.Errorf("failed to search for parent entity: %w", err)
 	}
 
 	if len(searchResults) == 0 {
-		return 0, fmt.Errorf("parent entity not found: %s", parent)
+		return 0, notFoundf("parent entity not found: %s", parent)
 	}
 
 	parentID := searchResults[0].ID
@@ -2111,31 +2221,36 @@ func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCou
 		Name: child,
 	}
 
-	documentResults, err := c.SearchEntities(documentSearchCriteria)
+	documentResults, err := c.SearchEntitiesContext(ctx, documentSearchCriteria)
 	if err != nil {
 		return 0, fmt.Errorf("failed to search for document entity: %w", err)
 	}
 
 	if len(documentResults) > 1 {
-		return 0, fmt.Errorf("multiple entities found for document: %s", child)
+		return 0, ambiguousf(searchResultIDs(documentResults), "multiple entities found for document: %s", child)
 	}
 
 	var childID string
-	entityCounter := 0
+	entityCounter := entityCounters["document"]
 	if len(documentResults) == 1 {
 		// Document exists, use existing ID
 		childID = documentResults[0].ID
+
+		// A transaction applied before has already added the document
+		applied, err := c.hasRelationship(ctx, parentID, RelationshipID(key, "AS_DOCUMENT", parentID, childID))
+		if err != nil {
+			return 0, err
+		}
+		if applied {
+			c.skipApplied(key, fmt.Sprintf("document '%s' [%s]", child, childID))
+			return entityCounter, nil
+		}
 	} else {
-		// Generate new entity ID
-		// Get the part before the first underscore for the prefix
-		prefixPart := strings.Split(transactionID, "_")[0]
-		prefix := fmt.Sprintf("%s_doc", prefixPart)
 		entityCounter = entityCounters["document"] + 1
-		newEntityID := fmt.Sprintf("%s_%d", prefix, entityCounter)
 
 		// Create the new document entity
 		documentEntity := &models.Entity{
-			ID: newEntityID,
+			ID: EntityID(key, "document"),
 			Kind: models.Kind{
 				Major: "Document",
 				Minor: childType,
@@ -2152,7 +2267,7 @@ func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCou
 		}
 
 		// Create the document entity
-		createdDocument, err := c.CreateEntity(documentEntity)
+		createdDocument, err := c.CreateEntityContext(ctx, documentEntity)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create document entity: %w", err)
 		}
@@ -2160,9 +2275,7 @@ func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCou
 	}
 
 	// Update the parent entity to add the relationship to the document
-	// Use transaction ID and current timestamp to ensure unique relationship ID
-	currentTimestamp := strings.ReplaceAll(time.Now().Format(time.RFC3339), ":", "-")
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%s", parentID, childID, currentTimestamp)
+	uniqueRelationshipID := RelationshipID(key, "AS_DOCUMENT", parentID, childID)
 
 	parentEntity := &models.Entity{
 		ID:         parentID,
@@ -2186,7 +2299,7 @@ func (c *Client) AddDocumentEntity(transaction map[string]interface{}, entityCou
 		},
 	}
 
-	_, err = c.UpdateEntity(parentID, parentEntity)
+	_, err = c.UpdateEntityContext(ctx, parentID, parentEntity)
 	if err != nil {
 		return 0, fmt.Errorf("failed to update parent entity: %w", err)
 	}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// Sentinel errors returned (wrapped) by the client and entity operations. Match them with errors.Is.
+var (
+	// ErrEntityNotFound means a lookup found no matching entity or relationship
+	ErrEntityNotFound = errors.New("entity not found")
+	// ErrAmbiguousMatch means a lookup that must resolve to one entity matched several; see AmbiguousMatchError
+	ErrAmbiguousMatch = errors.New("ambiguous match")
+	// ErrEntityExists means an entity that must be new is already present
+	ErrEntityExists = errors.New("entity already exists")
+	// ErrInvalidTransaction means a transaction is missing a field or holds an unusable value; see InvalidTransactionError
+	ErrInvalidTransaction = errors.New("invalid transaction")
+	// ErrUnrecognisedFile means the header of a CSV file fits no transaction schema, or fits several
+	// and its name does not pick one
+	ErrUnrecognisedFile = errors.New("unrecognised transaction file")
+)
+
+// HTTPError is returned when the Update or Query API answers with an unexpected status code
+type HTTPError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body != "" {
+		return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// Is makes errors.Is(err, ErrEntityNotFound) succeed for 404 responses
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrEntityNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// maxErrorBodySize limits how much of an error response is kept in HTTPError.Body
+const maxErrorBodySize = 4096
+
+// newHTTPError builds an HTTPError from a response, keeping the start of its body
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	httpErr := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+	if resp.Request != nil {
+		httpErr.Method = resp.Request.Method
+		httpErr.URL = resp.Request.URL.String()
+	}
+	return httpErr
+}
+
+// IsHTTPStatus reports whether err wraps an HTTPError with the given status code
+func IsHTTPStatus(err error, statusCode int) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == statusCode
+}
+
+// AmbiguousMatchError is returned when a lookup that must resolve to a single entity matched several.
+// It matches ErrAmbiguousMatch with errors.Is.
+type AmbiguousMatchError struct {
+	Message      string
+	CandidateIDs []string
+}
+
+func (e *AmbiguousMatchError) Error() string {
+	return fmt.Sprintf("%s (candidates: %s)", e.Message, strings.Join(e.CandidateIDs, ", "))
+}
+
+// Is makes errors.Is(err, ErrAmbiguousMatch) succeed
+func (e *AmbiguousMatchError) Is(target error) bool {
+	return target == ErrAmbiguousMatch
+}
+
+// InvalidTransactionError is returned when a transaction field is missing or unusable.
+// It matches ErrInvalidTransaction with errors.Is.
+type InvalidTransactionError struct {
+	Field  string
+	Reason string
+	Err    error
+	// File, Line and Column locate the field when the transaction was read from a CSV file.
+	// Column is 0 when the file has no such column.
+	File   string
+	Line   int
+	Column int
+}
+
+func (e *InvalidTransactionError) Error() string {
+	msg := fmt.Sprintf("invalid transaction field '%s': %s", e.Field, e.Reason)
+	if e.File != "" {
+		position := fmt.Sprintf("%s:%d", e.File, e.Line)
+		if e.Column > 0 {
+			position += fmt.Sprintf(":%d", e.Column)
+		}
+		msg = position + ": " + msg
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrInvalidTransaction) succeed
+func (e *InvalidTransactionError) Is(target error) bool {
+	return target == ErrInvalidTransaction
+}
+
+func (e *InvalidTransactionError) Unwrap() error {
+	return e.Err
+}
+
+// sentinelError keeps a descriptive message while matching a sentinel with errors.Is
+type sentinelError struct {
+	msg      string
+	sentinel error
+}
+
+func (e *sentinelError) Error() string {
+	return e.msg
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.sentinel
+}
+
+// notFoundf formats a message for an error that matches ErrEntityNotFound
+func notFoundf(format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), sentinel: ErrEntityNotFound}
+}
+
+// existsf formats a message for an error that matches ErrEntityExists
+func existsf(format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), sentinel: ErrEntityExists}
+}
+
+// unrecognisedf formats a message for an error that matches ErrUnrecognisedFile
+func unrecognisedf(format string, args ...interface{}) error {
+	return &sentinelError{msg: fmt.Sprintf(format, args...), sentinel: ErrUnrecognisedFile}
+}
+
+// ambiguousf builds an AmbiguousMatchError for the given candidates
+func ambiguousf(candidateIDs []string, format string, args ...interface{}) error {
+	return &AmbiguousMatchError{Message: fmt.Sprintf(format, args...), CandidateIDs: candidateIDs}
+}
+
+// invalidField builds an InvalidTransactionError for the given field
+func invalidField(field, reason string, err error) error {
+	return &InvalidTransactionError{Field: field, Reason: reason, Err: err}
+}
+
+// searchResultIDs lists the IDs of search results, for use as ambiguous match candidates
+func searchResultIDs(results []models.SearchResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
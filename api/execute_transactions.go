@@ -1,230 +1,226 @@
 package api
 
 import (
+	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"sort"
-	"strconv"
 	"strings"
 )
 
+// ProcessDocumentTransactions processes all document transactions from CSV files in the specified directory.
+// Documents are loaded like the other process types, so it is the same as ProcessTransactions.
+//
+// Deprecated: use ProcessTransactions with the document process type.
 func (c *Client) ProcessDocumentTransactions(dataDir string, processType string) error {
-	var entityCounters = map[string]int{
-		"document": 0,
+	return c.ProcessTransactionsContext(context.Background(), dataDir, processType)
+}
+
+// ProcessDocumentTransactionsContext is the same as ProcessTransactionsContext.
+//
+// Deprecated: use ProcessTransactionsContext with the document process type.
+func (c *Client) ProcessDocumentTransactionsContext(ctx context.Context, dataDir string, processType string) error {
+	return c.ProcessTransactionsContext(ctx, dataDir, processType)
+}
+
+// ProcessTransactions processes all transactions from CSV files in the specified directory
+func (c *Client) ProcessTransactions(dataDir string, processType string) error {
+	return c.ProcessTransactionsContext(context.Background(), dataDir, processType)
+}
+
+// ProcessTransactionsContext is like ProcessTransactions but stops before the next transaction once
+// ctx is done. The returned error names the transaction it stopped on.
+func (c *Client) ProcessTransactionsContext(ctx context.Context, dataDir string, processType string) (err error) {
+	// A transaction that has started is allowed to finish, so cancellation only takes effect between transactions
+	txCtx := context.WithoutCancel(ctx)
+
+	if len(c.handlers.Kinds(processType)) == 0 {
+		return fmt.Errorf("invalid process type: %s", processType)
 	}
 
-	// Get all CSV files in the directory
-	files, err := os.ReadDir(dataDir)
+	allTransactions, err := c.loadDataDir(dataDir)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dataDir, err)
+		return err
 	}
 
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".csv") && strings.HasSuffix(file.Name(), "_ADD.csv") {
-			transactions, err := loadTransactions(filepath.Join(dataDir, file.Name()), "ADD")
-			if err != nil {
-				return fmt.Errorf("failed to load transactions from %s: %w", file.Name(), err)
-			}
-			for _, transaction := range transactions {
-				if transaction["file_type"] == "ADD" {
-					entityCounters["document"], err = c.AddDocumentEntity(transaction, entityCounters)
-					if err != nil {
-						return fmt.Errorf("failed to process add transaction %s: %w", transaction["transaction_id"], err)
-					}
+	// Initialize entity counters for the kinds the process type loads
+	entityCounters, err := c.entityCounters(processType)
+	if err != nil {
+		return err
+	}
+
+	// Keep a journal of the transactions applied, skipping those a resumed load already applied
+	journal, err := c.openJournal(dataDir, entityCounters)
+	if err != nil {
+		return err
+	}
+	defer c.flushBatchOnReturn(txCtx, journal, &err)
+
+	// The transactions of the files are sorted together by date, so the rows of a file can be spread
+	// between those of other files. Relationship writes batched per file go out once the last
+	// transaction of a file is done, not whenever the next transaction comes from another file.
+	lastOfFile := make(map[string]int)
+	for i, transaction := range allTransactions {
+		fileName, _ := transaction["file_name"].(string)
+		lastOfFile[fileName] = i
+	}
+
+	// Process transactions in order
+	for i, transaction := range allTransactions {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("stopped before transaction %s: %w", transaction["transaction_id"], err)
+		}
+
+		if i > 0 {
+			if previousFile, _ := allTransactions[i-1]["file_name"].(string); lastOfFile[previousFile] == i-1 {
+				if err := c.flushBatchAt(txCtx, BatchPerFile); err != nil {
+					return fmt.Errorf("failed to flush relationship writes of %s: %w", previousFile, err)
+				}
+				if err := c.commitJournal(journal); err != nil {
+					return err
 				}
 			}
 		}
-	}
 
-	return nil
-}
+		if applied, err := journal.applied(transaction); err != nil || applied {
+			if err != nil {
+				return err
+			}
+			c.logf("Skipping transaction %s: already applied according to the journal\n", transaction["transaction_id"])
+			continue
+		}
 
-// ProcessTransactions processes all transactions from CSV files in the specified directory
-func (c *Client) ProcessTransactions(dataDir string, processType string) error {
-	// Initialize entity counters based on process type
-	var entityCounters map[string]int
-	if processType == "organisation" {
-		entityCounters = map[string]int{
-			"minister":   0,
-			"department": 0,
+		c.logf("Processing transaction: %s (Type: %s)\n", transaction["transaction_id"], transaction["file_type"])
+
+		journal.begin(transaction)
+		applied, err := c.applyTransaction(txCtx, processType, transaction, entityCounters)
+		if err != nil {
+			return err
+		}
+		journal.finish(applied, entityCounters)
+
+		if err := c.flushBatchAt(txCtx, BatchPerTransaction); err != nil {
+			return fmt.Errorf("failed to flush relationship writes of transaction %s: %w", transaction["transaction_id"], err)
 		}
-	} else if processType == "person" {
-		entityCounters = map[string]int{
-			"citizen": 0,
+		if err := c.commitJournal(journal); err != nil {
+			return err
 		}
-	} else {
-		return fmt.Errorf("invalid process type: %s", processType)
 	}
 
+	return nil
+}
+
+// loadDataDir loads the transactions of every CSV file in dataDir, sorted by SortTransactions and
+// checked for ordering dependencies as the dependency policy of the client says
+func (c *Client) loadDataDir(dataDir string) ([]map[string]interface{}, error) {
 	// Get all CSV files in the directory
 	files, err := os.ReadDir(dataDir)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dataDir, err)
+		return nil, fmt.Errorf("failed to read directory %s: %w", dataDir, err)
 	}
 
 	// Collect all transactions from all files
 	var allTransactions []map[string]interface{}
 	for _, file := range files {
 		if !file.IsDir() && strings.HasSuffix(file.Name(), ".csv") {
-			// Extract file type from filename (e.g., "ADD" from "2403-38_ADD.csv" or "ADD.csv")
-			fileName := strings.TrimSuffix(file.Name(), ".csv")
-			fileType := "ADD" // Default to ADD
-			if strings.Contains(fileName, "TERMINATE") {
-				fileType = "TERMINATE"
-			} else if strings.Contains(fileName, "MOVE") {
-				fileType = "MOVE"
-			} else if strings.Contains(fileName, "MERGE") {
-				fileType = "MERGE"
-			} else if strings.Contains(fileName, "RENAME") {
-				fileType = "RENAME"
-			}
-
-			// Load transactions from the CSV file
-			transactions, err := loadTransactions(filepath.Join(dataDir, file.Name()), fileType)
+			// Load transactions from the CSV file, of the type its header and name say
+			transactions, err := c.loadTransactions(filepath.Join(dataDir, file.Name()))
 			if err != nil {
-				return fmt.Errorf("failed to load transactions from %s: %w", file.Name(), err)
+				return nil, fmt.Errorf("failed to load transactions from %s: %w", file.Name(), err)
 			}
 			allTransactions = append(allTransactions, transactions...)
 		}
 	}
 
-	// Sort transactions by transaction_id, handling numeric parts correctly
-	sort.Slice(allTransactions, func(i, j int) bool {
-		idI := allTransactions[i]["transaction_id"].(string)
-		idJ := allTransactions[j]["transaction_id"].(string)
-
-		// Split the IDs into parts
-		partsI := strings.Split(idI, "_")
-		partsJ := strings.Split(idJ, "_")
-
-		// Compare the first part (e.g., "2153/12")
-		if partsI[0] != partsJ[0] {
-			return partsI[0] < partsJ[0]
-		}
-
-		// Compare the second part (e.g., "tr")
-		if partsI[1] != partsJ[1] {
-			return partsI[1] < partsJ[1]
+	// Sort transactions by date, sequence and transaction_id, then see whether any names an entity
+	// that only a later one adds
+	if err := SortTransactions(allTransactions); err != nil {
+		return nil, err
+	}
+	dependencies := FindOrderingDependencies(allTransactions)
+	switch {
+	case len(dependencies) == 0:
+	case c.dependencyPolicy == DependencyFail:
+		messages := make([]string, 0, len(dependencies))
+		for _, dependency := range dependencies {
+			messages = append(messages, dependency.String())
 		}
+		return nil, fmt.Errorf("transactions in %s are out of order: %s", dataDir, strings.Join(messages, "; "))
+	case c.dependencyPolicy == DependencyReorder:
+		dependencies = resolveDependencies(allTransactions)
+	}
+	for _, dependency := range dependencies {
+		c.logf("Ordering dependency in %s: %s\n", dataDir, dependency)
+	}
 
-		// Compare the numeric part by converting to integers
-		numI := strings.TrimPrefix(partsI[2], "tr_")
-		numJ := strings.TrimPrefix(partsJ[2], "tr_")
-
-		// Convert to integers for numeric comparison
-		valI, _ := strconv.Atoi(numI)
-		valJ, _ := strconv.Atoi(numJ)
-		return valI < valJ
-	})
-
-	// Process transactions in order
-	for _, transaction := range allTransactions {
-		fmt.Printf("Processing transaction: %s (Type: %s)\n", transaction["transaction_id"], transaction["file_type"])
-
-		switch transaction["file_type"] {
-		case "ADD":
-			// Check if the transaction type matches the process type
-			childType := transaction["child_type"].(string)
-			if (processType == "organisation" && (childType == "minister" || childType == "department")) ||
-				(processType == "person" && childType == "citizen") {
-				var err error
-
-				if processType == "person" && childType == "citizen" {
-					entityCounters[childType], err = c.AddPersonEntity(transaction, entityCounters)
-				} else {
-					entityCounters[childType], err = c.AddOrgEntity(transaction, entityCounters)
-				}
-
-				if err != nil {
-					return fmt.Errorf("failed to process add transaction %s: %w", transaction["transaction_id"], err)
-				}
-				//entityCounters[childType] = newCounter
-				fmt.Printf("Processed Add transaction: %s\n", transaction["transaction_id"])
-			} else {
-				fmt.Printf("Skipping transaction %s: type %s does not match process type %s\n",
-					transaction["transaction_id"], childType, processType)
-			}
-
-		case "TERMINATE":
-			if processType == "organisation" {
-				err := c.TerminateOrgEntity(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process terminate transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Terminate transaction: %s\n", transaction["transaction_id"])
-			} else if processType == "person" {
-				err := c.TerminatePersonEntity(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process terminate transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Terminate transaction: %s\n", transaction["transaction_id"])
-			}
+	return allTransactions, nil
+}
 
-		case "MOVE":
-			if processType == "organisation" {
-				// Check if we're moving a department or a minister
-				childType := transaction["type"].(string)
-				if childType == "department" {
-					err := c.MoveDepartment(transaction)
-					if err != nil {
-						return fmt.Errorf("failed to process move department transaction %s: %w", transaction["transaction_id"], err)
-					}
-					fmt.Printf("Processed Move Department transaction: %s\n", transaction["transaction_id"])
-				} else if childType == "minister" {
-					err := c.MoveMinister(transaction)
-					if err != nil {
-						return fmt.Errorf("failed to process move minister transaction %s: %w", transaction["transaction_id"], err)
-					}
-					fmt.Printf("Processed Move Minister transaction: %s\n", transaction["transaction_id"])
-				} else {
-					return fmt.Errorf("unknown child type for MOVE transaction: %s", childType)
-				}
-			} else if processType == "person" {
-				err := c.MovePerson(transaction)
-				if err != nil {
-					return fmt.Errorf("failed to process move transaction %s: %w", transaction["transaction_id"], err)
-				}
-				fmt.Printf("Processed Move transaction: %s\n", transaction["transaction_id"])
-			}
+// entityCounters returns the entity counters of the kinds processType loads, all at 0. Handlers of
+// AnyKind keep their own counters in the map.
+func (c *Client) entityCounters(processType string) (map[string]int, error) {
+	kinds := c.handlers.Kinds(processType)
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("invalid process type: %s", processType)
+	}
+	entityCounters := make(map[string]int, len(kinds))
+	for _, kind := range kinds {
+		if kind != AnyKind {
+			entityCounters[kind] = 0
+		}
+	}
+	return entityCounters, nil
+}
 
-		case "MERGE":
-			if processType == "organisation" {
-				newCounter, err := c.MergeMinisters(transaction, entityCounters)
-				if err != nil {
-					return fmt.Errorf("failed to process merge transaction %s: %w", transaction["transaction_id"], err)
-				}
-				entityCounters["minister"] = newCounter
-				fmt.Printf("Processed Merge transaction: %s\n", transaction["transaction_id"])
-			}
+// applyTransaction validates transaction and applies it with the handler registered for its type and
+// entity kind, and reports whether it did. Transactions of unknown types, and of kinds that processType
+// does not load, are skipped: a load of another process type applies them.
+func (c *Client) applyTransaction(ctx context.Context, processType string, transaction map[string]interface{}, entityCounters map[string]int) (bool, error) {
+	transactionType, _ := transaction["file_type"].(string)
+	transactionID := transaction["transaction_id"]
+	if !containsString(c.handlers.TransactionTypes(), transactionType) {
+		c.logf("Skipping unknown transaction type: %s\n", transactionType)
+		return false, nil
+	}
 
-		case "RENAME":
-			if processType == "organisation" {
-				var newCounter int
-				var err error
-				if transaction["type"] == "minister" {
-					newCounter, err = c.RenameMinister(transaction, entityCounters)
-				} else if transaction["type"] == "department" {
-					newCounter, err = c.RenameDepartment(transaction, entityCounters)
-				}
-				if err != nil {
-					return fmt.Errorf("failed to process rename transaction %s: %w", transaction["transaction_id"], err)
-				}
-				if transaction["type"] == "minister" {
-					entityCounters["minister"] = newCounter
-				} else if transaction["type"] == "department" {
-					entityCounters["department"] = newCounter
-				}
-				fmt.Printf("Processed Rename transaction: %s\n", transaction["transaction_id"])
-			}
+	kind := transactionKind(transaction)
+	if kind == "" {
+		return false, fmt.Errorf("invalid %s transaction %s: %w", strings.ToLower(transactionType), transactionID, missingKind(transaction))
+	}
+	if !c.handlers.loads(processType, kind) {
+		c.logf("Skipping transaction %s: type %s does not match process type %s\n", transactionID, kind, processType)
+		return false, nil
+	}
+	handler, ok := c.handlers.handlerFor(processType, transactionType, kind)
+	if !ok {
+		return false, fmt.Errorf("invalid %s transaction %s: %w", strings.ToLower(transactionType), transactionID,
+			transactionSource(transaction).locate(invalidField("type", fmt.Sprintf("%s loads have no %s handler for %s", processType, transactionType, kind), nil)))
+	}
 
-		default:
-			fmt.Printf("Skipping unknown transaction type: %s\n", transaction["file_type"])
-		}
+	if err := handler.Validate(transaction); err != nil {
+		return false, fmt.Errorf("invalid %s transaction %s: %w", strings.ToLower(transactionType), transactionID, err)
 	}
+	if err := handler.Apply(ctx, c, transaction, entityCounters); err != nil {
+		return false, fmt.Errorf("failed to process %s transaction %s: %w", strings.ToLower(transactionType), transactionID, err)
+	}
+	c.logf("Processed %s transaction %s: %s\n", transactionType, transactionID, handler.Describe(transaction))
+	return true, nil
+}
 
-	return nil
+// flushBatchOnReturn sends the relationship writes still batched when processing ends, including when
+// it stops early, and adds any flush failure to *err. The journal then records the transactions
+// whose writes all went out and is closed.
+func (c *Client) flushBatchOnReturn(ctx context.Context, journal *loadJournal, err *error) {
+	flushErr := c.FlushRelationshipsContext(ctx)
+	if flushErr != nil {
+		*err = errors.Join(*err, fmt.Errorf("failed to flush relationship writes: %w", flushErr))
+	}
+	if journalErr := c.closeJournal(journal, flushErr == nil); journalErr != nil {
+		*err = errors.Join(*err, journalErr)
+	}
 }
 
 // extractPresidentNameFromPath extracts the president's name from the file path.
@@ -242,12 +238,30 @@ func extractPresidentNameFromPath(filePath string) (string, error) {
 	return "", fmt.Errorf("neither 'orgchart' nor 'people' nor 'documents' found in path: %s", filePath)
 }
 
-// loadTransactions reads and processes transactions from a CSV file
-func loadTransactions(filePath string, fileType string) ([]map[string]interface{}, error) {
+// SetPresident makes the transactions loaded afterwards belong to the named president instead of
+// the one their path names. Transactions with a president column keep theirs. Empty restores the
+// president of the path.
+func (c *Client) SetPresident(name string) {
+	c.president = name
+}
+
+// President returns the president set with SetPresident, if any
+func (c *Client) President() string {
+	return c.president
+}
+
+// loadTransactions reads the transactions of a CSV file. Their type is detected from the header
+// and name of the file, or read from the action column of each row. Transactions without a
+// president column belong to the president set with SetPresident, or to the one the path names.
+func (c *Client) loadTransactions(filePath string) ([]map[string]interface{}, error) {
 	// Extract president name from file path
-	presidentName, err := extractPresidentNameFromPath(filePath)
-	if err != nil {
-		return nil, err
+	presidentName := c.president
+	if presidentName == "" {
+		var err error
+		presidentName, err = extractPresidentNameFromPath(filePath)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	file, err := os.Open(filePath)
@@ -263,20 +277,31 @@ func loadTransactions(filePath string, fileType string) ([]map[string]interface{
 	if err != nil {
 		return nil, fmt.Errorf("failed to read header from %s: %w", filePath, err)
 	}
-
-	// Read all records
-	records, err := reader.ReadAll()
+	for i, column := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+	}
+	fileType, err := c.handlers.DetectTransactionType(filepath.Base(filePath), header)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read records from %s: %w", filePath, err)
+		return nil, err
 	}
 
 	var transactions []map[string]interface{}
-	// Process each record
-	for _, record := range records {
+	// Process each record, keeping its line so that invalid fields can be located
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read records from %s: %w", filePath, err)
+		}
+		line, _ := reader.FieldPos(0)
+
 		transaction := make(map[string]interface{})
 		for i, value := range record {
 			transaction[header[i]] = value
 		}
+		transaction[sourceKey] = TxSource{File: filePath, Line: line, Columns: header}
 
 		// Use president from transaction if provided and not empty, otherwise use the one from path
 		if presidentFromTransaction, exists := transaction["president"]; exists && presidentFromTransaction != "" {
@@ -287,6 +312,15 @@ func loadTransactions(filePath string, fileType string) ([]map[string]interface{
 		}
 
 		transaction["file_type"] = fileType
+		if fileType == "" {
+			// Mixed files name the type of each row
+			rowType, err := c.handlers.actionType(fmt.Sprint(transaction[ActionColumn]), header)
+			if err != nil {
+				return nil, transactionSource(transaction).locate(err)
+			}
+			transaction["file_type"] = rowType
+		}
+		transaction["file_name"] = filepath.Base(filePath)
 		transactions = append(transactions, transaction)
 	}
 
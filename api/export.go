@@ -0,0 +1,396 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats ExportOrgChart renders
+const (
+	ExportDOT     = "dot"
+	ExportMermaid = "mermaid"
+	ExportGraphML = "graphml"
+)
+
+// ExportOptions selects what ExportOrgChart draws and how
+type ExportOptions struct {
+	// Format is ExportDOT, ExportMermaid or ExportGraphML
+	Format string
+	// President and Minister limit the drawing to the president or the minister with that name
+	President string
+	Minister  string
+	// SkipDepartments and SkipPeople leave departments and appointed people out of the drawing
+	SkipDepartments bool
+	SkipPeople      bool
+	// MaxLabelLength shortens longer names to that many characters; 0 means names are not shortened
+	MaxLabelLength int
+	// GroupByKind draws the presidents, ministers, departments and people each in their own group
+	GroupByKind bool
+}
+
+// exportNode is an entity drawn in an export
+type exportNode struct {
+	key    string
+	entity ChartEntity
+	group  string
+}
+
+// exportEdge connects the node of a parent to the node of one of its children
+type exportEdge struct {
+	from, to string
+	relType  string
+	since    string
+}
+
+// exportGraph is the part of an org chart selected for an export
+type exportGraph struct {
+	nodes []exportNode
+	edges []exportEdge
+	keys  map[string]string
+}
+
+// exportGroups are the groups of GroupByKind, in drawing order, with their titles
+var exportGroups = []struct{ name, title string }{
+	{"government", "Government"},
+	{"president", "Presidents"},
+	{"minister", "Ministers"},
+	{"department", "Departments"},
+	{"person", "People"},
+}
+
+// node adds entity to the graph once and returns its key
+func (g *exportGraph) node(entity ChartEntity, group string) string {
+	if key, ok := g.keys[entity.ID]; ok {
+		return key
+	}
+	key := fmt.Sprintf("n%d", len(g.nodes))
+	g.keys[entity.ID] = key
+	g.nodes = append(g.nodes, exportNode{key: key, entity: entity, group: group})
+	return key
+}
+
+// edge connects parent to child
+func (g *exportGraph) edge(parent string, child ChartEntity, group, relType string) {
+	g.edges = append(g.edges, exportEdge{from: parent, to: g.node(child, group), relType: relType, since: child.Since})
+}
+
+// buildExportGraph selects the part of chart that opts ask for
+func buildExportGraph(chart *OrgChart, opts *ExportOptions) (*exportGraph, error) {
+	graph := &exportGraph{keys: map[string]string{}}
+	government := graph.node(chart.Government, "government")
+
+	ministersDrawn := 0
+	for _, president := range chart.Presidents {
+		if opts.President != "" && president.Name != opts.President {
+			continue
+		}
+		var ministers []*ChartMinister
+		for _, minister := range president.Ministers {
+			if opts.Minister == "" || minister.Name == opts.Minister {
+				ministers = append(ministers, minister)
+			}
+		}
+		if opts.Minister != "" && len(ministers) == 0 {
+			continue
+		}
+
+		graph.edge(government, president.ChartEntity, "president", "AS_PRESIDENT")
+		presidentKey := graph.keys[president.ID]
+		for _, minister := range ministers {
+			ministersDrawn++
+			graph.edge(presidentKey, minister.ChartEntity, "minister", "AS_MINISTER")
+			ministerKey := graph.keys[minister.ID]
+			if !opts.SkipDepartments {
+				for _, department := range minister.Departments {
+					graph.edge(ministerKey, department.ChartEntity, "department", "AS_DEPARTMENT")
+					if !opts.SkipPeople {
+						departmentKey := graph.keys[department.ID]
+						for _, person := range department.People {
+							graph.edge(departmentKey, person, "person", "AS_APPOINTED")
+						}
+					}
+				}
+			}
+			if !opts.SkipPeople {
+				for _, person := range minister.People {
+					graph.edge(ministerKey, person, "person", "AS_APPOINTED")
+				}
+			}
+		}
+	}
+
+	if opts.President != "" && len(graph.nodes) == 1 {
+		return nil, notFoundf("president '%s' not found in the org chart on %s", opts.President, snapshotDate(chart.Date))
+	}
+	if opts.Minister != "" && ministersDrawn == 0 {
+		return nil, notFoundf("minister '%s' not found in the org chart on %s", opts.Minister, snapshotDate(chart.Date))
+	}
+	return graph, nil
+}
+
+// ExportOrgChart draws chart, or the part of it that opts select, as a Graphviz DOT graph, a Mermaid
+// flowchart or GraphML. It needs no API calls, so a chart read with ReadOrgChart can be drawn offline.
+func ExportOrgChart(w io.Writer, chart *OrgChart, opts *ExportOptions) error {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	graph, err := buildExportGraph(chart, opts)
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case ExportDOT:
+		return writeDOT(w, graph, opts)
+	case ExportMermaid:
+		return writeMermaid(w, graph, opts)
+	case ExportGraphML:
+		return writeGraphML(w, graph, opts)
+	}
+	return fmt.Errorf("unknown export format '%s': must be %s, %s or %s", opts.Format, ExportDOT, ExportMermaid, ExportGraphML)
+}
+
+// ReadOrgChart reads a chart saved as JSON, for example by -snapshot -format json
+func ReadOrgChart(r io.Reader) (*OrgChart, error) {
+	var chart OrgChart
+	if err := json.NewDecoder(r).Decode(&chart); err != nil {
+		return nil, fmt.Errorf("failed to decode org chart: %w", err)
+	}
+	if chart.Government.ID == "" {
+		return nil, fmt.Errorf("failed to decode org chart: no government node")
+	}
+	return &chart, nil
+}
+
+// exportLabel returns the name of entity, shortened to maxLength characters when that is set
+func exportLabel(entity ChartEntity, maxLength int) string {
+	name := []rune(entity.Name)
+	if maxLength <= 0 || len(name) <= maxLength {
+		return entity.Name
+	}
+	if maxLength <= 3 {
+		return string(name[:maxLength])
+	}
+	return strings.TrimSpace(string(name[:maxLength-3])) + "..."
+}
+
+// dotShapes are the node shapes of each group in DOT
+var dotShapes = map[string]string{
+	"government": "doubleoctagon",
+	"president":  "octagon",
+	"minister":   "box",
+	"department": "ellipse",
+	"person":     "plaintext",
+}
+
+// writeDOT draws graph in the Graphviz DOT language
+func writeDOT(w io.Writer, graph *exportGraph, opts *ExportOptions) error {
+	var b strings.Builder
+	b.WriteString("digraph orgchart {\n")
+	b.WriteString("  rankdir=LR;\n")
+	writeNode := func(node exportNode, indent string) {
+		fmt.Fprintf(&b, "%s%s [label=%s, shape=%s, tooltip=%s];\n", indent, node.key,
+			dotQuote(exportLabel(node.entity, opts.MaxLabelLength)), dotShapes[node.group], dotQuote(node.entity.Name))
+	}
+
+	if opts.GroupByKind {
+		for _, group := range exportGroups {
+			nodes := nodesInGroup(graph, group.name)
+			if len(nodes) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "  subgraph cluster_%s {\n    label=%s;\n", group.name, dotQuote(group.title))
+			for _, node := range nodes {
+				writeNode(node, "    ")
+			}
+			b.WriteString("  }\n")
+		}
+	} else {
+		for _, node := range graph.nodes {
+			writeNode(node, "  ")
+		}
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", edge.from, edge.to)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotQuote quotes value as a DOT string
+func dotQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// mermaidShapes are the opening and closing brackets of the node shape of each group in Mermaid
+var mermaidShapes = map[string][2]string{
+	"government": {"{{", "}}"},
+	"president":  {"([", "])"},
+	"minister":   {"[", "]"},
+	"department": {"(", ")"},
+	"person":     {">", "]"},
+}
+
+// writeMermaid draws graph as a Mermaid flowchart
+func writeMermaid(w io.Writer, graph *exportGraph, opts *ExportOptions) error {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	writeNode := func(node exportNode, indent string) {
+		shape := mermaidShapes[node.group]
+		fmt.Fprintf(&b, "%s%s%s%s%s\n", indent, node.key, shape[0], mermaidQuote(exportLabel(node.entity, opts.MaxLabelLength)), shape[1])
+	}
+
+	if opts.GroupByKind {
+		for _, group := range exportGroups {
+			nodes := nodesInGroup(graph, group.name)
+			if len(nodes) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "  subgraph %s [%s]\n", group.name, mermaidQuote(group.title))
+			for _, node := range nodes {
+				writeNode(node, "    ")
+			}
+			b.WriteString("  end\n")
+		}
+	} else {
+		for _, node := range graph.nodes {
+			writeNode(node, "  ")
+		}
+	}
+	for _, edge := range graph.edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", edge.from, edge.to)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidQuote quotes value as a Mermaid label
+func mermaidQuote(value string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(value) + `"`
+}
+
+// GraphML documents written by writeGraphML
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	Name     string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphMLData `xml:"data"`
+	Graph *graphMLGraph `xml:"graph,omitempty"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML draws graph as GraphML. Groups are nested graphs, as yEd and Gephi read them.
+func writeGraphML(w io.Writer, graph *exportGraph, opts *ExportOptions) error {
+	document := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", AttrType: "string"},
+			{ID: "name", For: "node", Name: "name", AttrType: "string"},
+			{ID: "kind", For: "node", Name: "kind", AttrType: "string"},
+			{ID: "entity", For: "node", Name: "entity", AttrType: "string"},
+			{ID: "relation", For: "edge", Name: "relation", AttrType: "string"},
+			{ID: "since", For: "edge", Name: "since", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "orgchart", EdgeDefault: "directed"},
+	}
+	toNode := func(node exportNode) graphMLNode {
+		return graphMLNode{
+			ID: node.key,
+			Data: []graphMLData{
+				{Key: "label", Value: exportLabel(node.entity, opts.MaxLabelLength)},
+				{Key: "name", Value: node.entity.Name},
+				{Key: "kind", Value: node.group},
+				{Key: "entity", Value: node.entity.ID},
+			},
+		}
+	}
+
+	if opts.GroupByKind {
+		for _, group := range exportGroups {
+			nodes := nodesInGroup(graph, group.name)
+			if len(nodes) == 0 {
+				continue
+			}
+			groupNode := graphMLNode{
+				ID:    "group_" + group.name,
+				Data:  []graphMLData{{Key: "label", Value: group.title}},
+				Graph: &graphMLGraph{ID: "group_" + group.name + ":", EdgeDefault: "directed"},
+			}
+			for _, node := range nodes {
+				groupNode.Graph.Nodes = append(groupNode.Graph.Nodes, toNode(node))
+			}
+			document.Graph.Nodes = append(document.Graph.Nodes, groupNode)
+		}
+	} else {
+		for _, node := range graph.nodes {
+			document.Graph.Nodes = append(document.Graph.Nodes, toNode(node))
+		}
+	}
+	for i, edge := range graph.edges {
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.from,
+			Target: edge.to,
+			Data: []graphMLData{
+				{Key: "relation", Value: edge.relType},
+				{Key: "since", Value: edge.since},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// nodesInGroup returns the nodes of graph in the given group
+func nodesInGroup(graph *exportGraph, group string) []exportNode {
+	var nodes []exportNode
+	for _, node := range graph.nodes {
+		if node.group == group {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// GetEntityOptions selects what GetEntity fetches besides the entity itself
+type GetEntityOptions struct {
+	// AsOf (RFC3339) limits relationships and attribute values to those active at that time and
+	// the name history to names given by then. Empty means the whole history.
+	AsOf string
+	// Attributes names the attributes to fetch. The Query API cannot list them, so none are fetched by default.
+	Attributes []string
+	// SkipMetadata, SkipRelationships and SkipNameHistory leave those parts out to save API calls
+	SkipMetadata      bool
+	SkipRelationships bool
+	SkipNameHistory   bool
+}
+
+// GetEntity fetches an entity with its metadata, decoded attributes, outgoing and incoming relationships
+// and name history. opts may be nil.
+func (c *Client) GetEntity(id string, opts *GetEntityOptions) (*models.Entity, error) {
+	return c.GetEntityContext(context.Background(), id, opts)
+}
+
+// GetEntityContext is like GetEntity but stops issuing API calls once ctx is done
+func (c *Client) GetEntityContext(ctx context.Context, id string, opts *GetEntityOptions) (*models.Entity, error) {
+	if opts == nil {
+		opts = &GetEntityOptions{}
+	}
+
+	results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for entity %s: %w", id, err)
+	}
+	if len(results) == 0 {
+		return nil, notFoundf("entity not found: %s", id)
+	}
+	result := results[0]
+	if opts.AsOf != "" && result.Created > opts.AsOf {
+		return nil, notFoundf("entity %s was created after %s", id, opts.AsOf)
+	}
+
+	entity := &models.Entity{
+		ID:         result.ID,
+		Kind:       result.Kind,
+		Created:    result.Created,
+		Terminated: result.Terminated,
+		Name: models.TimeBasedValue{
+			StartTime: result.Created,
+			EndTime:   result.Terminated,
+			Value:     result.Name,
+		},
+		Metadata:      []models.MetadataEntry{},
+		Attributes:    []models.AttributeEntry{},
+		Relationships: []models.RelationshipEntry{},
+	}
+
+	if !opts.SkipMetadata {
+		metadata, err := c.GetEntityMetadataContext(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of entity %s: %w", id, err)
+		}
+		entity.Metadata = metadataEntries(metadata)
+	}
+
+	for _, name := range opts.Attributes {
+		raw, err := c.GetEntityAttributeContext(ctx, id, name, opts.AsOf, opts.AsOf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attribute %s of entity %s: %w", name, id, err)
+		}
+		values, err := attributeValues(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode attribute %s of entity %s: %w", name, id, err)
+		}
+		entity.Attributes = append(entity.Attributes, models.AttributeEntry{
+			Key:   name,
+			Value: models.AttributeValueCollection{Values: values},
+		})
+	}
+
+	if !opts.SkipRelationships {
+		relations, err := c.GetRelatedEntitiesContext(ctx, id, &models.Relationship{ActiveAt: opts.AsOf})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relationships of entity %s: %w", id, err)
+		}
+		for _, rel := range relations {
+			entity.Relationships = append(entity.Relationships, models.RelationshipEntry{Key: rel.ID, Value: rel})
+		}
+	}
+
+	if !opts.SkipNameHistory {
+		history, err := c.nameHistory(ctx, entity, opts.AsOf)
+		if err != nil {
+			return nil, err
+		}
+		entity.NameHistory = history
+	}
+
+	return entity, nil
+}
+
+// nameHistory follows incoming RENAMED_TO relationships back from entity and returns its names,
+// oldest first. Each earlier name ends when the rename to the next one started.
+func (c *Client) nameHistory(ctx context.Context, entity *models.Entity, asOf string) ([]models.TimeBasedValue, error) {
+	history := []models.TimeBasedValue{entity.Name}
+	visited := map[string]bool{entity.ID: true}
+
+	currentID := entity.ID
+	for {
+		renames, err := c.GetRelatedEntitiesContext(ctx, currentID, &models.Relationship{
+			Name:      "RENAMED_TO",
+			Direction: DirectionIncoming,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get renames of entity %s: %w", currentID, err)
+		}
+
+		// Take the latest rename into the current entity that has not been seen yet
+		var previous *models.Relationship
+		for i := range renames {
+			rel := renames[i]
+			if visited[rel.RelatedEntityID] || (asOf != "" && rel.StartTime > asOf) {
+				continue
+			}
+			if previous == nil || rel.StartTime > previous.StartTime {
+				previous = &rel
+			}
+		}
+		if previous == nil {
+			break
+		}
+		visited[previous.RelatedEntityID] = true
+
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: previous.RelatedEntityID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for entity %s: %w", previous.RelatedEntityID, err)
+		}
+		if len(results) == 0 {
+			break
+		}
+		history = append(history, models.TimeBasedValue{
+			StartTime: results[0].Created,
+			EndTime:   previous.StartTime,
+			Value:     results[0].Name,
+		})
+		currentID = previous.RelatedEntityID
+	}
+
+	// Collected newest first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// metadataEntries turns the metadata map of the Query API into entries sorted by key, decoding the values
+func metadataEntries(metadata map[string]interface{}) []models.MetadataEntry {
+	entries := make([]models.MetadataEntry, 0, len(metadata))
+	for key, value := range metadata {
+		entries = append(entries, models.MetadataEntry{Key: key, Value: decodeValue(value)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// attributeValues converts a raw attribute response into time-based values with decoded values
+func attributeValues(raw interface{}) ([]models.TimeBasedValue, error) {
+	if raw == nil {
+		return []models.TimeBasedValue{}, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []models.TimeBasedValue
+	if err := json.Unmarshal(data, &values); err != nil {
+		// A single value rather than a list
+		var value models.TimeBasedValue
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		values = []models.TimeBasedValue{value}
+	}
+
+	for i := range values {
+		values[i].Value = decodeValue(values[i].Value)
+	}
+	return values, nil
+}
+
+// decodeValue unwraps values the Query API returns as protobuf Any objects ({"typeUrl","value"}),
+// either as an object or as a JSON string holding one, like entity names. The payload is hex
+// encoded, or base64 encoded in older responses. Other values are returned unchanged.
+func decodeValue(value interface{}) interface{} {
+	wrapped, ok := value.(map[string]interface{})
+	if !ok {
+		text, isString := value.(string)
+		if !isString || !strings.HasPrefix(strings.TrimSpace(text), "{") {
+			return value
+		}
+		if err := json.Unmarshal([]byte(text), &wrapped); err != nil {
+			return value
+		}
+	}
+
+	typeURL, hasType := wrapped["typeUrl"].(string)
+	payload, hasPayload := wrapped["value"].(string)
+	if !hasType || !hasPayload {
+		return value
+	}
+
+	decoded, err := hex.DecodeString(payload)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return value
+		}
+	}
+	if strings.HasSuffix(typeURL, "StringValue") || typeURL == "" {
+		return string(decoded)
+	}
+
+	// Structured payloads are JSON when they can be parsed as such
+	var structured interface{}
+	if err := json.Unmarshal(decoded, &structured); err == nil {
+		return structured
+	}
+	return string(decoded)
+}
@@ -0,0 +1,444 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransactionHandler applies one type of transaction to one kind of entity, for example ADD to
+// ministers or MOVE to citizens. Handlers are looked up in a HandlerRegistry by the transaction's
+// file type and the kind named by its child_type (or type) column.
+type TransactionHandler interface {
+	// Validate checks the fields of transaction before anything is written
+	Validate(transaction map[string]interface{}) error
+	// Apply performs transaction. Handlers that create entities advance entityCounters, which
+	// count the entities created per kind.
+	Apply(ctx context.Context, c *Client, transaction map[string]interface{}, entityCounters map[string]int) error
+	// Describe summarises transaction in one line for progress messages
+	Describe(transaction map[string]interface{}) string
+}
+
+// AnyKind registers a handler, or a process type, for transactions of every entity kind. Documents
+// use it, as their child_type names the gazette type rather than an entity kind. A process type
+// registered for AnyKind applies only the AnyKind handlers to the kinds it does not name.
+const AnyKind = "*"
+
+// handlerKey identifies the handler of a transaction type for an entity kind
+type handlerKey struct {
+	transactionType string
+	kind            string
+}
+
+// HandlerRegistry maps transaction types and entity kinds to their handlers, and process types
+// (the loader's -type) to the entity kinds they load. It is safe for concurrent use.
+type HandlerRegistry struct {
+	mu           sync.RWMutex
+	handlers     map[handlerKey]TransactionHandler
+	processTypes map[string][]string
+	schemas      []CSVSchema
+}
+
+// NewHandlerRegistry returns an empty registry. Use BuiltinHandlers for one that knows the
+// transactions of the published datasets.
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers:     make(map[handlerKey]TransactionHandler),
+		processTypes: make(map[string][]string),
+	}
+}
+
+// Register makes handler apply transactions of transactionType (for example "SPLIT") to entities
+// of kind (for example "department"), replacing any handler registered for the pair before.
+// Files of the transaction type are recognised by a schema registered with RegisterSchema, and
+// by their name, as in 2403-38_SPLIT.csv.
+func (r *HandlerRegistry) Register(transactionType, kind string, handler TransactionHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[handlerKey{strings.ToUpper(transactionType), kind}] = handler
+}
+
+// Lookup returns the handler registered for transactionType and kind, falling back to the one
+// registered for AnyKind
+func (r *HandlerRegistry) Lookup(transactionType, kind string) (TransactionHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	transactionType = strings.ToUpper(transactionType)
+	if handler, ok := r.handlers[handlerKey{transactionType, kind}]; ok {
+		return handler, true
+	}
+	handler, ok := r.handlers[handlerKey{transactionType, AnyKind}]
+	return handler, ok
+}
+
+// handlerFor returns the handler a load of processType applies transactions of transactionType and
+// kind with. A process type that loads kind only through AnyKind, like document, uses only the
+// handlers registered for AnyKind, so an organisation or person row in its files is rejected rather
+// than applied by the handler of that kind.
+func (r *HandlerRegistry) handlerFor(processType, transactionType, kind string) (TransactionHandler, bool) {
+	if containsString(r.Kinds(processType), kind) {
+		return r.Lookup(transactionType, kind)
+	}
+	return r.Lookup(transactionType, AnyKind)
+}
+
+// loads reports whether processType loads transactions of kind
+func (r *HandlerRegistry) loads(processType, kind string) bool {
+	kinds := r.Kinds(processType)
+	return containsString(kinds, kind) || containsString(kinds, AnyKind)
+}
+
+// RegisterProcessType makes processType load the transactions of the given entity kinds.
+// Registering a process type again adds to its kinds.
+func (r *HandlerRegistry) RegisterProcessType(processType string, kinds ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kind := range kinds {
+		if !containsString(r.processTypes[processType], kind) {
+			r.processTypes[processType] = append(r.processTypes[processType], kind)
+		}
+	}
+}
+
+// ProcessTypes returns the registered process types, sorted
+func (r *HandlerRegistry) ProcessTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	processTypes := make([]string, 0, len(r.processTypes))
+	for processType := range r.processTypes {
+		processTypes = append(processTypes, processType)
+	}
+	sort.Strings(processTypes)
+	return processTypes
+}
+
+// Kinds returns the entity kinds loaded by processType, or nil if it is not registered
+func (r *HandlerRegistry) Kinds(processType string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.processTypes[processType]...)
+}
+
+// TransactionTypes returns the transaction types that have at least one handler, sorted
+func (r *HandlerRegistry) TransactionTypes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := make(map[string]bool)
+	var transactionTypes []string
+	for key := range r.handlers {
+		if !seen[key.transactionType] {
+			seen[key.transactionType] = true
+			transactionTypes = append(transactionTypes, key.transactionType)
+		}
+	}
+	sort.Strings(transactionTypes)
+	return transactionTypes
+}
+
+// transactionKind returns the entity kind a transaction applies to: its child_type column, or its
+// type column for MOVE, MERGE and RENAME files
+func transactionKind(transaction map[string]interface{}) string {
+	if kind, ok := transaction["child_type"].(string); ok && strings.TrimSpace(kind) != "" {
+		return strings.TrimSpace(kind)
+	}
+	kind, _ := transaction["type"].(string)
+	return strings.TrimSpace(kind)
+}
+
+// missingKind reports a transaction whose kind is empty, naming the column it should have been read from
+func missingKind(transaction map[string]interface{}) error {
+	field := "type"
+	if _, ok := transaction["child_type"]; ok {
+		field = "child_type"
+	}
+	reason := fmt.Sprintf("%s is required and must be a non-empty string", field)
+	if _, ok := transaction[field]; !ok {
+		reason = "column is missing"
+	}
+	return transactionSource(transaction).locate(invalidField(field, reason, nil))
+}
+
+// TransactionHandlers returns the client's handler registry, so that custom transaction types can be
+// registered on it before processing
+func (c *Client) TransactionHandlers() *HandlerRegistry {
+	return c.handlers
+}
+
+// FuncHandler builds a TransactionHandler from a function. Required lists the fields Validate insists on;
+// a "date" field must also be a YYYY-MM-DD date. Check, if set, validates the transaction further, for
+// example by decoding it with DecodeAddTx. Summary, if set, describes the transaction.
+type FuncHandler struct {
+	Required []string
+	Check    func(transaction map[string]interface{}) error
+	Summary  func(transaction map[string]interface{}) string
+	Run      func(ctx context.Context, c *Client, transaction map[string]interface{}, entityCounters map[string]int) error
+}
+
+// Validate checks that the required fields are non-empty strings and that the date parses, then calls Check
+func (h FuncHandler) Validate(transaction map[string]interface{}) error {
+	source := transactionSource(transaction)
+	for _, field := range h.Required {
+		value, ok := transaction[field].(string)
+		if !ok || strings.TrimSpace(value) == "" {
+			return source.locate(invalidField(field, fmt.Sprintf("%s is required and must be a non-empty string", field), nil))
+		}
+		if field == "date" {
+			if _, err := time.Parse(dateLayout, strings.TrimSpace(value)); err != nil {
+				return source.locate(invalidField("date", "failed to parse date", err))
+			}
+		}
+	}
+	if h.Check != nil {
+		return h.Check(transaction)
+	}
+	return nil
+}
+
+// Apply calls Run
+func (h FuncHandler) Apply(ctx context.Context, c *Client, transaction map[string]interface{}, entityCounters map[string]int) error {
+	return h.Run(ctx, c, transaction, entityCounters)
+}
+
+// Describe calls Summary, or names the transaction ID when there is none
+func (h FuncHandler) Describe(transaction map[string]interface{}) string {
+	if h.Summary != nil {
+		return h.Summary(transaction)
+	}
+	return fmt.Sprintf("%s %s", transaction["file_type"], transaction["transaction_id"])
+}
+
+// BuiltinHandlers returns a new registry holding the ADD, TERMINATE, MOVE, MERGE, SPLIT and RENAME handlers
+// and schemas, and the organisation, person and document process types. Each call returns a separate registry.
+func BuiltinHandlers() *HandlerRegistry {
+	r := NewHandlerRegistry()
+	for _, schema := range builtinSchemas {
+		r.RegisterSchema(schema)
+	}
+	r.RegisterProcessType("organisation", "minister", "department")
+	r.RegisterProcessType("person", "citizen")
+	r.RegisterProcessType("document", AnyKind)
+
+	addOrg := FuncHandler{
+		Check:   checkAdd,
+		Summary: describeParentChild("add", "under"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			childType := transactionKind(tx)
+			counter, err := c.AddOrgEntityContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters[childType] = counter
+			return nil
+		},
+	}
+	r.Register("ADD", "minister", addOrg)
+	r.Register("ADD", "department", addOrg)
+	r.Register("ADD", "citizen", FuncHandler{
+		Check:   checkAdd,
+		Summary: describeParentChild("appoint", "to"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.AddPersonEntityContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["citizen"] = counter
+			return nil
+		},
+	})
+	r.Register("ADD", AnyKind, FuncHandler{
+		Check:   checkDocument,
+		Summary: describeParentChild("add", "to"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.AddDocumentEntityContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["document"] = counter
+			return nil
+		},
+	})
+
+	terminateOrg := FuncHandler{
+		Check:   checkTerminate,
+		Summary: describeParentChild("terminate", "under"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			return c.TerminateOrgEntityContext(ctx, tx)
+		},
+	}
+	r.Register("TERMINATE", "minister", terminateOrg)
+	r.Register("TERMINATE", "department", terminateOrg)
+	r.Register("TERMINATE", "citizen", FuncHandler{
+		Check:   checkTerminate,
+		Summary: describeParentChild("terminate", "at"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			return c.TerminatePersonEntityContext(ctx, tx)
+		},
+	})
+
+	r.Register("MOVE", "department", FuncHandler{
+		Check:   checkMove("department"),
+		Summary: describeMove,
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			return c.MoveDepartmentContext(ctx, tx)
+		},
+	})
+	r.Register("MOVE", "minister", FuncHandler{
+		Check:   checkMove("minister"),
+		Summary: describeMove,
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			return c.MoveMinisterContext(ctx, tx)
+		},
+	})
+	r.Register("MOVE", "citizen", FuncHandler{
+		Check:   checkMove("citizen"),
+		Summary: describeMove,
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			return c.MovePersonContext(ctx, tx)
+		},
+	})
+
+	r.Register("MERGE", "minister", FuncHandler{
+		Check:   checkMerge("minister"),
+		Summary: describeOldNew("merge", "into"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.MergeMinistersContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["minister"] = counter
+			return nil
+		},
+	})
+	r.Register("MERGE", "department", FuncHandler{
+		Check:   checkMerge("department"),
+		Summary: describeOldNew("merge", "into"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.MergeDepartmentsContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["department"] = counter
+			return nil
+		},
+	})
+
+	r.Register("SPLIT", "minister", FuncHandler{
+		Check:   checkSplit("minister"),
+		Summary: describeOldNew("split", "into"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			_, err := c.SplitMinisterContext(ctx, tx, counters)
+			return err
+		},
+	})
+	r.Register("SPLIT", "department", FuncHandler{
+		Check:   checkSplit("department"),
+		Summary: describeOldNew("split", "into"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			_, err := c.SplitDepartmentContext(ctx, tx, counters)
+			return err
+		},
+	})
+
+	r.Register("RENAME", "minister", FuncHandler{
+		Check:   checkRename("minister"),
+		Summary: describeOldNew("rename", "to"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.RenameMinisterContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["minister"] = counter
+			return nil
+		},
+	})
+	r.Register("RENAME", "department", FuncHandler{
+		Check:   checkRename("department"),
+		Summary: describeOldNew("rename", "to"),
+		Run: func(ctx context.Context, c *Client, tx map[string]interface{}, counters map[string]int) error {
+			counter, err := c.RenameDepartmentContext(ctx, tx, counters)
+			if err != nil {
+				return err
+			}
+			counters["department"] = counter
+			return nil
+		},
+	})
+
+	return r
+}
+
+// checkAdd, checkTerminate and checkDocument validate builtin transactions by decoding them
+func checkAdd(tx map[string]interface{}) error {
+	_, err := DecodeAddTx(tx)
+	return err
+}
+
+func checkTerminate(tx map[string]interface{}) error {
+	_, err := DecodeTerminateTx(tx)
+	return err
+}
+
+func checkDocument(tx map[string]interface{}) error {
+	_, err := DecodeDocumentTx(tx)
+	return err
+}
+
+// checkMove, checkMerge, checkSplit and checkRename validate transactions of the kind the handler is registered for
+func checkMove(kind string) func(map[string]interface{}) error {
+	return func(tx map[string]interface{}) error {
+		_, err := decodeMoveTx(tx, kind)
+		return err
+	}
+}
+
+func checkMerge(kind string) func(map[string]interface{}) error {
+	return func(tx map[string]interface{}) error {
+		_, err := decodeMergeTx(tx, kind)
+		return err
+	}
+}
+
+func checkSplit(kind string) func(map[string]interface{}) error {
+	return func(tx map[string]interface{}) error {
+		_, err := decodeSplitTx(tx, kind)
+		return err
+	}
+}
+
+func checkRename(kind string) func(map[string]interface{}) error {
+	return func(tx map[string]interface{}) error {
+		_, err := decodeRenameTx(tx, kind)
+		return err
+	}
+}
+
+// describeParentChild describes transactions that relate a child to a parent, like
+// "add department 'Department of X' under 'Minister of Y' on 2020-01-01"
+func describeParentChild(verb, preposition string) func(map[string]interface{}) string {
+	return func(tx map[string]interface{}) string {
+		return fmt.Sprintf("%s %s '%s' %s '%s' on %s", verb, transactionKind(tx), tx["child"], preposition, tx["parent"], tx["date"])
+	}
+}
+
+// describeOldNew describes transactions that replace old entities by a new one
+func describeOldNew(verb, preposition string) func(map[string]interface{}) string {
+	return func(tx map[string]interface{}) string {
+		return fmt.Sprintf("%s %s '%s' %s '%s' on %s", verb, transactionKind(tx), tx["old"], preposition, tx["new"], tx["date"])
+	}
+}
+
+func describeMove(tx map[string]interface{}) string {
+	return fmt.Sprintf("move %s '%s' from '%s' to '%s' on %s", transactionKind(tx), tx["child"], tx["old_parent"], tx["new_parent"], tx["date"])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,372 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyRoots maps the folders of a data root to the process type of the transactions they hold
+var historyRoots = []struct {
+	folder      string
+	processType string
+}{
+	{"documents", "document"},
+	{"orgchart", "organisation"},
+	{"people", "person"},
+}
+
+// HistoryStep is one data directory of a history: a folder that holds transaction files
+type HistoryStep struct {
+	Path        string `json:"path"`
+	President   string `json:"president"`
+	ProcessType string `json:"processType"`
+	// Date is the date folder the directory is in. Document folders have none and take the
+	// earliest date of their president.
+	Date string `json:"date"`
+	// Gazette is the gazette number the directory is named after, e.g. 2403-38-1, if any
+	Gazette string `json:"gazette"`
+	// Presidency is set for people directories that appoint a president, which are loaded
+	// before the organisation directories of their date
+	Presidency bool `json:"presidency"`
+	// Transactions is the number of transactions the directory holds
+	Transactions int `json:"transactions"`
+
+	gazette []int
+}
+
+// HistoryResult is a step of a history that was loaded
+type HistoryResult struct {
+	Step     HistoryStep   `json:"step"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HistorySummary is what loading a history did. Failed is set when the load stopped on a step.
+type HistorySummary struct {
+	Loaded   []HistoryResult `json:"loaded"`
+	Failed   *HistoryStep    `json:"failed,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// HistoryOrderFileName is the manifest in a data root that lists its directories in the order
+// FindHistory returns them
+const HistoryOrderFileName = "history.yaml"
+
+// FindHistory walks the documents, orgchart and people folders of dataRoot and returns every
+// directory holding transaction files, in the order they are loaded. Only the named presidents are
+// included; none means all of them. The directories listed in the data root's HistoryOrderFileName
+// come in its order, and those it skips are left out. The others are ordered by date, then
+// documents before presidencies before organisation before people, then by gazette number, and
+// come before the first listed directory that would sort after them.
+func (c *Client) FindHistory(dataRoot string, presidents []string) ([]HistoryStep, error) {
+	wanted := map[string]bool{}
+	for _, president := range presidents {
+		wanted[president] = true
+	}
+
+	var steps []HistoryStep
+	for _, root := range historyRoots {
+		rootDir := filepath.Join(dataRoot, root.folder)
+		entries, err := os.ReadDir(rootDir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", rootDir, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || (len(wanted) > 0 && !wanted[entry.Name()]) {
+				continue
+			}
+			found, err := c.findHistorySteps(filepath.Join(rootDir, entry.Name()), entry.Name(), root.processType)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, found...)
+		}
+	}
+
+	// Document folders are undated; they are loaded with the first folder of their president
+	firstDates := map[string]string{}
+	for _, step := range steps {
+		if step.Date != "" && (firstDates[step.President] == "" || step.Date < firstDates[step.President]) {
+			firstDates[step.President] = step.Date
+		}
+	}
+	for i := range steps {
+		if steps[i].Date == "" {
+			steps[i].Date = firstDates[steps[i].President]
+		}
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return historyLess(steps[i], steps[j])
+	})
+	return orderHistory(dataRoot, steps)
+}
+
+// historyLess reports whether step a is loaded before step b when the history order file lists
+// neither
+func historyLess(a, b HistoryStep) bool {
+	if a.Date != b.Date {
+		return a.Date < b.Date
+	}
+	if a.rank() != b.rank() {
+		return a.rank() < b.rank()
+	}
+	if n := compareGazettes(a.gazette, b.gazette); n != 0 {
+		return n < 0
+	}
+	return a.Path < b.Path
+}
+
+// orderHistory puts steps, sorted by historyLess, in the order of the HistoryOrderFileName
+// manifest of dataRoot, if there is one
+func orderHistory(dataRoot string, steps []HistoryStep) ([]HistoryStep, error) {
+	orderPath := filepath.Join(dataRoot, HistoryOrderFileName)
+	if _, err := os.Stat(orderPath); os.IsNotExist(err) {
+		return steps, nil
+	}
+	order, err := ReadManifest(orderPath)
+	if err != nil {
+		return nil, err
+	}
+	position := make(map[string]int, len(order.Steps))
+	skipped := map[string]bool{}
+	for i, step := range order.Steps {
+		path := filepath.Join(order.Dir, step.Path)
+		if step.Skip {
+			skipped[path] = true
+			continue
+		}
+		position[path] = i
+	}
+
+	var listed, unlisted []HistoryStep
+	for _, step := range steps {
+		if skipped[step.Path] {
+			continue
+		}
+		if _, ok := position[step.Path]; ok {
+			listed = append(listed, step)
+		} else {
+			unlisted = append(unlisted, step)
+		}
+	}
+	sort.SliceStable(listed, func(i, j int) bool {
+		return position[listed[i].Path] < position[listed[j].Path]
+	})
+
+	for _, step := range unlisted {
+		at := len(listed)
+		for i, other := range listed {
+			if historyLess(step, other) {
+				at = i
+				break
+			}
+		}
+		listed = append(listed[:at], append([]HistoryStep{step}, listed[at:]...)...)
+	}
+	return listed, nil
+}
+
+// findHistorySteps returns the directories under presidentDir that hold transaction files
+func (c *Client) findHistorySteps(presidentDir, president, processType string) ([]HistoryStep, error) {
+	var steps []HistoryStep
+	err := filepath.WalkDir(presidentDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		files, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("failed to read directory %s: %w", path, err)
+		}
+		var csvFiles []string
+		for _, file := range files {
+			if !file.IsDir() && strings.HasSuffix(file.Name(), ".csv") {
+				csvFiles = append(csvFiles, file.Name())
+			}
+		}
+		if len(csvFiles) == 0 {
+			return nil
+		}
+
+		step, err := c.historyStep(presidentDir, path, president, processType, csvFiles)
+		if err != nil {
+			return err
+		}
+		steps = append(steps, step)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", presidentDir, err)
+	}
+	return steps, nil
+}
+
+// historyStep describes the directory path under presidentDir holding csvFiles
+func (c *Client) historyStep(presidentDir, path, president, processType string, csvFiles []string) (HistoryStep, error) {
+	step := HistoryStep{Path: path, President: president, ProcessType: processType}
+
+	// The first folder named like a date dates the directory; the folders below it name the gazette
+	relative, err := filepath.Rel(presidentDir, path)
+	if err != nil {
+		return step, err
+	}
+	var gazetteFolders []string
+	for _, folder := range strings.Split(filepath.ToSlash(relative), "/") {
+		if step.Date == "" {
+			if _, err := time.Parse(dateLayout, folder); err == nil {
+				step.Date = folder
+			}
+			continue
+		}
+		gazetteFolders = append(gazetteFolders, folder)
+	}
+	if step.Date == "" && processType != "document" {
+		return step, fmt.Errorf("directory %s is not in a YYYY-MM-DD folder", path)
+	}
+	if len(gazetteFolders) > 0 {
+		step.gazette, step.Gazette = gazetteNumber(gazetteFolders[len(gazetteFolders)-1])
+	} else {
+		// Directories named after their date take the first gazette their files are named after
+		for _, name := range csvFiles {
+			if number, text := gazetteNumber(name); number != nil && (step.gazette == nil || compareGazettes(number, step.gazette) < 0) {
+				step.gazette, step.Gazette = number, text
+			}
+		}
+	}
+
+	transactions, err := c.loadDataDir(path)
+	if err != nil {
+		return step, err
+	}
+	step.Transactions = len(transactions)
+	for _, transaction := range transactions {
+		if processType == "person" && transaction["file_type"] == "ADD" && transaction["rel_type"] == "AS_PRESIDENT" {
+			step.Presidency = true
+		}
+	}
+	return step, nil
+}
+
+// rank orders the steps of the same date: documents, presidencies, organisation, then people
+func (s HistoryStep) rank() int {
+	switch {
+	case s.ProcessType == "document":
+		return 0
+	case s.Presidency:
+		return 1
+	case s.ProcessType == "organisation":
+		return 2
+	}
+	return 3
+}
+
+// gazetteNumber returns the numbers a folder or file name starts with, e.g. [2403 38 1] for
+// 2403-38-1 or 2403-38-1_ADD.csv, and the part of the name they are written in, or nil if it does
+// not start with a number
+func gazetteNumber(name string) ([]int, string) {
+	var number []int
+	end := 0
+	for end < len(name) {
+		next := strings.IndexAny(name[end:], "-_.")
+		if next < 0 {
+			next = len(name) - end
+		}
+		n, err := strconv.Atoi(name[end : end+next])
+		if err != nil {
+			break
+		}
+		number = append(number, n)
+		end += next + 1
+	}
+	if number == nil {
+		return nil, ""
+	}
+	return number, name[:end-1]
+}
+
+// compareGazettes compares gazette numbers part by part; a number sorts before the numbers it is a
+// prefix of, and no number sorts first
+func compareGazettes(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return len(a) - len(b)
+}
+
+// LoadHistory processes the transactions of each step in order, as ProcessTransactions would, and
+// stops on the first step that fails. The summary covers the steps loaded and the one the load
+// stopped on.
+func (c *Client) LoadHistory(steps []HistoryStep) (*HistorySummary, error) {
+	return c.LoadHistoryContext(context.Background(), steps)
+}
+
+// LoadHistoryContext is like LoadHistory but stops before the next transaction once ctx is done
+func (c *Client) LoadHistoryContext(ctx context.Context, steps []HistoryStep) (*HistorySummary, error) {
+	summary := &HistorySummary{Loaded: []HistoryResult{}}
+	started := time.Now()
+	defer func() { summary.Duration = time.Since(started) }()
+
+	for _, step := range steps {
+		c.logf("Loading %s transactions of %s from %s\n", step.ProcessType, step.Date, step.Path)
+		stepStarted := time.Now()
+		if err := c.ProcessTransactionsContext(ctx, step.Path, step.ProcessType); err != nil {
+			failed := step
+			summary.Failed = &failed
+			summary.Error = err.Error()
+			return summary, fmt.Errorf("failed to load %s: %w", step.Path, err)
+		}
+		summary.Loaded = append(summary.Loaded, HistoryResult{Step: step, Duration: time.Since(stepStarted)})
+	}
+	return summary, nil
+}
+
+// Transactions returns the number of transactions in the steps loaded
+func (s *HistorySummary) Transactions() int {
+	total := 0
+	for _, result := range s.Loaded {
+		total += result.Step.Transactions
+	}
+	return total
+}
+
+// WriteText writes the number of directories and transactions loaded per process type, and the
+// directory the load stopped on
+func (s *HistorySummary) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Loaded %d directories (%d transactions) in %s:\n", len(s.Loaded), s.Transactions(), s.Duration.Round(time.Millisecond))
+	for _, root := range historyRoots {
+		directories, transactions := 0, 0
+		for _, result := range s.Loaded {
+			if result.Step.ProcessType == root.processType {
+				directories++
+				transactions += result.Step.Transactions
+			}
+		}
+		fmt.Fprintf(&b, "  %s: %d directories, %d transactions\n", root.processType, directories, transactions)
+	}
+	if s.Failed != nil {
+		fmt.Fprintf(&b, "Stopped on %s (%s, %s): %s\n", s.Failed.Path, s.Failed.ProcessType, s.Failed.Date, s.Error)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
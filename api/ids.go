@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// Entity and relationship IDs are derived from the transaction that creates them: its key (see
+// transactionKey) and the entity's role in it, see EntityID, or the relationship's name and ends, see
+// RelationshipID. Replaying a transaction therefore names the same entities and relationships, which
+// is how the operations recognise a transaction that has already been applied and skip it.
+
+// EntityID returns the ID of the entity created in the given role, e.g. "minister", by the
+// transaction with the given key
+func EntityID(key, role string) string {
+	return fmt.Sprintf("%s_%s", key, role)
+}
+
+// RelationshipID returns the ID of the relationship named name from parentID to childID started by
+// the transaction with the given key
+func RelationshipID(key, name, parentID, childID string) string {
+	return fmt.Sprintf("%s_%s_%s_%s", key, name, parentID, childID)
+}
+
+// relationshipKey returns the key of the transaction that started rel, a relationship of parentID
+func relationshipKey(rel models.Relationship, parentID string) string {
+	return strings.TrimSuffix(rel.ID, RelationshipID("", rel.Name, parentID, rel.RelatedEntityID))
+}
+
+// transactionKey returns the key of a transaction of transactionType for EntityID and
+// RelationshipID: the type and its ID, or the type and its date for transactions read without one.
+// The type keeps apart transactions of different files that share an ID.
+func transactionKey(transactionType, transactionID string, date time.Time) string {
+	if transactionID != "" {
+		return transactionType + "/" + transactionID
+	}
+	return transactionType + "/" + date.Format(dateLayout)
+}
+
+// hasRelationship reports whether the relationship with the given ID starts at entityID
+func (c *Client) hasRelationship(ctx context.Context, entityID, relationshipID string) (bool, error) {
+	relations, err := c.GetRelatedEntitiesContext(ctx, entityID, &models.Relationship{
+		ID:        relationshipID,
+		Direction: DirectionOutgoing,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get relationship %s of %s: %w", relationshipID, entityID, err)
+	}
+	for _, rel := range relations {
+		if rel.ID == relationshipID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// startedBy reports whether entityID has a relationship named name in the given direction started
+// by the transaction with the given key
+func (c *Client) startedBy(ctx context.Context, entityID, name, key, direction string) (bool, error) {
+	relations, err := c.GetRelatedEntitiesContext(ctx, entityID, &models.Relationship{
+		Name:      name,
+		Direction: direction,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get %s relationships of %s: %w", name, entityID, err)
+	}
+
+	for _, rel := range relations {
+		parentID, childID := entityID, rel.RelatedEntityID
+		if direction == DirectionIncoming {
+			parentID, childID = rel.RelatedEntityID, entityID
+		}
+		if rel.ID == RelationshipID(key, name, parentID, childID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lineageRecorded reports whether an entity of the given kind and name has a lineage relationship
+// (RENAMED_TO, MERGED_INTO or SPLIT_INTO) named name started by the transaction with the given key
+func (c *Client) lineageRecorded(ctx context.Context, kind models.Kind, entityName, name, key string) (bool, error) {
+	results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{Kind: &kind, Name: entityName})
+	if err != nil {
+		return false, fmt.Errorf("failed to search for %s '%s': %w", kind.Minor, entityName, err)
+	}
+	for _, result := range results {
+		applied, err := c.startedBy(ctx, result.ID, name, key, DirectionOutgoing)
+		if err != nil || applied {
+			return applied, err
+		}
+	}
+	return false, nil
+}
+
+// endedMinisters returns the IDs of the ministers named ministerName whose relationship with the
+// president ended on dateISO. A minister renamed or terminated by a gazette looks like this when the
+// gazette is loaded again.
+func (c *Client) endedMinisters(ctx context.Context, presidentName, ministerName, dateISO string) ([]string, error) {
+	president, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
+	if err != nil {
+		return nil, err
+	}
+	presidentRelations, err := c.GetRelatedEntitiesContext(ctx, president.ID, &models.Relationship{Name: "AS_MINISTER"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get president's relationships: %w", err)
+	}
+
+	var ended []string
+	for _, rel := range presidentRelations {
+		if rel.EndTime != dateISO {
+			continue
+		}
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: rel.RelatedEntityID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for entity %s: %w", rel.RelatedEntityID, err)
+		}
+		if len(results) > 0 && results[0].Kind.Minor == "minister" && results[0].Name == ministerName {
+			ended = append(ended, rel.RelatedEntityID)
+		}
+	}
+	return ended, nil
+}
+
+// skipApplied logs that the transaction with the given key is skipped because it was applied before
+func (c *Client) skipApplied(key, what string) {
+	c.logf("Skipping transaction %s: %s already applied\n", key, what)
+}
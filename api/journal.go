@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// JournalFileName is the file in a data directory that records the transactions loaded from it
+const JournalFileName = ".orgchart_journal.jsonl"
+
+// JournalMode controls whether loading a data directory keeps a journal of the transactions applied
+type JournalMode int
+
+const (
+	// JournalOff loads without a journal
+	JournalOff JournalMode = iota
+	// JournalRecord starts a new journal, replacing any the data directory holds
+	JournalRecord
+	// JournalResume skips the transactions the journal records and adds the rest to it, so a load
+	// that failed continues from the transaction it failed on
+	JournalResume
+)
+
+// JournalEntry records a transaction that was applied and whose writes all reached the Update API
+type JournalEntry struct {
+	TransactionID string `json:"transaction_id"`
+	// Hash is the content hash of the transaction's row, see TransactionHash
+	Hash string `json:"hash"`
+	// Entities and Relationships are the IDs of the entities and relationships the transaction created
+	Entities      []string `json:"entities,omitempty"`
+	Relationships []string `json:"relationships,omitempty"`
+	// Counters are the entity counters after the transaction, so that a resumed load counts the
+	// entities it creates on from them
+	Counters map[string]int `json:"counters,omitempty"`
+}
+
+// loadJournal records the transactions of one load of a data directory. Entries are held back until
+// the relationship writes of their transactions have been flushed.
+type loadJournal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	done    map[string]JournalEntry
+	current *JournalEntry
+	pending []JournalEntry
+}
+
+// SetJournalMode sets whether ProcessTransactions keeps a journal
+// in the data directory it loads, and whether it resumes from it
+func (c *Client) SetJournalMode(mode JournalMode) {
+	c.journalMode = mode
+}
+
+// JournalMode returns the journal mode of the client
+func (c *Client) JournalMode() JournalMode {
+	return c.journalMode
+}
+
+// ReadJournal returns the entries of the journal in dataDir, in the order they were written.
+// A data directory without a journal has no entries.
+func ReadJournal(dataDir string) ([]JournalEntry, error) {
+	path := filepath.Join(dataDir, JournalFileName)
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to read line %d of journal %s: %w", line, path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// TransactionHash returns the content hash of a transaction loaded from a CSV file: a SHA-256 of
+// its type and columns. The file it was read from does not count, so moving a row between files
+// keeps its hash.
+func TransactionHash(transaction map[string]interface{}) string {
+	keys := make([]string, 0, len(transaction))
+	for key := range transaction {
+		if key != sourceKey && key != "file_name" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%q=%q\n", key, fmt.Sprint(transaction[key]))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// openJournal opens the journal of dataDir for a load in the client's journal mode, or returns nil
+// when journaling is off. When resuming, entityCounters are advanced past the entities the journal
+// records.
+func (c *Client) openJournal(dataDir string, entityCounters map[string]int) (*loadJournal, error) {
+	if c.journalMode == JournalOff {
+		return nil, nil
+	}
+	journal := &loadJournal{
+		path: filepath.Join(dataDir, JournalFileName),
+		done: make(map[string]JournalEntry),
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if c.journalMode == JournalResume {
+		entries, err := ReadJournal(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			journal.done[entry.TransactionID] = entry
+			for kind, counter := range entry.Counters {
+				if counter > entityCounters[kind] {
+					entityCounters[kind] = counter
+				}
+			}
+		}
+		if len(entries) > 0 {
+			c.logf("Resuming from journal %s: %d transactions already applied\n", journal.path, len(entries))
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(journal.path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", journal.path, err)
+	}
+	journal.file = file
+	c.journal = journal
+	return journal, nil
+}
+
+// closeJournal writes the entries still held back when commit is set and closes the journal
+func (c *Client) closeJournal(journal *loadJournal, commit bool) error {
+	if journal == nil {
+		return nil
+	}
+	c.journal = nil
+	var err error
+	if commit {
+		err = journal.commit()
+	}
+	if closeErr := journal.file.Close(); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to close journal %s: %w", journal.path, closeErr))
+	}
+	return err
+}
+
+// commitJournal writes the finished transactions to the journal once none of their relationship
+// writes are held back in the batch
+func (c *Client) commitJournal(journal *loadJournal) error {
+	if journal == nil || c.batch.hasPending() {
+		return nil
+	}
+	return journal.commit()
+}
+
+// applied reports whether the journal records transaction as applied. A recorded transaction whose
+// row has changed since is an error, as skipping it would leave the graph out of step with the files.
+func (j *loadJournal) applied(transaction map[string]interface{}) (bool, error) {
+	if j == nil {
+		return false, nil
+	}
+	transactionID := fmt.Sprint(transaction["transaction_id"])
+	entry, ok := j.done[transactionID]
+	if !ok {
+		return false, nil
+	}
+	if entry.Hash != TransactionHash(transaction) {
+		return false, fmt.Errorf("transaction %s changed since journal %s recorded it as applied; load the directory without resuming to apply it again", transactionID, j.path)
+	}
+	return true, nil
+}
+
+// begin starts recording the writes of transaction
+func (j *loadJournal) begin(transaction map[string]interface{}) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.current = &JournalEntry{
+		TransactionID: fmt.Sprint(transaction["transaction_id"]),
+		Hash:          TransactionHash(transaction),
+	}
+}
+
+// finish ends the transaction being recorded. Its entry is written on the next commit if it was
+// applied; a transaction that was skipped is left for a load that applies it.
+func (j *loadJournal) finish(applied bool, entityCounters map[string]int) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.current == nil {
+		return
+	}
+	if !applied {
+		j.current = nil
+		return
+	}
+	j.current.Counters = make(map[string]int, len(entityCounters))
+	for kind, counter := range entityCounters {
+		j.current.Counters[kind] = counter
+	}
+	j.pending = append(j.pending, *j.current)
+	j.current = nil
+}
+
+// recordEntity records an entity created by the transaction being recorded
+func (j *loadJournal) recordEntity(entityID string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.current != nil && entityID != "" {
+		j.current.Entities = append(j.current.Entities, entityID)
+	}
+}
+
+// recordRelationships records the relationships created by an update in the transaction being
+// recorded. Entries without a related entity only end or change existing relationships.
+func (j *loadJournal) recordRelationships(entries []models.RelationshipEntry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.current == nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Value.RelatedEntityID != "" {
+			j.current.Relationships = append(j.current.Relationships, relationshipEntryID(entry))
+		}
+	}
+}
+
+// commit appends the finished transactions to the journal file
+func (j *loadJournal) commit() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.pending) == 0 {
+		return nil
+	}
+	for _, entry := range j.pending {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal entry of transaction %s: %w", entry.TransactionID, err)
+		}
+		if _, err := j.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write journal %s: %w", j.path, err)
+		}
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write journal %s: %w", j.path, err)
+	}
+	j.pending = nil
+	return nil
+}
@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// LineageRelations are the relationships from an entity to the entities that replaced it
+var LineageRelations = []string{"RENAMED_TO", "MERGED_INTO", "SPLIT_INTO"}
+
+// LineageOptions selects what GetLineage follows
+type LineageOptions struct {
+	// Direction is DirectionOutgoing for successors only, DirectionIncoming for predecessors only,
+	// or DirectionBoth (or empty) for both
+	Direction string
+	// AsOf (RFC3339) ignores renames, merges and splits that took effect after that time. Empty means all of them.
+	AsOf string
+	// MaxDepth limits how many steps are followed in each direction; 0 means no limit
+	MaxDepth int
+}
+
+// LineageNode is an entity in a lineage tree. Relation and Date describe the rename, merge or split
+// connecting it to its parent node, and Children are its own predecessors or successors.
+type LineageNode struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Created    string         `json:"created,omitempty"`
+	Terminated string         `json:"terminated,omitempty"`
+	Relation   string         `json:"relation,omitempty"`
+	Date       string         `json:"date,omitempty"`
+	Children   []*LineageNode `json:"children,omitempty"`
+	// Repeated marks an entity already shown elsewhere in the tree, for example where two merged
+	// entities share a successor or the edges form a cycle; its children are not repeated
+	Repeated bool `json:"repeated,omitempty"`
+}
+
+// Lineage is an entity with the tree of entities it replaced and the tree of entities that replaced it
+type Lineage struct {
+	Entity       *LineageNode   `json:"entity"`
+	Predecessors []*LineageNode `json:"predecessors"`
+	Successors   []*LineageNode `json:"successors"`
+}
+
+// Current returns the entities that the entity has become: the successors that were not replaced
+// themselves, or the entity itself when it has no successors
+func (l *Lineage) Current() []*LineageNode {
+	return lineageLeaves(l.Entity, l.Successors)
+}
+
+// Origins returns the earliest predecessors of the entity, or the entity itself when it has none
+func (l *Lineage) Origins() []*LineageNode {
+	return lineageLeaves(l.Entity, l.Predecessors)
+}
+
+// lineageLeaves returns the distinct nodes without children below nodes, or root when nodes is empty
+func lineageLeaves(root *LineageNode, nodes []*LineageNode) []*LineageNode {
+	if len(nodes) == 0 {
+		return []*LineageNode{root}
+	}
+	var leaves []*LineageNode
+	seen := map[string]bool{}
+	var walk func(nodes []*LineageNode)
+	walk = func(nodes []*LineageNode) {
+		for _, node := range nodes {
+			if len(node.Children) > 0 {
+				walk(node.Children)
+				continue
+			}
+			if !node.Repeated && !seen[node.ID] {
+				seen[node.ID] = true
+				leaves = append(leaves, node)
+			}
+		}
+	}
+	walk(nodes)
+	return leaves
+}
+
+// WriteText writes the lineage as an indented tree, predecessors first
+func (l *Lineage) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", describeLineageNode(l.Entity))
+	if len(l.Predecessors) > 0 {
+		b.WriteString("Predecessors:\n")
+		writeLineageNodes(&b, l.Predecessors, 1)
+	}
+	if len(l.Successors) > 0 {
+		b.WriteString("Successors:\n")
+		writeLineageNodes(&b, l.Successors, 1)
+	}
+	b.WriteString("Current:\n")
+	for _, node := range l.Current() {
+		fmt.Fprintf(&b, "  %s\n", describeLineageNode(node))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeLineageNodes writes nodes and their children, indented by depth
+func writeLineageNodes(b *strings.Builder, nodes []*LineageNode, depth int) {
+	for _, node := range nodes {
+		fmt.Fprintf(b, "%s%s %s %s", strings.Repeat("  ", depth), shortDate(node.Date), node.Relation, describeLineageNode(node))
+		if node.Repeated {
+			b.WriteString(" (see above)")
+		}
+		b.WriteString("\n")
+		writeLineageNodes(b, node.Children, depth+1)
+	}
+}
+
+// describeLineageNode formats a node as its name, kind, ID and lifetime
+func describeLineageNode(node *LineageNode) string {
+	lifetime := shortDate(node.Created) + " - "
+	if node.Terminated != "" {
+		lifetime += shortDate(node.Terminated)
+	}
+	return fmt.Sprintf("%s [%s %s] (%s)", node.Name, node.Kind, node.ID, strings.TrimSpace(lifetime))
+}
+
+// shortDate shortens an RFC3339 time to its date
+func shortDate(value string) string {
+	if len(value) >= len(dateLayout) {
+		return value[:len(dateLayout)]
+	}
+	return value
+}
+
+// GetLineage follows the RENAMED_TO, MERGED_INTO and SPLIT_INTO relationships of an entity backwards
+// to the entities it replaced and forwards to the entities that replaced it. opts may be nil.
+func (c *Client) GetLineage(entityID string, opts *LineageOptions) (*Lineage, error) {
+	return c.GetLineageContext(context.Background(), entityID, opts)
+}
+
+// GetLineageContext is like GetLineage but stops issuing API calls once ctx is done
+func (c *Client) GetLineageContext(ctx context.Context, entityID string, opts *LineageOptions) (*Lineage, error) {
+	if opts == nil {
+		opts = &LineageOptions{}
+	}
+	direction := strings.ToUpper(strings.TrimSpace(opts.Direction))
+	switch direction {
+	case "", DirectionBoth, DirectionOutgoing, DirectionIncoming:
+	default:
+		return nil, fmt.Errorf("invalid lineage direction '%s': must be %s, %s or %s",
+			opts.Direction, DirectionOutgoing, DirectionIncoming, DirectionBoth)
+	}
+
+	entity, err := c.lineageNode(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+	lineage := &Lineage{Entity: entity, Predecessors: []*LineageNode{}, Successors: []*LineageNode{}}
+
+	if direction != DirectionOutgoing {
+		lineage.Predecessors, err = c.lineageChildren(ctx, entityID, DirectionIncoming, opts, 1, map[string]bool{entityID: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if direction != DirectionIncoming {
+		lineage.Successors, err = c.lineageChildren(ctx, entityID, DirectionOutgoing, opts, 1, map[string]bool{entityID: true})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lineage, nil
+}
+
+// GetLineageByName is like GetLineage for the minister or department with the given name under
+// presidentName. dateISO picks between entities of the same name; empty means any date.
+func (c *Client) GetLineageByName(presidentName, name, dateISO string, opts *LineageOptions) (*Lineage, error) {
+	return c.GetLineageByNameContext(context.Background(), presidentName, name, dateISO, opts)
+}
+
+// GetLineageByNameContext is like GetLineageByName but stops issuing API calls once ctx is done
+func (c *Client) GetLineageByNameContext(ctx context.Context, presidentName, name, dateISO string, opts *LineageOptions) (*Lineage, error) {
+	entityID, err := c.findOrgEntityContext(ctx, presidentName, name, dateISO)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetLineageContext(ctx, entityID, opts)
+}
+
+// findOrgEntityContext returns the ID of the only minister or department named name that the
+// president held, directly or through one of its ministers, on dateISO (or at any time when it is empty)
+func (c *Client) findOrgEntityContext(ctx context.Context, presidentName, name, dateISO string) (string, error) {
+	president, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
+	if err != nil {
+		return "", err
+	}
+	ministerRelations, err := c.GetRelatedEntitiesContext(ctx, president.ID, &models.Relationship{
+		Name:      "AS_MINISTER",
+		Direction: DirectionOutgoing,
+		ActiveAt:  dateISO,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get president's relationships: %w", err)
+	}
+	ministerIDs := make(map[string]bool, len(ministerRelations))
+	for _, rel := range ministerRelations {
+		ministerIDs[rel.RelatedEntityID] = true
+	}
+
+	var candidateIDs []string
+	for _, kind := range []string{"minister", "department"} {
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+			Kind: &models.Kind{
+				Major: "Organisation",
+				Minor: kind,
+			},
+			Name: name,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to search for %s: %w", kind, err)
+		}
+		for _, result := range results {
+			if kind == "minister" {
+				if ministerIDs[result.ID] {
+					candidateIDs = append(candidateIDs, result.ID)
+				}
+				continue
+			}
+			departmentRelations, err := c.GetRelatedEntitiesContext(ctx, result.ID, &models.Relationship{
+				Name:      "AS_DEPARTMENT",
+				Direction: DirectionIncoming,
+				ActiveAt:  dateISO,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to get department relationships: %w", err)
+			}
+			for _, rel := range departmentRelations {
+				if ministerIDs[rel.RelatedEntityID] {
+					candidateIDs = append(candidateIDs, result.ID)
+					break
+				}
+			}
+		}
+	}
+
+	if len(candidateIDs) == 0 {
+		return "", notFoundf("no minister or department named '%s' found under president '%s'", name, presidentName)
+	}
+	if len(candidateIDs) > 1 {
+		return "", ambiguousf(candidateIDs, "multiple ministers or departments named '%s' found under president '%s'", name, presidentName)
+	}
+	return candidateIDs[0], nil
+}
+
+// lineageNode fetches the entity with the given ID as a node without a relation
+func (c *Client) lineageNode(ctx context.Context, entityID string) (*LineageNode, error) {
+	results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: entityID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for entity %s: %w", entityID, err)
+	}
+	if len(results) == 0 {
+		return nil, notFoundf("entity not found: %s", entityID)
+	}
+	return &LineageNode{
+		ID:         results[0].ID,
+		Name:       results[0].Name,
+		Kind:       results[0].Kind.Minor,
+		Created:    results[0].Created,
+		Terminated: results[0].Terminated,
+	}, nil
+}
+
+// lineageChildren returns the predecessors (DirectionIncoming) or successors (DirectionOutgoing) of
+// entityID, oldest relation first, each with its own. seen holds the entities already in the tree.
+func (c *Client) lineageChildren(ctx context.Context, entityID, direction string, opts *LineageOptions, depth int, seen map[string]bool) ([]*LineageNode, error) {
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return []*LineageNode{}, nil
+	}
+	relations, err := c.GetAllRelatedEntitiesContext(ctx, entityID, &RelationFilter{
+		Direction: direction,
+		Names:     LineageRelations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lineage of %s: %w", entityID, err)
+	}
+	sort.SliceStable(relations, func(i, j int) bool {
+		if relations[i].StartTime != relations[j].StartTime {
+			return relations[i].StartTime < relations[j].StartTime
+		}
+		return relations[i].RelatedEntityID < relations[j].RelatedEntityID
+	})
+
+	nodes := []*LineageNode{}
+	for _, rel := range relations {
+		if opts.AsOf != "" && rel.StartTime > opts.AsOf {
+			continue
+		}
+		node, err := c.lineageNode(ctx, rel.RelatedEntityID)
+		if err != nil {
+			return nil, err
+		}
+		node.Relation = rel.Name
+		node.Date = rel.StartTime
+		if seen[node.ID] {
+			node.Repeated = true
+			nodes = append(nodes, node)
+			continue
+		}
+		seen[node.ID] = true
+		node.Children, err = c.lineageChildren(ctx, node.ID, direction, opts, depth+1, seen)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
@@ -0,0 +1,463 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"orgchart_nexoan/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists the directories of a load in the order they are loaded, with what to do about
+// each of them. It is read from YAML or JSON with ReadManifest.
+type Manifest struct {
+	Steps []ManifestStep `json:"steps" yaml:"steps"`
+	// Dir is the directory relative step paths are resolved against. ReadManifest sets it to the
+	// directory of the manifest file; empty means the working directory.
+	Dir string `json:"-" yaml:"-"`
+}
+
+// ManifestStep is one directory of a manifest
+type ManifestStep struct {
+	Path string `json:"path" yaml:"path"`
+	// Type is the process type of the directory. Empty means document, organisation or person
+	// after the documents, orgchart or people folder the path is in, or organisation.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Init creates the government node before the step, unless it exists
+	Init bool `json:"init,omitempty" yaml:"init,omitempty"`
+	// President is the president of the transactions without a president column, instead of the
+	// one the path names
+	President string `json:"president,omitempty" yaml:"president,omitempty"`
+	// ContinueOnError reports a failure of the step and goes on with the next one
+	ContinueOnError bool `json:"continueOnError,omitempty" yaml:"continueOnError,omitempty"`
+	// Skip leaves the directory out, for example while it is being fixed
+	Skip bool   `json:"skip,omitempty" yaml:"skip,omitempty"`
+	Note string `json:"note,omitempty" yaml:"note,omitempty"`
+	// Expect fails the step when it does not load what is expected
+	Expect *ManifestCounts `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+// ManifestCounts are what a step is expected to load; counts left out are not checked. Entities
+// and relationships count what the step creates, so they are not checked when every transaction
+// of the step had already been applied.
+type ManifestCounts struct {
+	Transactions  *int `json:"transactions,omitempty" yaml:"transactions,omitempty"`
+	Entities      *int `json:"entities,omitempty" yaml:"entities,omitempty"`
+	Relationships *int `json:"relationships,omitempty" yaml:"relationships,omitempty"`
+}
+
+// Statuses of the steps of a manifest run
+const (
+	StepLoaded  = "loaded"
+	StepSkipped = "skipped"
+	StepFailed  = "failed"
+	StepNotRun  = "not run"
+)
+
+// ManifestStepResult is what running a step of a manifest did
+type ManifestStepResult struct {
+	Step        ManifestStep `json:"step"`
+	Path        string       `json:"path"`
+	ProcessType string       `json:"processType"`
+	Status      string       `json:"status"`
+	// Transactions is the number of transactions in the directory
+	Transactions int `json:"transactions"`
+	// Entities, Relationships and Ended count the entities and relationships the step created
+	// and the relationships it ended
+	Entities      int           `json:"entities"`
+	Relationships int           `json:"relationships"`
+	Ended         int           `json:"ended"`
+	Duration      time.Duration `json:"duration"`
+	Error         string        `json:"error,omitempty"`
+}
+
+// ManifestResult is what running a manifest did, step by step
+type ManifestResult struct {
+	Steps    []ManifestStepResult `json:"steps"`
+	Duration time.Duration        `json:"duration"`
+}
+
+// ReadManifest reads a manifest from a .json file, or from YAML otherwise. Unknown fields are
+// rejected so that a misspelt option is not silently ignored.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	manifest := &Manifest{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(manifest)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(manifest)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	manifest.Dir = filepath.Dir(path)
+	return manifest, nil
+}
+
+// WriteYAML writes the manifest as YAML
+func (m *Manifest) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(m); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// ParseLoadScript turns a shell script of ./orgchart -data invocations, like load_gr_data.sh, into
+// a manifest of the same loads in the same order. $(pwd)/ is dropped from the paths, so they are
+// relative to the directory the script ran in. The comment on or above an invocation becomes the
+// note of its step, and commented-out invocations become skipped steps. Other lines are ignored.
+func ParseLoadScript(r io.Reader) (*Manifest, error) {
+	manifest := &Manifest{Steps: []ManifestStep{}}
+	scanner := bufio.NewScanner(r)
+	note := ""
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		skip := false
+		if strings.HasPrefix(line, "#") {
+			comment := strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if !strings.HasPrefix(comment, "./orgchart ") {
+				if comment != "" && !strings.HasPrefix(comment, "!/") {
+					note = comment
+				}
+				continue
+			}
+			line, skip = comment, true
+		}
+		if line == "" {
+			note = ""
+			continue
+		}
+		if !strings.HasPrefix(line, "./orgchart ") {
+			continue
+		}
+
+		words, comment := splitShellWords(line)
+		step, err := scriptStep(words[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		step.Skip = skip
+		step.Note = note
+		if comment != "" {
+			step.Note = comment
+		}
+		manifest.Steps = append(manifest.Steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	return manifest, nil
+}
+
+// scriptStep builds the step of the arguments of an ./orgchart invocation
+func scriptStep(args []string) (ManifestStep, error) {
+	step := ManifestStep{Type: "organisation"}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		switch name {
+		case "data", "type":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return step, fmt.Errorf("-%s needs a value", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "type" {
+				step.Type = value
+				continue
+			}
+			step.Path = filepath.Clean(strings.TrimPrefix(value, "$(pwd)/"))
+		case "init":
+			step.Init = !hasValue || value == "true"
+		case "cache":
+			// Lookup caching is set on the client that runs the manifest, not per step
+		default:
+			return step, fmt.Errorf("unsupported flag %s", args[i])
+		}
+	}
+	if step.Path == "" {
+		return step, fmt.Errorf("-data is missing")
+	}
+	return step, nil
+}
+
+// splitShellWords splits a command line into words the way a shell would for the simple quoting
+// and escaping of the load scripts, and returns the trailing comment separately
+func splitShellWords(line string) ([]string, string) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped, inWord = false, true
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == '#' && !inWord:
+			return words, strings.TrimSpace(line[i+1:])
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, ""
+}
+
+// loadTally counts the writes of the manifest step being run
+type loadTally struct {
+	mu            sync.Mutex
+	entities      int
+	relationships int
+	ended         int
+}
+
+// recordEntity counts an entity created
+func (t *loadTally) recordEntity() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entities++
+}
+
+// recordRelationships counts the relationships an update creates and ends
+func (t *loadTally) recordRelationships(entries []models.RelationshipEntry) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, entry := range entries {
+		switch {
+		case entry.Value.RelatedEntityID != "":
+			t.relationships++
+		case entry.Value.EndTime != "":
+			t.ended++
+		}
+	}
+}
+
+// manifestProcessType returns the process type of a step without one, after the folder its path is in
+func manifestProcessType(path string) string {
+	for _, folder := range strings.Split(filepath.ToSlash(path), "/") {
+		for _, root := range historyRoots {
+			if folder == root.folder {
+				return root.processType
+			}
+		}
+	}
+	return "organisation"
+}
+
+// RunManifest loads the directories of the manifest in order, as ProcessTransactions would. Every
+// step is checked before anything is loaded. The run stops
+// on the first step that fails unless the step continues on error; the error returned is that of
+// the step it stopped on.
+func (c *Client) RunManifest(manifest *Manifest) (*ManifestResult, error) {
+	return c.RunManifestContext(context.Background(), manifest)
+}
+
+// RunManifestContext is like RunManifest but stops before the next transaction once ctx is done,
+// whether or not the step continues on error
+func (c *Client) RunManifestContext(ctx context.Context, manifest *Manifest) (*ManifestResult, error) {
+	result := &ManifestResult{Steps: make([]ManifestStepResult, len(manifest.Steps))}
+	for i, step := range manifest.Steps {
+		path := step.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(manifest.Dir, path)
+		}
+		processType := step.Type
+		if processType == "" {
+			processType = manifestProcessType(step.Path)
+		}
+		result.Steps[i] = ManifestStepResult{Step: step, Path: path, ProcessType: processType, Status: StepNotRun}
+
+		if step.Path == "" {
+			return result, fmt.Errorf("step %d: path is missing", i+1)
+		}
+		if len(c.handlers.Kinds(processType)) == 0 {
+			return result, fmt.Errorf("step %d (%s): invalid process type: %s", i+1, step.Path, processType)
+		}
+		if info, err := os.Stat(path); !step.Skip && (err != nil || !info.IsDir()) {
+			return result, fmt.Errorf("step %d: data directory does not exist: %s", i+1, path)
+		}
+	}
+
+	started := time.Now()
+	defer func() { result.Duration = time.Since(started) }()
+	for i := range result.Steps {
+		stepResult := &result.Steps[i]
+		if stepResult.Step.Skip {
+			c.logf("Skipping %s\n", stepResult.Path)
+			stepResult.Status = StepSkipped
+			continue
+		}
+
+		c.logf("Loading %s transactions from %s\n", stepResult.ProcessType, stepResult.Path)
+		stepStarted := time.Now()
+		err := c.runManifestStep(ctx, stepResult)
+		stepResult.Duration = time.Since(stepStarted)
+		if err == nil {
+			stepResult.Status = StepLoaded
+			continue
+		}
+
+		stepResult.Status = StepFailed
+		stepResult.Error = err.Error()
+		if !stepResult.Step.ContinueOnError || ctx.Err() != nil {
+			return result, fmt.Errorf("step %d (%s): %w", i+1, stepResult.Step.Path, err)
+		}
+		c.logf("Continuing after step %d (%s) failed: %v\n", i+1, stepResult.Step.Path, err)
+	}
+	return result, nil
+}
+
+// runManifestStep loads the directory of a step and checks what it loaded against the step's expectations
+func (c *Client) runManifestStep(ctx context.Context, result *ManifestStepResult) error {
+	step := result.Step
+	if step.Init {
+		if err := c.ensureGovernmentNode(ctx); err != nil {
+			return err
+		}
+	}
+
+	c.president = step.President
+	tally := &loadTally{}
+	c.tally = tally
+	defer func() {
+		c.president = ""
+		c.tally = nil
+	}()
+
+	transactions, err := c.loadDataDir(result.Path)
+	if err != nil {
+		return err
+	}
+	result.Transactions = len(transactions)
+
+	err = c.ProcessTransactionsContext(ctx, result.Path, result.ProcessType)
+	result.Entities, result.Relationships, result.Ended = tally.entities, tally.relationships, tally.ended
+	if err != nil {
+		return err
+	}
+
+	if step.Expect == nil {
+		return nil
+	}
+	var mismatches []string
+	check := func(what string, expected *int, actual int) {
+		if expected != nil && *expected != actual {
+			mismatches = append(mismatches, fmt.Sprintf("expected %d %s, got %d", *expected, what, actual))
+		}
+	}
+	check("transactions", step.Expect.Transactions, result.Transactions)
+	// A step applied before creates nothing the second time
+	if result.Entities+result.Relationships+result.Ended > 0 {
+		check("entities", step.Expect.Entities, result.Entities)
+		check("relationships", step.Expect.Relationships, result.Relationships)
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("unexpected load: %s", strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// ensureGovernmentNode creates the government node unless it exists
+func (c *Client) ensureGovernmentNode(ctx context.Context) error {
+	results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: "gov_01"})
+	if err != nil {
+		return fmt.Errorf("failed to search for government node: %w", err)
+	}
+	if len(results) > 0 {
+		return nil
+	}
+	_, err = c.CreateGovernmentNodeContext(ctx)
+	return err
+}
+
+// Count returns the number of steps with the given status
+func (r *ManifestResult) Count(status string) int {
+	count := 0
+	for _, step := range r.Steps {
+		if step.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteText writes a line per step run, skipped or failed, then the totals
+func (r *ManifestResult) WriteText(w io.Writer) error {
+	var b strings.Builder
+	transactions, entities, relationships, ended := 0, 0, 0, 0
+	for i, step := range r.Steps {
+		if step.Status == StepNotRun {
+			continue
+		}
+		fmt.Fprintf(&b, "%3d. %-7s %s (%s)", i+1, step.Status, step.Step.Path, step.ProcessType)
+		if step.Status != StepSkipped {
+			fmt.Fprintf(&b, ": %d transactions, %d entities and %d relationships created, %d relationships ended",
+				step.Transactions, step.Entities, step.Relationships, step.Ended)
+		}
+		if step.Error != "" {
+			fmt.Fprintf(&b, ": %s", step.Error)
+		}
+		b.WriteString("\n")
+		transactions += step.Transactions
+		entities += step.Entities
+		relationships += step.Relationships
+		ended += step.Ended
+	}
+	fmt.Fprintf(&b, "%d loaded, %d skipped, %d failed, %d not run in %s: %d transactions, %d entities and %d relationships created, %d relationships ended\n",
+		r.Count(StepLoaded), r.Count(StepSkipped), r.Count(StepFailed), r.Count(StepNotRun), r.Duration.Round(time.Millisecond),
+		transactions, entities, relationships, ended)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,580 @@
+// Package memgraph holds a graph of entities and relationships in memory and serves it through the
+// Nexoan Update API (/entities) and Query API (/v1/entities). The loader's -plan mode applies
+// transactions to such a copy of the graph, and apitest puts it behind an HTTP server for tests.
+//
+// A client reaches the graph in-process, without a server, through the graph's transport:
+//
+//	graph := memgraph.New()
+//	client, err := api.NewClient(memgraph.UpdateURL, memgraph.QueryURL,
+//		api.WithHTTPClient(&http.Client{Transport: graph.Transport()}))
+package memgraph
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// Endpoints to pass to api.NewClient for a client that uses the graph's transport. The host is
+// never resolved.
+const (
+	UpdateURL = "http://memgraph/entities"
+	QueryURL  = "http://memgraph/v1/entities"
+)
+
+// Directions reported for relationships returned by the relations endpoint
+const (
+	DirectionOutgoing = "OUTGOING"
+	DirectionIncoming = "INCOMING"
+)
+
+// relationship is a stored edge from source to target
+type relationship struct {
+	ID        string
+	Name      string
+	Source    string
+	Target    string
+	StartTime string
+	EndTime   string
+}
+
+// Graph is an in-memory graph that serves the Nexoan Update and Query APIs as an http.Handler
+type Graph struct {
+	handler http.Handler
+
+	mu            sync.Mutex
+	entities      map[string]*models.Entity
+	order         []string
+	relationships []*relationship
+	relByID       map[string]*relationship
+}
+
+// New returns an empty graph
+func New() *Graph {
+	g := &Graph{}
+	g.reset()
+
+	mux := http.NewServeMux()
+
+	// Update API
+	mux.HandleFunc("POST /entities", g.handleCreate)
+	mux.HandleFunc("PUT /entities/{id}", g.handleUpdate)
+	mux.HandleFunc("DELETE /entities/{id}", g.handleDelete)
+
+	// Query API
+	mux.HandleFunc("GET /v1/entities/root", g.handleRoot)
+	mux.HandleFunc("POST /v1/entities/search", g.handleSearch)
+	mux.HandleFunc("POST /v1/entities/{id}/relations", g.handleRelations)
+	mux.HandleFunc("GET /v1/entities/{id}/metadata", g.handleMetadata)
+	mux.HandleFunc("GET /v1/entities/{id}/attributes/{name}", g.handleAttribute)
+
+	g.handler = mux
+	return g
+}
+
+// ServeHTTP answers a request of the Update or Query API from the graph
+func (g *Graph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.handler.ServeHTTP(w, r)
+}
+
+// Reset discards every entity and relationship in the graph
+func (g *Graph) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reset()
+}
+
+func (g *Graph) reset() {
+	g.entities = map[string]*models.Entity{}
+	g.order = nil
+	g.relationships = nil
+	g.relByID = map[string]*relationship{}
+}
+
+// EntityCount returns the number of entities in the graph
+func (g *Graph) EntityCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.entities)
+}
+
+// RelationshipCount returns the number of relationships in the graph
+func (g *Graph) RelationshipCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.relationships)
+}
+
+// entityID returns the unescaped entity ID from the request path.
+// The client escapes IDs with url.QueryEscape, so '+' has to be turned back into a space.
+func entityID(r *http.Request) string {
+	id := r.PathValue("id")
+	if unescaped, err := url.QueryUnescape(id); err == nil {
+		return unescaped
+	}
+	return id
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+func (g *Graph) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var entity models.Entity
+	if err := json.NewDecoder(r.Body).Decode(&entity); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid entity: %v", err)
+		return
+	}
+	if entity.ID == "" {
+		writeError(w, http.StatusBadRequest, "entity id is required")
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.entities[entity.ID]; exists {
+		writeError(w, http.StatusConflict, "entity %s already exists", entity.ID)
+		return
+	}
+
+	// A create that fails on a relationship leaves nothing behind
+	if err := g.checkRelationships(entity.ID, entity.Relationships); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	stored := entity
+	stored.Relationships = nil
+	g.entities[entity.ID] = &stored
+	g.order = append(g.order, entity.ID)
+
+	for _, entry := range entity.Relationships {
+		g.applyRelationship(entity.ID, entry)
+	}
+
+	writeJSON(w, http.StatusCreated, g.snapshot(entity.ID))
+}
+
+func (g *Graph) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := entityID(r)
+
+	var update models.Entity
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid entity: %v", err)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entity, exists := g.entities[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "entity %s not found", id)
+		return
+	}
+	if err := g.checkRelationships(id, update.Relationships); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	if name, ok := update.Name.Value.(string); ok && name != "" {
+		entity.Name = update.Name
+	}
+	if update.Terminated != "" {
+		entity.Terminated = update.Terminated
+	}
+	entity.Metadata = mergeMetadata(entity.Metadata, update.Metadata)
+	entity.Attributes = append(entity.Attributes, update.Attributes...)
+
+	for _, entry := range update.Relationships {
+		g.applyRelationship(id, entry)
+	}
+
+	writeJSON(w, http.StatusOK, g.snapshot(id))
+}
+
+// relationshipID returns the ID of the relationship entry writes
+func relationshipID(entry models.RelationshipEntry) string {
+	if entry.Value.ID != "" {
+		return entry.Value.ID
+	}
+	return entry.Key
+}
+
+// checkRelationships returns why applying entries to source would fail, before any of them is
+// applied, so that a request is applied in full or not at all. source itself counts as existing.
+// Callers must hold g.mu.
+func (g *Graph) checkRelationships(source string, entries []models.RelationshipEntry) error {
+	created := make(map[string]bool)
+	for _, entry := range entries {
+		relID := relationshipID(entry)
+		if relID == "" {
+			return fmt.Errorf("relationship id is required")
+		}
+
+		start, end := entry.Value.StartTime, entry.Value.EndTime
+		if existing, ok := g.relByID[relID]; ok {
+			if existing.Source != source {
+				return fmt.Errorf("relationship %s does not belong to entity %s", relID, source)
+			}
+			if start == "" {
+				start = existing.StartTime
+			}
+			if end == "" {
+				end = existing.EndTime
+			}
+		}
+		if end != "" && end < start {
+			return fmt.Errorf("relationship %s would end at %s, before it starts at %s", relID, end, start)
+		}
+		if _, ok := g.relByID[relID]; ok || created[relID] {
+			continue
+		}
+
+		related := entry.Value.RelatedEntityID
+		if related == "" {
+			return fmt.Errorf("relationship %s has no related entity", relID)
+		}
+		if _, ok := g.entities[related]; !ok && related != source {
+			return fmt.Errorf("related entity %s not found", related)
+		}
+		created[relID] = true
+	}
+	return nil
+}
+
+// applyRelationship adds a new relationship from source, or updates the start and end time of an
+// existing one when the relationship ID is already known. entry must have passed
+// checkRelationships. Callers must hold g.mu.
+func (g *Graph) applyRelationship(source string, entry models.RelationshipEntry) {
+	rel := entry.Value
+	relID := relationshipID(entry)
+
+	if existing, ok := g.relByID[relID]; ok {
+		if rel.StartTime != "" {
+			existing.StartTime = rel.StartTime
+		}
+		if rel.EndTime != "" {
+			existing.EndTime = rel.EndTime
+		}
+		return
+	}
+
+	created := &relationship{
+		ID:        relID,
+		Name:      rel.Name,
+		Source:    source,
+		Target:    rel.RelatedEntityID,
+		StartTime: rel.StartTime,
+		EndTime:   rel.EndTime,
+	}
+	g.relationships = append(g.relationships, created)
+	g.relByID[relID] = created
+}
+
+func mergeMetadata(current, update []models.MetadataEntry) []models.MetadataEntry {
+	for _, entry := range update {
+		replaced := false
+		for i := range current {
+			if current[i].Key == entry.Key {
+				current[i].Value = entry.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			current = append(current, entry)
+		}
+	}
+	return current
+}
+
+// snapshot returns a copy of the entity including its outgoing relationships. Callers must hold g.mu.
+func (g *Graph) snapshot(id string) models.Entity {
+	entity := *g.entities[id]
+	entity.Relationships = nil
+	for _, rel := range g.relationships {
+		if rel.Source != id {
+			continue
+		}
+		entity.Relationships = append(entity.Relationships, models.RelationshipEntry{
+			Key:   rel.ID,
+			Value: rel.view(rel.Target, DirectionOutgoing),
+		})
+	}
+	return entity
+}
+
+func (rel *relationship) view(relatedID, direction string) models.Relationship {
+	return models.Relationship{
+		RelatedEntityID: relatedID,
+		StartTime:       rel.StartTime,
+		EndTime:         rel.EndTime,
+		ID:              rel.ID,
+		Name:            rel.Name,
+		Direction:       direction,
+	}
+}
+
+func (g *Graph) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := entityID(r)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.entities[id]; !exists {
+		writeError(w, http.StatusNotFound, "entity %s not found", id)
+		return
+	}
+
+	delete(g.entities, id)
+	for i, existing := range g.order {
+		if existing == id {
+			g.order = append(g.order[:i], g.order[i+1:]...)
+			break
+		}
+	}
+
+	kept := g.relationships[:0]
+	for _, rel := range g.relationships {
+		if rel.Source == id || rel.Target == id {
+			delete(g.relByID, rel.ID)
+			continue
+		}
+		kept = append(kept, rel)
+	}
+	g.relationships = kept
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (g *Graph) handleRoot(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	hasIncoming := map[string]bool{}
+	for _, rel := range g.relationships {
+		hasIncoming[rel.Target] = true
+	}
+
+	ids := []string{}
+	for _, id := range g.order {
+		if hasIncoming[id] {
+			continue
+		}
+		if kind != "" && g.entities[id].Kind.Major != kind {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	writeJSON(w, http.StatusOK, models.RootEntitiesResponse{Body: ids})
+}
+
+// searchResult mirrors models.SearchResult on the wire, where the name is a JSON-encoded
+// protobuf StringValue whose value is hex encoded
+type searchResult struct {
+	ID         string      `json:"id"`
+	Kind       models.Kind `json:"kind"`
+	Name       string      `json:"name"`
+	Created    string      `json:"created"`
+	Terminated string      `json:"terminated,omitempty"`
+}
+
+func encodeName(name string) string {
+	wrapped, _ := json.Marshal(struct {
+		TypeURL string `json:"typeUrl"`
+		Value   string `json:"value"`
+	}{
+		TypeURL: "type.googleapis.com/google.protobuf.StringValue",
+		Value:   hex.EncodeToString([]byte(name)),
+	})
+	return string(wrapped)
+}
+
+func (g *Graph) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var criteria models.SearchCriteria
+	if err := json.NewDecoder(r.Body).Decode(&criteria); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid search criteria: %v", err)
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	results := []searchResult{}
+	for _, id := range g.order {
+		entity := g.entities[id]
+		name, _ := entity.Name.Value.(string)
+
+		if criteria.ID != "" && criteria.ID != id {
+			continue
+		}
+		if criteria.Kind != nil {
+			if criteria.Kind.Major != "" && criteria.Kind.Major != entity.Kind.Major {
+				continue
+			}
+			if criteria.Kind.Minor != "" && criteria.Kind.Minor != entity.Kind.Minor {
+				continue
+			}
+		}
+		if criteria.Name != "" && criteria.Name != name {
+			continue
+		}
+		if criteria.Created != "" && criteria.Created != entity.Created {
+			continue
+		}
+		if criteria.Terminated != "" && criteria.Terminated != entity.Terminated {
+			continue
+		}
+
+		results = append(results, searchResult{
+			ID:         id,
+			Kind:       entity.Kind,
+			Name:       encodeName(name),
+			Created:    entity.Created,
+			Terminated: entity.Terminated,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"body": results})
+}
+
+func (g *Graph) handleRelations(w http.ResponseWriter, r *http.Request) {
+	id := entityID(r)
+
+	var filter models.Relationship
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid relationship filter: %v", err)
+			return
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, exists := g.entities[id]; !exists {
+		writeError(w, http.StatusNotFound, "entity %s not found", id)
+		return
+	}
+
+	relations := []models.Relationship{}
+	for _, rel := range g.relationships {
+		var view models.Relationship
+		switch id {
+		case rel.Source:
+			view = rel.view(rel.Target, DirectionOutgoing)
+		case rel.Target:
+			view = rel.view(rel.Source, DirectionIncoming)
+		default:
+			continue
+		}
+		if matchesRelationship(view, filter) {
+			relations = append(relations, view)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, relations)
+}
+
+// matchesRelationship applies the non-empty fields of filter to rel
+func matchesRelationship(rel, filter models.Relationship) bool {
+	if filter.RelatedEntityID != "" && filter.RelatedEntityID != rel.RelatedEntityID {
+		return false
+	}
+	if filter.Name != "" && filter.Name != rel.Name {
+		return false
+	}
+	if filter.ID != "" && filter.ID != rel.ID {
+		return false
+	}
+	if filter.StartTime != "" && filter.StartTime != rel.StartTime {
+		return false
+	}
+	if filter.EndTime != "" && filter.EndTime != rel.EndTime {
+		return false
+	}
+	if filter.Direction != "" && !strings.EqualFold(filter.Direction, rel.Direction) {
+		return false
+	}
+	if filter.ActiveAt != "" {
+		// RFC3339 timestamps in UTC compare correctly as strings
+		if rel.StartTime > filter.ActiveAt {
+			return false
+		}
+		if rel.EndTime != "" && rel.EndTime <= filter.ActiveAt {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Graph) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	id := entityID(r)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entity, exists := g.entities[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "entity %s not found", id)
+		return
+	}
+
+	metadata := map[string]interface{}{}
+	for _, entry := range entity.Metadata {
+		metadata[entry.Key] = entry.Value
+	}
+
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+func (g *Graph) handleAttribute(w http.ResponseWriter, r *http.Request) {
+	id := entityID(r)
+	name := r.PathValue("name")
+	startTime := r.URL.Query().Get("startTime")
+	endTime := r.URL.Query().Get("endTime")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entity, exists := g.entities[id]
+	if !exists {
+		writeError(w, http.StatusNotFound, "entity %s not found", id)
+		return
+	}
+
+	values := []models.TimeBasedValue{}
+	for _, attribute := range entity.Attributes {
+		if attribute.Key != name {
+			continue
+		}
+		for _, value := range attribute.Value.Values {
+			if startTime != "" && value.EndTime != "" && value.EndTime <= startTime {
+				continue
+			}
+			if endTime != "" && value.StartTime > endTime {
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, values)
+}
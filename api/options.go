@@ -0,0 +1,123 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultTimeout is the per-request timeout used when no HTTP client or timeout is given
+const defaultTimeout = 30 * time.Second
+
+// Logger receives the progress and retry messages of the client. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Option configures a Client created by NewClient
+type Option func(*clientOptions)
+
+// clientOptions collects the options before NewClient builds the HTTP client from them
+type clientOptions struct {
+	httpClient *http.Client
+	timeout    *time.Duration
+	tlsConfig  *tls.Config
+	headers    http.Header
+	logger     Logger
+	handlers   *HandlerRegistry
+}
+
+// WithHTTPClient makes the client send requests through httpClient, for example to use a custom
+// proxy or transport. WithTimeout and WithTLSConfig still apply, to a copy of httpClient; WithTLSConfig
+// needs its transport to be nil or an *http.Transport.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request (default 30s)
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = &timeout
+	}
+}
+
+// WithTLSConfig sets the TLS configuration of the transport, for a custom CA or client certificates
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(o *clientOptions) {
+		o.tlsConfig = tlsConfig
+	}
+}
+
+// WithHeader adds a header to every request, for example User-Agent. It can be given several times.
+func WithHeader(key, value string) Option {
+	return func(o *clientOptions) {
+		o.headers.Add(key, value)
+	}
+}
+
+// WithBearerToken authenticates every request with an Authorization: Bearer header
+func WithBearerToken(token string) Option {
+	return func(o *clientOptions) {
+		o.headers.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithLogger sends the client's progress and retry messages to logger instead of standard output
+func WithLogger(logger Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithHandlers makes the client process transactions with the handlers of registry instead of
+// BuiltinHandlers, for example one holding custom transaction types
+func WithHandlers(registry *HandlerRegistry) Option {
+	return func(o *clientOptions) {
+		o.handlers = registry
+	}
+}
+
+// buildHTTPClient returns the HTTP client described by the options. A TLS configuration can only be
+// set on an *http.Transport, so it is an error to combine it with any other transport rather than
+// replace that transport.
+func (o *clientOptions) buildHTTPClient() (*http.Client, error) {
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		httpClient = &copied
+	}
+	if o.timeout != nil {
+		httpClient.Timeout = *o.timeout
+	}
+
+	if o.tlsConfig != nil {
+		var transport *http.Transport
+		switch current := httpClient.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = current.Clone()
+		default:
+			return nil, fmt.Errorf("cannot apply the TLS configuration to the %T transport of the HTTP client; set it on that transport instead", current)
+		}
+		transport.TLSClientConfig = o.tlsConfig
+		httpClient.Transport = transport
+	}
+
+	return httpClient, nil
+}
+
+// logf writes a progress message through the client's logger
+func (c *Client) logf(format string, args ...interface{}) {
+	c.logger.Printf(format, args...)
+}
+
+// defaultLogger writes plain lines to standard output, as the loader always has
+func defaultLogger() Logger {
+	return log.New(os.Stdout, "", 0)
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SequenceColumn is the optional column that orders the transactions of a date explicitly, ahead of
+// their transaction IDs. Rows without it, or with it empty, have sequence 0.
+const SequenceColumn = "sequence"
+
+// DependencyPolicy controls what loading a data directory does with ordering dependencies, see
+// FindOrderingDependencies
+type DependencyPolicy int
+
+const (
+	// DependencyWarn logs the dependencies and loads the transactions in their sorted order
+	DependencyWarn DependencyPolicy = iota
+	// DependencyReorder moves the transaction that adds an entity ahead of the first transaction that
+	// needs it, when both have the same date, and logs the dependencies it cannot resolve
+	DependencyReorder
+	// DependencyFail rejects the data directory before any transaction is applied
+	DependencyFail
+)
+
+// ParseDependencyPolicy parses the name of a dependency policy: warn, reorder or fail
+func ParseDependencyPolicy(name string) (DependencyPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "warn":
+		return DependencyWarn, nil
+	case "reorder":
+		return DependencyReorder, nil
+	case "fail":
+		return DependencyFail, nil
+	}
+	return DependencyWarn, fmt.Errorf("invalid dependency policy: %s (must be warn, reorder or fail)", name)
+}
+
+// SetDependencyPolicy sets what ProcessTransactions does when a transaction needs an entity that a
+// later transaction of the data directory adds
+func (c *Client) SetDependencyPolicy(policy DependencyPolicy) {
+	c.dependencyPolicy = policy
+}
+
+// DependencyPolicy returns the dependency policy of the client
+func (c *Client) DependencyPolicy() DependencyPolicy {
+	return c.dependencyPolicy
+}
+
+// TransactionID is a transaction_id split into its parts. 2403-38-2_tr_05 is gazette 2403-38, part 2,
+// sequence 5; 2156/15_tr_03 is gazette 2156-15, as / and - both separate the numbers of a gazette.
+// IDs without a sequence, like the document ID 2289-34, have sequence 0.
+type TransactionID struct {
+	Gazette  []int
+	Part     int
+	Sequence int
+	// Parsed is false for IDs that are not made of numbers, which sort after the others by their text
+	Parsed bool
+}
+
+// ParseTransactionID splits id into its gazette number, part and sequence
+func ParseTransactionID(id string) TransactionID {
+	prefix, sequence, hasSequence := strings.Cut(strings.ToLower(strings.TrimSpace(id)), "_tr_")
+	numbers := strings.FieldsFunc(prefix, func(c rune) bool {
+		return c == '-' || c == '/' || c == '_'
+	})
+	if len(numbers) == 0 {
+		return TransactionID{}
+	}
+
+	parsed := TransactionID{Parsed: true}
+	for i, number := range numbers {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return TransactionID{}
+		}
+		switch {
+		case i < 2:
+			parsed.Gazette = append(parsed.Gazette, n)
+		case i == 2:
+			parsed.Part = n
+		}
+	}
+	if hasSequence {
+		n, err := strconv.Atoi(strings.TrimSpace(sequence))
+		if err != nil {
+			return TransactionID{}
+		}
+		parsed.Sequence = n
+	}
+	return parsed
+}
+
+// Compare orders transaction IDs by gazette, part and sequence, with unparsed IDs last
+func (id TransactionID) Compare(other TransactionID) int {
+	if id.Parsed != other.Parsed {
+		if id.Parsed {
+			return -1
+		}
+		return 1
+	}
+	if order := compareGazettes(id.Gazette, other.Gazette); order != 0 {
+		return order
+	}
+	if id.Part != other.Part {
+		return id.Part - other.Part
+	}
+	return id.Sequence - other.Sequence
+}
+
+// orderingKey is what transactions are sorted by
+type orderingKey struct {
+	date     string
+	sequence int
+	id       TransactionID
+	rawID    string
+}
+
+// transactionOrderingKey returns the ordering key of transaction. The sequence column must hold a
+// whole number when it is set.
+func transactionOrderingKey(transaction map[string]interface{}) (orderingKey, error) {
+	rawID := strings.TrimSpace(stringField(transaction, "transaction_id"))
+	key := orderingKey{
+		date:  strings.TrimSpace(stringField(transaction, "date")),
+		id:    ParseTransactionID(rawID),
+		rawID: rawID,
+	}
+	if sequence := strings.TrimSpace(stringField(transaction, SequenceColumn)); sequence != "" {
+		n, err := strconv.Atoi(sequence)
+		if err != nil {
+			return key, transactionSource(transaction).locate(invalidField(SequenceColumn, "sequence must be a whole number", err))
+		}
+		key.sequence = n
+	}
+	return key, nil
+}
+
+// stringField returns the value of a string column of transaction, or "" if it has none
+func stringField(transaction map[string]interface{}, field string) string {
+	value, _ := transaction[field].(string)
+	return value
+}
+
+// less reports whether key sorts before other: by date, then the sequence column, then the
+// transaction ID
+func (key orderingKey) less(other orderingKey) bool {
+	if key.date != other.date {
+		return key.date < other.date
+	}
+	if key.sequence != other.sequence {
+		return key.sequence < other.sequence
+	}
+	if order := key.id.Compare(other.id); order != 0 {
+		return order < 0
+	}
+	if !key.id.Parsed {
+		return key.rawID < other.rawID
+	}
+	return false
+}
+
+// SortTransactions sorts transactions by date, then by the sequence column, then by transaction ID.
+// Transactions that tie keep their order, which is that of the files and rows they were read from.
+func SortTransactions(transactions []map[string]interface{}) error {
+	keys := make([]orderingKey, len(transactions))
+	for i, transaction := range transactions {
+		key, err := transactionOrderingKey(transaction)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+
+	indexes := make([]int, len(transactions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return keys[indexes[i]].less(keys[indexes[j]])
+	})
+
+	sorted := make([]map[string]interface{}, len(transactions))
+	for i, index := range indexes {
+		sorted[i] = transactions[index]
+	}
+	copy(transactions, sorted)
+	return nil
+}
+
+// OrderingDependency is a transaction that needs an entity which only a later transaction of the
+// same load adds, like a MOVE into a minister that an ADD further down creates
+type OrderingDependency struct {
+	// TransactionID names the transaction that comes too early, and Index is its position
+	TransactionID string
+	Index         int
+	// Name is the entity it needs
+	Name string
+	// AddedBy names the first transaction that adds the entity, and AddedAt is its position
+	AddedBy string
+	AddedAt int
+}
+
+func (d OrderingDependency) String() string {
+	return fmt.Sprintf("transaction %s needs '%s', which transaction %s only adds later", d.TransactionID, d.Name, d.AddedBy)
+}
+
+// orderingNames returns the name of the entity transaction attaches something to, which must exist
+// by then, and the names of the entities it adds. Only the parent of an ADD and the new parent of a
+// MOVE count: the entities a TERMINATE, RENAME or MOVE acts on were there before, and a later ADD of
+// the same name, as in a reshuffle, adds another entity.
+func orderingNames(transaction map[string]interface{}) (needs string, adds []string) {
+	field := func(name string) string {
+		return strings.TrimSpace(stringField(transaction, name))
+	}
+	switch stringField(transaction, "file_type") {
+	case "ADD":
+		return field("parent"), []string{field("child")}
+	case "MOVE":
+		return field("new_parent"), nil
+	case "RENAME", "MERGE":
+		return "", []string{field("new")}
+	case "SPLIT":
+		return "", splitMergedNames(field("new"))
+	}
+	return "", nil
+}
+
+// FindOrderingDependencies returns the transactions that need an entity no earlier transaction adds
+// but a later one does, in the order of the transactions. Names are matched exactly.
+func FindOrderingDependencies(transactions []map[string]interface{}) []OrderingDependency {
+	added := make(map[string]int)
+	for i, transaction := range transactions {
+		_, adds := orderingNames(transaction)
+		for _, name := range adds {
+			if _, seen := added[name]; name != "" && !seen {
+				added[name] = i
+			}
+		}
+	}
+
+	var dependencies []OrderingDependency
+	for i, transaction := range transactions {
+		name, _ := orderingNames(transaction)
+		if at, ok := added[name]; ok && at > i {
+			dependencies = append(dependencies, OrderingDependency{
+				TransactionID: stringField(transaction, "transaction_id"),
+				Index:         i,
+				Name:          name,
+				AddedBy:       stringField(transactions[at], "transaction_id"),
+				AddedAt:       at,
+			})
+		}
+	}
+	return dependencies
+}
+
+// resolveDependencies moves the transaction that adds an entity ahead of the first transaction that
+// needs it when both have the same date, and returns the dependencies it leaves. A transaction is
+// moved at most once, so dependencies that form a cycle are left as they are.
+func resolveDependencies(transactions []map[string]interface{}) []OrderingDependency {
+	// positions holds where each transaction was before any was moved
+	positions := make([]int, len(transactions))
+	for i := range positions {
+		positions[i] = i
+	}
+	moved := make(map[int]bool)
+
+	for {
+		var unresolved []OrderingDependency
+		changed := false
+		for _, dependency := range FindOrderingDependencies(transactions) {
+			original := positions[dependency.AddedAt]
+			if changed || moved[original] ||
+				stringField(transactions[dependency.AddedAt], "date") != stringField(transactions[dependency.Index], "date") {
+				unresolved = append(unresolved, dependency)
+				continue
+			}
+			moved[original] = true
+			moveBefore(transactions, dependency.AddedAt, dependency.Index)
+			moveBefore(positions, dependency.AddedAt, dependency.Index)
+			changed = true
+		}
+		if !changed {
+			return unresolved
+		}
+	}
+}
+
+// moveBefore moves the element at from to position to, which is before it, shifting the elements
+// in between along
+func moveBefore[T any](items []T, from, to int) {
+	item := items[from]
+	copy(items[to+1:from+1], items[to:from])
+	items[to] = item
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"orgchart_nexoan/models"
+)
+
+// PlannedEntity is an entity a load would create
+type PlannedEntity struct {
+	TransactionID string `json:"transactionId"`
+	ID            string `json:"id"`
+	Kind          string `json:"kind"`
+	Name          string `json:"name"`
+	Created       string `json:"created,omitempty"`
+}
+
+// PlannedRelationship is a relationship a load would create or end, from Parent to Child
+type PlannedRelationship struct {
+	TransactionID string `json:"transactionId"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ParentID      string `json:"parentId"`
+	Parent        string `json:"parent"`
+	ChildID       string `json:"childId"`
+	Child         string `json:"child"`
+	StartTime     string `json:"startTime,omitempty"`
+	EndTime       string `json:"endTime,omitempty"`
+}
+
+// PlanFailure is a transaction that would fail, for example because a minister it names is not active
+type PlanFailure struct {
+	TransactionID string `json:"transactionId"`
+	Error         string `json:"error"`
+	Err           error  `json:"-"`
+}
+
+// LoadPlan is what loading a data directory would do. Failures come first as they are what a
+// reviewer has to fix before the load.
+type LoadPlan struct {
+	DataDir     string `json:"dataDir"`
+	ProcessType string `json:"processType"`
+	// Transactions is the number of transactions in the directory
+	Transactions  int                   `json:"transactions"`
+	Failures      []PlanFailure         `json:"failures"`
+	Entities      []PlannedEntity       `json:"entities"`
+	Relationships []PlannedRelationship `json:"relationships"`
+	Ended         []PlannedRelationship `json:"ended"`
+}
+
+// planRecorder collects the writes of the transactions applied while planning. The writes of a
+// transaction are kept only once it succeeds.
+type planRecorder struct {
+	mu            sync.Mutex
+	transactionID string
+	entities      []PlannedEntity
+	relationships []PlannedRelationship
+	ended         []PlannedRelationship
+}
+
+// PlanTransactions applies the transactions of dataDir the way ProcessTransactions would, but
+// carries on past failures and returns what each transaction wrote. It writes to the graph c points at, so c must point at a copy of the
+// graph, such as a memgraph.Graph seeded with SeedFromClient or SeedFromOrgChart.
+func (c *Client) PlanTransactions(dataDir string, processType string) (*LoadPlan, error) {
+	return c.PlanTransactionsContext(context.Background(), dataDir, processType)
+}
+
+// PlanTransactionsContext is like PlanTransactions but stops before the next transaction once ctx is done
+func (c *Client) PlanTransactionsContext(ctx context.Context, dataDir string, processType string) (*LoadPlan, error) {
+	if len(c.handlers.Kinds(processType)) == 0 {
+		return nil, fmt.Errorf("invalid process type: %s", processType)
+	}
+	transactions, err := c.loadDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	entityCounters, err := c.entityCounters(processType)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &planRecorder{}
+	c.plan = recorder
+	defer func() { c.plan = nil }()
+
+	plan := &LoadPlan{
+		DataDir:       dataDir,
+		ProcessType:   processType,
+		Transactions:  len(transactions),
+		Failures:      []PlanFailure{},
+		Entities:      []PlannedEntity{},
+		Relationships: []PlannedRelationship{},
+		Ended:         []PlannedRelationship{},
+	}
+	for _, transaction := range transactions {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("stopped before transaction %s: %w", transaction["transaction_id"], err)
+		}
+		transactionID := fmt.Sprint(transaction["transaction_id"])
+		checkpoint := recorder.begin(transactionID)
+		_, err := c.applyTransaction(ctx, processType, transaction, entityCounters)
+		if err == nil {
+			err = c.FlushRelationshipsContext(ctx)
+		}
+		if err != nil {
+			recorder.rollback(checkpoint)
+			plan.Failures = append(plan.Failures, PlanFailure{TransactionID: transactionID, Error: err.Error(), Err: err})
+		}
+	}
+
+	plan.Entities = append(plan.Entities, recorder.entities...)
+	plan.Relationships = append(plan.Relationships, recorder.relationships...)
+	plan.Ended = append(plan.Ended, recorder.ended...)
+	if err := c.describePlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// describePlan fills in the names of the entities the planned relationships join, and the related
+// entity and type of the relationships that would end
+func (c *Client) describePlan(ctx context.Context, plan *LoadPlan) error {
+	names := make(map[string]string, len(plan.Entities))
+	for _, entity := range plan.Entities {
+		names[entity.ID] = entity.Name
+	}
+	name := func(id string) (string, error) {
+		if known, ok := names[id]; ok {
+			return known, nil
+		}
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: id})
+		if err != nil {
+			return "", fmt.Errorf("failed to search for entity %s: %w", id, err)
+		}
+		if len(results) > 0 {
+			names[id] = results[0].Name
+		}
+		return names[id], nil
+	}
+
+	for i := range plan.Ended {
+		ended := &plan.Ended[i]
+		relations, err := c.GetRelatedEntitiesContext(ctx, ended.ParentID, &models.Relationship{ID: ended.ID, Direction: DirectionOutgoing})
+		if err != nil {
+			return fmt.Errorf("failed to get relationship %s: %w", ended.ID, err)
+		}
+		if len(relations) > 0 {
+			ended.Name = relations[0].Name
+			ended.ChildID = relations[0].RelatedEntityID
+			ended.StartTime = relations[0].StartTime
+		}
+	}
+
+	for _, relationships := range [][]PlannedRelationship{plan.Relationships, plan.Ended} {
+		for i := range relationships {
+			var err error
+			if relationships[i].Parent, err = name(relationships[i].ParentID); err != nil {
+				return err
+			}
+			if relationships[i].ChildID != "" {
+				if relationships[i].Child, err = name(relationships[i].ChildID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// begin starts recording the writes of a transaction and returns the point to roll back to if it fails
+func (r *planRecorder) begin(transactionID string) [3]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transactionID = transactionID
+	return [3]int{len(r.entities), len(r.relationships), len(r.ended)}
+}
+
+// rollback drops the writes recorded since checkpoint
+func (r *planRecorder) rollback(checkpoint [3]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entities = r.entities[:checkpoint[0]]
+	r.relationships = r.relationships[:checkpoint[1]]
+	r.ended = r.ended[:checkpoint[2]]
+}
+
+// recordEntity records an entity the transaction being planned creates
+func (r *planRecorder) recordEntity(entity *models.Entity) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	name, _ := entity.Name.Value.(string)
+	r.entities = append(r.entities, PlannedEntity{
+		TransactionID: r.transactionID,
+		ID:            entity.ID,
+		Kind:          entity.Kind.Minor,
+		Name:          name,
+		Created:       entity.Created,
+	})
+	r.mu.Unlock()
+	r.recordRelationships(entity.ID, entity.Relationships)
+}
+
+// recordRelationships records the relationships of entityID that an update creates or ends
+func (r *planRecorder) recordRelationships(entityID string, entries []models.RelationshipEntry) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, entry := range entries {
+		planned := PlannedRelationship{
+			TransactionID: r.transactionID,
+			ID:            relationshipEntryID(entry),
+			Name:          entry.Value.Name,
+			ParentID:      entityID,
+			ChildID:       entry.Value.RelatedEntityID,
+			StartTime:     entry.Value.StartTime,
+			EndTime:       entry.Value.EndTime,
+		}
+		switch {
+		case entry.Value.RelatedEntityID != "":
+			r.relationships = append(r.relationships, planned)
+		case entry.Value.EndTime != "":
+			r.ended = append(r.ended, planned)
+		}
+	}
+}
+
+// WriteText writes the plan as an indented list: failures, then entities and relationships
+// created, then relationships ended
+func (p *LoadPlan) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan for %s (%s, %d transactions):\n", p.DataDir, p.ProcessType, p.Transactions)
+
+	fmt.Fprintf(&b, "Failures (%d):\n", len(p.Failures))
+	for _, failure := range p.Failures {
+		fmt.Fprintf(&b, "  %s: %s\n", failure.TransactionID, failure.Error)
+	}
+	fmt.Fprintf(&b, "Entities to create (%d):\n", len(p.Entities))
+	for _, entity := range p.Entities {
+		fmt.Fprintf(&b, "  %s %s '%s' [%s] from %s\n", entity.TransactionID, entity.Kind, entity.Name, entity.ID, shortDate(entity.Created))
+	}
+	fmt.Fprintf(&b, "Relationships to create (%d):\n", len(p.Relationships))
+	for _, rel := range p.Relationships {
+		fmt.Fprintf(&b, "  %s %s '%s' -> '%s' [%s] from %s\n", rel.TransactionID, rel.Name, rel.Parent, rel.Child, rel.ID, shortDate(rel.StartTime))
+	}
+	fmt.Fprintf(&b, "Relationships to end (%d):\n", len(p.Ended))
+	for _, rel := range p.Ended {
+		fmt.Fprintf(&b, "  %s %s '%s' -> '%s' [%s] on %s\n", rel.TransactionID, rel.Name, rel.Parent, rel.Child, rel.ID, shortDate(rel.EndTime))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SeedFromClient copies the government node and every entity and relationship reachable from it,
+// ended relationships included, from the graph source reads into the graph c writes to. It is meant
+// for filling a copy of the graph, such as an apitest.Server, to plan a load against.
+func (c *Client) SeedFromClient(source *Client) error {
+	return c.SeedFromClientContext(context.Background(), source)
+}
+
+// SeedFromClientContext is like SeedFromClient but stops issuing API calls once ctx is done
+func (c *Client) SeedFromClientContext(ctx context.Context, source *Client) error {
+	governmentID, err := source.getGovernmentIDContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get government node: %w", err)
+	}
+
+	graph := newGraphCopy()
+	queue := []string{governmentID}
+	seen := map[string]bool{governmentID: true}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+
+		results, err := source.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: id})
+		if err != nil {
+			return fmt.Errorf("failed to search for entity %s: %w", id, err)
+		}
+		if len(results) == 0 {
+			return notFoundf("failed to find entity with ID: %s", id)
+		}
+		result := results[0]
+		graph.addEntity(&models.Entity{
+			ID:         result.ID,
+			Kind:       result.Kind,
+			Created:    result.Created,
+			Terminated: result.Terminated,
+			Name:       models.TimeBasedValue{StartTime: result.Created, Value: result.Name},
+		})
+
+		relations, err := source.GetRelatedEntitiesContext(ctx, id, &models.Relationship{Direction: DirectionOutgoing})
+		if err != nil {
+			return fmt.Errorf("failed to get relationships of entity %s: %w", id, err)
+		}
+		for _, rel := range relations {
+			graph.addRelationship(id, rel)
+			if !seen[rel.RelatedEntityID] {
+				seen[rel.RelatedEntityID] = true
+				queue = append(queue, rel.RelatedEntityID)
+			}
+		}
+	}
+	return graph.write(ctx, c)
+}
+
+// SeedFromOrgChart writes the entities and relationships of a saved org chart, as read by
+// ReadOrgChart, into the graph c writes to. Only what was active on the chart's date is known, so
+// plans against it cannot see earlier relationships.
+func (c *Client) SeedFromOrgChart(chart *OrgChart) error {
+	return c.SeedFromOrgChartContext(context.Background(), chart)
+}
+
+// SeedFromOrgChartContext is like SeedFromOrgChart but stops issuing API calls once ctx is done
+func (c *Client) SeedFromOrgChartContext(ctx context.Context, chart *OrgChart) error {
+	if chart == nil || chart.Government.ID == "" {
+		return fmt.Errorf("failed to seed from org chart: the chart has no government")
+	}
+
+	graph := newGraphCopy()
+	graph.addChartEntity("", "", chart.Government)
+	for _, president := range chart.Presidents {
+		graph.addChartEntity(chart.Government.ID, "AS_PRESIDENT", president.ChartEntity)
+		for _, minister := range president.Ministers {
+			graph.addChartEntity(president.ID, "AS_MINISTER", minister.ChartEntity)
+			for _, department := range minister.Departments {
+				graph.addChartEntity(minister.ID, "AS_DEPARTMENT", department.ChartEntity)
+				for _, person := range department.People {
+					graph.addChartEntity(department.ID, "AS_APPOINTED", person)
+				}
+			}
+			for _, person := range minister.People {
+				graph.addChartEntity(minister.ID, "AS_APPOINTED", person)
+			}
+		}
+	}
+	return graph.write(ctx, c)
+}
+
+// graphCopy collects entities and their outgoing relationships before they are written, so that
+// every entity exists before relationships point at it
+type graphCopy struct {
+	order     []string
+	entities  map[string]*models.Entity
+	relations map[string][]models.RelationshipEntry
+}
+
+func newGraphCopy() *graphCopy {
+	return &graphCopy{
+		entities:  make(map[string]*models.Entity),
+		relations: make(map[string][]models.RelationshipEntry),
+	}
+}
+
+// addEntity adds an entity unless one with the same ID was added before
+func (g *graphCopy) addEntity(entity *models.Entity) {
+	if _, ok := g.entities[entity.ID]; ok {
+		return
+	}
+	g.order = append(g.order, entity.ID)
+	g.entities[entity.ID] = entity
+}
+
+// addRelationship adds an outgoing relationship of parentID
+func (g *graphCopy) addRelationship(parentID string, rel models.Relationship) {
+	g.relations[parentID] = append(g.relations[parentID], models.RelationshipEntry{
+		Key: rel.ID,
+		Value: models.Relationship{
+			RelatedEntityID: rel.RelatedEntityID,
+			StartTime:       rel.StartTime,
+			EndTime:         rel.EndTime,
+			ID:              rel.ID,
+			Name:            rel.Name,
+		},
+	})
+}
+
+// addChartEntity adds an entity of an org chart and the relationship placing it under parentID.
+// Appointed people can appear more than once; each appointment is a relationship of its own.
+func (g *graphCopy) addChartEntity(parentID, relName string, entity ChartEntity) {
+	major := "Organisation"
+	if entity.Kind == "citizen" {
+		major = "Person"
+	}
+	g.addEntity(&models.Entity{
+		ID:      entity.ID,
+		Kind:    models.Kind{Major: major, Minor: entity.Kind},
+		Created: entity.Since,
+		Name:    models.TimeBasedValue{StartTime: entity.Since, Value: entity.Name},
+	})
+	if parentID == "" {
+		return
+	}
+	relationshipID := entity.RelationshipID
+	if relationshipID == "" {
+		relationshipID = fmt.Sprintf("%s_%s", parentID, entity.ID)
+	}
+	g.addRelationship(parentID, models.Relationship{
+		ID:              relationshipID,
+		Name:            relName,
+		RelatedEntityID: entity.ID,
+		StartTime:       entity.Since,
+	})
+}
+
+// write creates the entities and then their relationships through c
+func (g *graphCopy) write(ctx context.Context, c *Client) error {
+	for _, id := range g.order {
+		entity := *g.entities[id]
+		entity.Metadata = []models.MetadataEntry{}
+		entity.Attributes = []models.AttributeEntry{}
+		entity.Relationships = []models.RelationshipEntry{}
+		if _, err := c.CreateEntityContext(ctx, &entity); err != nil {
+			return fmt.Errorf("failed to copy entity %s: %w", id, err)
+		}
+	}
+	for _, id := range g.order {
+		if len(g.relations[id]) == 0 {
+			continue
+		}
+		if _, err := c.UpdateEntityContext(ctx, id, &models.Entity{ID: id, Relationships: g.relations[id]}); err != nil {
+			return fmt.Errorf("failed to copy relationships of entity %s: %w", id, err)
+		}
+	}
+	return c.FlushRelationshipsContext(ctx)
+}
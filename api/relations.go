@@ -0,0 +1,290 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// Relationship directions, as seen from the entity whose relations are queried
+const (
+	DirectionOutgoing = "OUTGOING"
+	DirectionIncoming = "INCOMING"
+	DirectionBoth     = "BOTH"
+)
+
+// RelationFilter selects relations for GetAllRelatedEntities. The zero value selects every relation.
+type RelationFilter struct {
+	// Direction is DirectionOutgoing, DirectionIncoming or DirectionBoth; empty means both
+	Direction string
+	// Names keeps the relations with one of these names, for example AS_MINISTER and AS_DEPARTMENT; empty means any name
+	Names []string
+	// ActiveAt (RFC3339) keeps the relations that had started and not yet ended at that time
+	ActiveAt string
+}
+
+// GetAllRelatedEntities gets the relations of an entity in either direction without knowing their names.
+// filter may be nil.
+func (c *Client) GetAllRelatedEntities(entityID string, filter *RelationFilter) ([]models.Relationship, error) {
+	return c.GetAllRelatedEntitiesContext(context.Background(), entityID, filter)
+}
+
+// GetAllRelatedEntitiesContext is like GetAllRelatedEntities but aborts the request when ctx is done
+func (c *Client) GetAllRelatedEntitiesContext(ctx context.Context, entityID string, filter *RelationFilter) ([]models.Relationship, error) {
+	if filter == nil {
+		filter = &RelationFilter{}
+	}
+
+	query := &models.Relationship{
+		Direction: filter.Direction,
+		ActiveAt:  filter.ActiveAt,
+	}
+	// The Query API filters on a single name, so larger name sets are applied here
+	if len(filter.Names) == 1 {
+		query.Name = filter.Names[0]
+	}
+
+	relations, err := c.GetRelatedEntitiesContext(ctx, entityID, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all related entities of %s: %w", entityID, err)
+	}
+	if len(filter.Names) <= 1 {
+		return relations, nil
+	}
+
+	names := make(map[string]bool, len(filter.Names))
+	for _, name := range filter.Names {
+		names[name] = true
+	}
+	selected := make([]models.Relationship, 0, len(relations))
+	for _, rel := range relations {
+		if names[rel.Name] {
+			selected = append(selected, rel)
+		}
+	}
+	return selected, nil
+}
+
+// normalizeRelationQuery returns query with its direction in the form the Query API expects.
+// DirectionBoth becomes empty, which the Query API reads as both directions.
+func normalizeRelationQuery(query *models.Relationship) (*models.Relationship, error) {
+	if query == nil || query.Direction == "" {
+		return query, nil
+	}
+
+	normalized := *query
+	switch direction := strings.ToUpper(strings.TrimSpace(query.Direction)); direction {
+	case DirectionOutgoing, DirectionIncoming:
+		normalized.Direction = direction
+	case DirectionBoth:
+		normalized.Direction = ""
+	default:
+		return nil, fmt.Errorf("invalid relationship direction '%s': must be %s, %s or %s",
+			query.Direction, DirectionOutgoing, DirectionIncoming, DirectionBoth)
+	}
+	return &normalized, nil
+}
+
+// restrictRelations applies the direction and ActiveAt filters of query to relations. Deployments of the
+// Query API that do not support these filters return every relation, so they are enforced here as well.
+func restrictRelations(relations []models.Relationship, query *models.Relationship) []models.Relationship {
+	if query == nil || (query.Direction == "" && query.ActiveAt == "") {
+		return relations
+	}
+
+	filter := models.Relationship{Direction: query.Direction, ActiveAt: query.ActiveAt}
+	restricted := make([]models.Relationship, 0, len(relations))
+	for _, rel := range relations {
+		if matchesRelationship(rel, filter) {
+			restricted = append(restricted, rel)
+		}
+	}
+	return restricted
+}
+
+// activeAt reports whether rel had started and not yet ended at dateISO (RFC3339). An empty dateISO
+// means now, that is, rel has not ended.
+func activeAt(rel models.Relationship, dateISO string) bool {
+	if dateISO == "" {
+		return rel.EndTime == ""
+	}
+	return rel.StartTime <= dateISO && (rel.EndTime == "" || rel.EndTime > dateISO)
+}
+
+// nextStartAfter returns the earliest start time after dateISO among relations, or "" if there is none.
+// A relationship written for a back-filled date ends there, where the already loaded history continues.
+func nextStartAfter(relations []models.Relationship, dateISO string) string {
+	next := ""
+	for _, rel := range relations {
+		if rel.StartTime > dateISO && (next == "" || rel.StartTime < next) {
+			next = rel.StartTime
+		}
+	}
+	return next
+}
+
+// terminationTarget returns the relationship among relations that a termination on dateISO ends: the
+// one active on dateISO that started before it, or else one that starts on it. applied is set instead
+// when none that started before dateISO is active and one ended on it, as the termination leaves it
+// when it was applied before.
+func terminationTarget(relations []models.Relationship, dateISO string) (target *models.Relationship, applied bool) {
+	for i := range relations {
+		rel := relations[i]
+		switch {
+		case activeAt(rel, dateISO) && rel.StartTime < dateISO:
+			return &relations[i], false
+		case activeAt(rel, dateISO):
+			if target == nil {
+				target = &relations[i]
+			}
+		case rel.EndTime == dateISO:
+			applied = true
+		}
+	}
+	if applied {
+		return nil, true
+	}
+	return target, false
+}
+
+// ministerEnd returns when the minister's term that includes dateISO ends, following it across the
+// presidents it was moved between, or "" if it has not ended
+func (c *Client) ministerEnd(ctx context.Context, ministerID, dateISO string) (string, error) {
+	terms, err := c.GetRelatedEntitiesContext(ctx, ministerID, &models.Relationship{
+		Name:      "AS_MINISTER",
+		Direction: DirectionIncoming,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get AS_MINISTER relationships of %s: %w", ministerID, err)
+	}
+
+	end := ""
+	for _, term := range terms {
+		if activeAt(term, dateISO) {
+			end = term.EndTime
+			break
+		}
+	}
+	// A minister moved to another president continues under the new one
+	for end != "" {
+		next, moved := "", false
+		for _, term := range terms {
+			if term.StartTime == end && term.EndTime != end {
+				next, moved = term.EndTime, true
+				break
+			}
+		}
+		if !moved {
+			break
+		}
+		end = next
+	}
+	return end, nil
+}
+
+// startMinisterRelationship starts the relationship with the given ID named name from the minister
+// ministerID to childID on startISO, lasting until endISO if that is set. A relationship back-filled
+// to a minister that a gazette loaded before has already renamed ends with the rename and continues
+// on the renamed minister, as the rename would have carried it over. An appointment also ends when
+// the minister was terminated.
+func (c *Client) startMinisterRelationship(ctx context.Context, ministerID, relationshipID, name, childID, startISO, endISO string) error {
+	ministerEnd, err := c.ministerEnd(ctx, ministerID, startISO)
+	if err != nil {
+		return err
+	}
+
+	until := endISO
+	var rename *models.Relationship
+	if ministerEnd != "" && (endISO == "" || ministerEnd < endISO) {
+		renames, err := c.GetRelatedEntitiesContext(ctx, ministerID, &models.Relationship{
+			Name:      "RENAMED_TO",
+			Direction: DirectionOutgoing,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get renames of minister %s: %w", ministerID, err)
+		}
+		for i := range renames {
+			if renames[i].StartTime == ministerEnd {
+				rename = &renames[i]
+				break
+			}
+		}
+		if rename != nil || name == "AS_APPOINTED" {
+			until = ministerEnd
+		}
+	}
+
+	_, err = c.UpdateEntityContext(ctx, ministerID, &models.Entity{
+		ID: ministerID,
+		Relationships: []models.RelationshipEntry{
+			{
+				Key: relationshipID,
+				Value: models.Relationship{
+					RelatedEntityID: childID,
+					StartTime:       startISO,
+					EndTime:         until,
+					ID:              relationshipID,
+					Name:            name,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create %s relationship: %w", name, err)
+	}
+	if rename == nil {
+		return nil
+	}
+
+	renamedID := rename.RelatedEntityID
+	carriedID := RelationshipID(relationshipKey(*rename, ministerID), name, renamedID, childID)
+	return c.startMinisterRelationship(ctx, renamedID, carriedID, name, childID, ministerEnd, endISO)
+}
+
+// terminateRelationship ends rel, a relationship of parentID, at endISO, which must not be before rel
+// starts. When rel had already ended later, because a rename loaded before carried it over to the
+// renamed minister, the relationships it was carried over to end where they start: they never began.
+func (c *Client) terminateRelationship(ctx context.Context, parentID string, rel models.Relationship, endISO string) error {
+	if endISO < rel.StartTime {
+		return fmt.Errorf("relationship %s cannot end at %s, before it starts at %s", rel.ID, endISO, rel.StartTime)
+	}
+	if err := c.endRelationship(ctx, parentID, rel.ID, endISO); err != nil {
+		return fmt.Errorf("failed to terminate relationship: %w", err)
+	}
+	if rel.EndTime == "" || rel.EndTime <= endISO {
+		return nil
+	}
+
+	renames, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
+		Name:      "RENAMED_TO",
+		Direction: DirectionOutgoing,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get renames of %s: %w", parentID, err)
+	}
+	for _, rename := range renames {
+		if rename.StartTime != rel.EndTime {
+			continue
+		}
+		renamedID := rename.RelatedEntityID
+		carriedID := RelationshipID(relationshipKey(rename, parentID), rel.Name, renamedID, rel.RelatedEntityID)
+		carried, err := c.GetRelatedEntitiesContext(ctx, renamedID, &models.Relationship{
+			ID:        carriedID,
+			Direction: DirectionOutgoing,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get relationship %s of %s: %w", carriedID, renamedID, err)
+		}
+		for _, next := range carried {
+			if next.ID != carriedID {
+				continue
+			}
+			if err := c.terminateRelationship(ctx, renamedID, next, next.StartTime); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
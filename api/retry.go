@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// RetryPolicy controls how the client retries transient failures of Update and Query API calls
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per call, including the first. Values below 1 mean 1.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after every attempt
+	Multiplier float64
+	// Jitter is the fraction (0 to 1) of each backoff that is randomised
+	Jitter float64
+	// RetryableStatusCodes lists the HTTP status codes that are worth retrying.
+	// Network errors are always retried.
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.5,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// NoRetryPolicy returns a policy that makes every call exactly once
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// SetRetryPolicy replaces the client's retry policy
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.retryPolicy = policy
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryableStatus(statusCode int) bool {
+	for _, code := range p.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait after the given (1-based) failed attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	jitter := math.Min(math.Max(p.Jitter, 0), 1)
+	wait = wait*(1-jitter) + wait*jitter*rand.Float64()
+	return time.Duration(wait)
+}
+
+// landedFunc reports whether an earlier attempt of a write already took effect on the server
+type landedFunc func(ctx context.Context) (bool, error)
+
+// newRequest builds a request with a JSON body, or no body when body is nil
+func newRequest(ctx context.Context, method, url string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends a request, retrying network errors and retryable status codes according to the client's
+// retry policy. The last response is returned whatever its status, so callers check the status as before.
+//
+// For writes, landed is consulted before every retry. If it reports that the earlier attempt already
+// took effect, do returns a nil response and a nil error and the caller must treat the write as done.
+func (c *Client) do(ctx context.Context, method, url string, body []byte, landed landedFunc) (*http.Response, error) {
+	policy := c.retryPolicy
+	maxAttempts := policy.attempts()
+
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest(ctx, method, url, body)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range c.headers {
+			req.Header[key] = values
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && !policy.retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= maxAttempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		// Discard the failed response before trying again
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = fmt.Sprintf("status code %d", resp.StatusCode)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.logf("Retrying %s %s (attempt %d/%d) after %s\n", method, url, attempt+1, maxAttempts, reason)
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		if landed != nil {
+			if done, err := landed(ctx); err == nil && done {
+				return nil, nil
+			}
+		}
+	}
+}
+
+// relationshipsLanded returns a landedFunc for an UpdateEntity call that adds or terminates the given
+// relationships of entityID. The write counts as done when every added relationship exists and every
+// terminated relationship carries the requested end time. Updates without relationships are simply retried.
+func (c *Client) relationshipsLanded(entityID string, relationships []models.RelationshipEntry) landedFunc {
+	if len(relationships) == 0 {
+		return nil
+	}
+
+	return func(ctx context.Context) (bool, error) {
+		for _, entry := range relationships {
+			want := entry.Value
+			relationshipID := want.ID
+			if relationshipID == "" {
+				relationshipID = entry.Key
+			}
+
+			existing, err := c.getRelatedEntities(ctx, entityID, &models.Relationship{ID: relationshipID})
+			if err != nil {
+				return false, err
+			}
+
+			found := false
+			for _, rel := range existing {
+				if rel.ID != relationshipID {
+					continue
+				}
+				if want.EndTime != "" && rel.EndTime != want.EndTime {
+					continue
+				}
+				found = true
+				break
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
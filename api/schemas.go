@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ActionColumn is the optional column that names the transaction type of each row, so that one CSV
+// file can hold transactions of several types. Files with it are not matched against the schemas.
+const ActionColumn = "action"
+
+// CSVSchema describes the header of the CSV files holding transactions of one type. A header fits
+// the schema when it has all of Columns and none of Without; other columns are allowed.
+type CSVSchema struct {
+	TransactionType string
+	Columns         []string
+	// Without lists columns that tell the files of another schema apart, such as rel_type, which
+	// ADD files of documents do not have
+	Without []string
+}
+
+// fits reports whether header fits the schema
+func (s CSVSchema) fits(header []string) bool {
+	for _, column := range s.Columns {
+		if !containsString(header, column) {
+			return false
+		}
+	}
+	for _, column := range s.Without {
+		if containsString(header, column) {
+			return false
+		}
+	}
+	return true
+}
+
+// RegisterSchema adds a header the files of schema.TransactionType can have. A type can have
+// several schemas; types without any are recognised by their file name alone.
+func (r *HandlerRegistry) RegisterSchema(schema CSVSchema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema.TransactionType = strings.ToUpper(schema.TransactionType)
+	r.schemas = append(r.schemas, schema)
+}
+
+// Schemas returns the registered schemas in the order they were registered
+func (r *HandlerRegistry) Schemas() []CSVSchema {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]CSVSchema(nil), r.schemas...)
+}
+
+// fittingTypes returns the transaction types with a schema header fits, sorted
+func (r *HandlerRegistry) fittingTypes(header []string) []string {
+	var transactionTypes []string
+	for _, schema := range r.Schemas() {
+		if schema.fits(header) && !containsString(transactionTypes, schema.TransactionType) {
+			transactionTypes = append(transactionTypes, schema.TransactionType)
+		}
+	}
+	sort.Strings(transactionTypes)
+	return transactionTypes
+}
+
+// hasSchema reports whether a schema is registered for transactionType
+func (r *HandlerRegistry) hasSchema(transactionType string) bool {
+	for _, schema := range r.Schemas() {
+		if schema.TransactionType == transactionType {
+			return true
+		}
+	}
+	return false
+}
+
+// namedTypes returns the registered transaction types that a file name names as one of its words,
+// like TERMINATE in 2289-34-2-TERMINATE.csv or 2412_08_terminate.csv
+func (r *HandlerRegistry) namedTypes(fileName string) []string {
+	transactionTypes := r.TransactionTypes()
+	var named []string
+	words := strings.FieldsFunc(strings.TrimSuffix(fileName, ".csv"), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	for _, word := range words {
+		word = strings.ToUpper(word)
+		if containsString(transactionTypes, word) && !containsString(named, word) {
+			named = append(named, word)
+		}
+	}
+	return named
+}
+
+// DetectTransactionType returns the transaction type of the rows of the CSV file with the given name
+// and header. The header decides: it must fit the schema of a registered type. When it fits several,
+// as ADD and TERMINATE files share their columns, the type named in the file name picks one; a name
+// that contradicts the header is rejected. Files with an ActionColumn return "", as each row names
+// its own type. The errors match ErrUnrecognisedFile.
+func (r *HandlerRegistry) DetectTransactionType(fileName string, header []string) (string, error) {
+	if containsString(header, ActionColumn) {
+		return "", nil
+	}
+
+	fitting := r.fittingTypes(header)
+	named := r.namedTypes(fileName)
+	var candidates []string
+	for _, transactionType := range named {
+		if containsString(fitting, transactionType) || !r.hasSchema(transactionType) {
+			candidates = append(candidates, transactionType)
+		}
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) > 1:
+		return "", unrecognisedf("%s names several transaction types: %s", fileName, strings.Join(candidates, ", "))
+	case len(fitting) == 0:
+		return "", unrecognisedf("the header of %s matches no transaction schema: %s", fileName, strings.Join(header, ","))
+	case len(named) > 0:
+		return "", unrecognisedf("%s is named as a %s file but its header is that of %s", fileName, strings.Join(named, " or "), strings.Join(fitting, " or "))
+	case len(fitting) == 1:
+		return fitting[0], nil
+	}
+	return "", unrecognisedf("the header of %s fits %s; name the type in the file name, as in 2403-38_%s.csv, or add an %s column",
+		fileName, strings.Join(fitting, " and "), fitting[0], ActionColumn)
+}
+
+// actionType returns the transaction type the action column of a row names, checking that the
+// header of its file fits that type
+func (r *HandlerRegistry) actionType(action string, header []string) (string, error) {
+	transactionType := strings.ToUpper(strings.TrimSpace(action))
+	if transactionType == "" {
+		return "", invalidField(ActionColumn, "action is required and must name a transaction type", nil)
+	}
+	if !containsString(r.TransactionTypes(), transactionType) {
+		return "", invalidField(ActionColumn, fmt.Sprintf("unknown transaction type '%s': must be one of %s",
+			action, strings.Join(r.TransactionTypes(), ", ")), nil)
+	}
+	if r.hasSchema(transactionType) && !containsString(r.fittingTypes(header), transactionType) {
+		return "", invalidField(ActionColumn, fmt.Sprintf("the columns of the file do not fit %s transactions", transactionType), nil)
+	}
+	return transactionType, nil
+}
+
+// builtinSchemas are the headers of the files of the builtin transaction types. The kind of
+// entity comes from either child_type or type, so neither is required.
+var builtinSchemas = []CSVSchema{
+	{TransactionType: "ADD", Columns: []string{"transaction_id", "parent", "parent_type", "child", "rel_type", "date"}},
+	{TransactionType: "ADD", Columns: []string{"transaction_id", "parent", "parent_type", "child", "child_type", "date"}, Without: []string{"rel_type"}},
+	{TransactionType: "TERMINATE", Columns: []string{"parent", "parent_type", "child", "rel_type", "date"}},
+	{TransactionType: "MOVE", Columns: []string{"new_parent", "child", "date"}},
+	{TransactionType: "MERGE", Columns: []string{"transaction_id", "old", "new", "date"}},
+	{TransactionType: "SPLIT", Columns: []string{"transaction_id", "old", "new", "date"}},
+	{TransactionType: "RENAME", Columns: []string{"transaction_id", "old", "new", "date"}},
+}
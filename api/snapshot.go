@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"orgchart_nexoan/models"
+)
+
+// SnapshotOptions selects what GetOrgChart includes
+type SnapshotOptions struct {
+	// President limits the chart to the president with this name; empty means every president active on the date
+	President string
+	// SkipDepartments and SkipPeople leave departments and appointed people out to save API calls
+	SkipDepartments bool
+	SkipPeople      bool
+}
+
+// ChartEntity is an entity in an org chart. RelationshipID and Since identify the relationship that
+// places it under its parent in the chart and when that relationship started.
+type ChartEntity struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Kind           string `json:"kind"`
+	RelationshipID string `json:"relationshipId,omitempty"`
+	Since          string `json:"since,omitempty"`
+}
+
+// ChartPresident is a president with the ministers they held
+type ChartPresident struct {
+	ChartEntity
+	Ministers []*ChartMinister `json:"ministers"`
+}
+
+// ChartMinister is a minister with its departments and the people appointed to it
+type ChartMinister struct {
+	ChartEntity
+	Departments []*ChartDepartment `json:"departments"`
+	People      []ChartEntity      `json:"people"`
+}
+
+// ChartDepartment is a department with the people appointed to it
+type ChartDepartment struct {
+	ChartEntity
+	People []ChartEntity `json:"people"`
+}
+
+// OrgChart is the structure of the government on a date
+type OrgChart struct {
+	// Date (RFC3339) the chart was taken at; empty means the relationships that have not ended
+	Date       string            `json:"date"`
+	Government ChartEntity       `json:"government"`
+	Presidents []*ChartPresident `json:"presidents"`
+}
+
+// GetOrgChart builds the org chart as it stood on dateISO (RFC3339) from the AS_PRESIDENT, AS_MINISTER,
+// AS_DEPARTMENT and AS_APPOINTED relationships active then. An empty dateISO means now. opts may be nil.
+func (c *Client) GetOrgChart(dateISO string, opts *SnapshotOptions) (*OrgChart, error) {
+	return c.GetOrgChartContext(context.Background(), dateISO, opts)
+}
+
+// GetOrgChartContext is like GetOrgChart but stops issuing API calls once ctx is done
+func (c *Client) GetOrgChartContext(ctx context.Context, dateISO string, opts *SnapshotOptions) (*OrgChart, error) {
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+
+	governmentID, err := c.getGovernmentIDContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get government node: %w", err)
+	}
+	government, err := c.chartEntity(ctx, governmentID, models.Relationship{})
+	if err != nil {
+		return nil, err
+	}
+	chart := &OrgChart{Date: dateISO, Government: government, Presidents: []*ChartPresident{}}
+
+	presidents, err := c.chartChildren(ctx, governmentID, "AS_PRESIDENT", dateISO)
+	if err != nil {
+		return nil, err
+	}
+	for _, president := range presidents {
+		if opts.President != "" && president.Name != opts.President {
+			continue
+		}
+		chartPresident := &ChartPresident{ChartEntity: president, Ministers: []*ChartMinister{}}
+
+		ministers, err := c.chartChildren(ctx, president.ID, "AS_MINISTER", dateISO)
+		if err != nil {
+			return nil, err
+		}
+		for _, minister := range ministers {
+			chartMinister := &ChartMinister{ChartEntity: minister, Departments: []*ChartDepartment{}, People: []ChartEntity{}}
+
+			if !opts.SkipDepartments {
+				departments, err := c.chartChildren(ctx, minister.ID, "AS_DEPARTMENT", dateISO)
+				if err != nil {
+					return nil, err
+				}
+				for _, department := range departments {
+					chartDepartment := &ChartDepartment{ChartEntity: department, People: []ChartEntity{}}
+					if !opts.SkipPeople {
+						chartDepartment.People, err = c.chartChildren(ctx, department.ID, "AS_APPOINTED", dateISO)
+						if err != nil {
+							return nil, err
+						}
+					}
+					chartMinister.Departments = append(chartMinister.Departments, chartDepartment)
+				}
+			}
+
+			if !opts.SkipPeople {
+				chartMinister.People, err = c.chartChildren(ctx, minister.ID, "AS_APPOINTED", dateISO)
+				if err != nil {
+					return nil, err
+				}
+			}
+			chartPresident.Ministers = append(chartPresident.Ministers, chartMinister)
+		}
+		chart.Presidents = append(chart.Presidents, chartPresident)
+	}
+
+	if opts.President != "" && len(chart.Presidents) == 0 {
+		return nil, notFoundf("president '%s' not active on %s", opts.President, snapshotDate(dateISO))
+	}
+	return chart, nil
+}
+
+// chartChildren returns the entities parentID holds on dateISO through relationships named name,
+// sorted by name and ID
+func (c *Client) chartChildren(ctx context.Context, parentID, name, dateISO string) ([]ChartEntity, error) {
+	relations, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
+		Name:      name,
+		Direction: DirectionOutgoing,
+		ActiveAt:  dateISO,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s relationships of %s: %w", name, parentID, err)
+	}
+
+	children := []ChartEntity{}
+	for _, rel := range relations {
+		if !activeAt(rel, dateISO) {
+			continue
+		}
+		child, err := c.chartEntity(ctx, rel.RelatedEntityID, rel)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].Name != children[j].Name {
+			return children[i].Name < children[j].Name
+		}
+		return children[i].ID < children[j].ID
+	})
+	return children, nil
+}
+
+// chartEntity fetches the entity with the given ID, placed in the chart by rel
+func (c *Client) chartEntity(ctx context.Context, entityID string, rel models.Relationship) (ChartEntity, error) {
+	results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: entityID})
+	if err != nil {
+		return ChartEntity{}, fmt.Errorf("failed to search for entity %s: %w", entityID, err)
+	}
+	if len(results) == 0 {
+		return ChartEntity{}, notFoundf("entity not found: %s", entityID)
+	}
+	return ChartEntity{
+		ID:             results[0].ID,
+		Name:           results[0].Name,
+		Kind:           results[0].Kind.Minor,
+		RelationshipID: rel.ID,
+		Since:          rel.StartTime,
+	}, nil
+}
+
+// WriteText writes the chart as an indented tree
+func (chart *OrgChart) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s on %s\n", chart.Government.Name, snapshotDate(chart.Date))
+	for _, president := range chart.Presidents {
+		fmt.Fprintf(&b, "  President: %s\n", describeChartEntity(president.ChartEntity))
+		for _, minister := range president.Ministers {
+			fmt.Fprintf(&b, "    Minister: %s\n", describeChartEntity(minister.ChartEntity))
+			for _, department := range minister.Departments {
+				fmt.Fprintf(&b, "      Department: %s\n", describeChartEntity(department.ChartEntity))
+				for _, person := range department.People {
+					fmt.Fprintf(&b, "        Person: %s\n", describeChartEntity(person))
+				}
+			}
+			for _, person := range minister.People {
+				fmt.Fprintf(&b, "      Person: %s\n", describeChartEntity(person))
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// describeChartEntity formats an entity as its name, ID and start date
+func describeChartEntity(entity ChartEntity) string {
+	if entity.Since == "" {
+		return fmt.Sprintf("%s [%s]", entity.Name, entity.ID)
+	}
+	return fmt.Sprintf("%s [%s] since %s", entity.Name, entity.ID, shortDate(entity.Since))
+}
+
+// snapshotDate formats the date of a chart for messages
+func snapshotDate(dateISO string) string {
+	if dateISO == "" {
+		return "now"
+	}
+	return shortDate(dateISO)
+}
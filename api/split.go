@@ -0,0 +1,366 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"orgchart_nexoan/models"
+)
+
+// heldChild is a department or person held by an entity through one of its relationships
+type heldChild struct {
+	id             string
+	name           string
+	relName        string
+	relationshipID string
+}
+
+// getHeldChildrenContext lists the entities that parentID holds on dateISO through relationships named name
+func (c *Client) getHeldChildrenContext(ctx context.Context, parentID, name, dateISO string) ([]heldChild, error) {
+	relations, err := c.GetRelatedEntitiesContext(ctx, parentID, &models.Relationship{
+		Name:      name,
+		Direction: DirectionOutgoing,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s relationships of %s: %w", name, parentID, err)
+	}
+
+	var children []heldChild
+	for _, rel := range relations {
+		if !activeAt(rel, dateISO) {
+			continue
+		}
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{ID: rel.RelatedEntityID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for entity %s: %w", rel.RelatedEntityID, err)
+		}
+		if len(results) == 0 {
+			return nil, notFoundf("failed to find entity with ID: %s", rel.RelatedEntityID)
+		}
+		children = append(children, heldChild{
+			id:             rel.RelatedEntityID,
+			name:           results[0].Name,
+			relName:        name,
+			relationshipID: rel.ID,
+		})
+	}
+	return children, nil
+}
+
+// assignSuccessors returns the successor taking over each child of a split, by child ID. Departments
+// must be mapped; people that are not mapped get no successor. Every mapped name must be a child.
+func assignSuccessors(tx *SplitTx, departments, people []heldChild) (map[string]string, error) {
+	successorOf := make(map[string]string, len(departments)+len(people))
+	mapped := make(map[string]bool, len(tx.Mapping))
+	for _, department := range departments {
+		successor, ok := tx.Mapping[department.name]
+		if !ok {
+			return nil, invalidField("mapping", fmt.Sprintf("department '%s' of '%s' is not mapped to a successor", department.name, tx.Old), nil)
+		}
+		successorOf[department.id] = successor
+		mapped[department.name] = true
+	}
+	for _, person := range people {
+		if successor, ok := tx.Mapping[person.name]; ok {
+			successorOf[person.id] = successor
+			mapped[person.name] = true
+		}
+	}
+
+	var unknown []string
+	for name := range tx.Mapping {
+		if !mapped[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, invalidField("mapping", fmt.Sprintf("'%s' is not a department or person of '%s'", unknown[0], tx.Old), nil)
+	}
+	return successorOf, nil
+}
+
+// handOverChildren ends the relationships of parentID with children and starts those of the mapped
+// successors, given by name in successorIDs
+func (c *Client) handOverChildren(ctx context.Context, key, parentID string, children []heldChild, successorOf, successorIDs map[string]string, dateISO string) error {
+	for _, child := range children {
+		if successor, ok := successorOf[child.id]; ok {
+			if err := c.startRelationship(ctx, key, successorIDs[successor], child.id, child.relName, dateISO); err != nil {
+				return fmt.Errorf("failed to create new %s relationship: %w", child.relName, err)
+			}
+		}
+		if err := c.endRelationship(ctx, parentID, child.relationshipID, dateISO); err != nil {
+			return fmt.Errorf("failed to terminate old %s relationship: %w", child.relName, err)
+		}
+	}
+	return nil
+}
+
+// recordSplit creates the SPLIT_INTO relationships from oldID to each successor, in the order of tx.New
+func (c *Client) recordSplit(ctx context.Context, tx *SplitTx, oldID string, successorIDs map[string]string, dateISO string) error {
+	key := transactionKey(ChangeSplit, tx.TransactionID, tx.Date)
+	for _, name := range tx.New {
+		if err := c.startRelationship(ctx, key, oldID, successorIDs[name], "SPLIT_INTO", dateISO); err != nil {
+			return fmt.Errorf("failed to create SPLIT_INTO relationship: %w", err)
+		}
+	}
+	return nil
+}
+
+// SplitMinister splits a minister into new ministers under the same president. Its departments, and
+// the people named in the mapping, move to the successor they are mapped to; the appointments of
+// other people end with the old minister. The old minister gets a SPLIT_INTO relationship to each successor.
+// The whole split is resolved before anything is written, so a transaction that does not fit the
+// current org chart writes nothing.
+func (c *Client) SplitMinister(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	return c.SplitMinisterContext(context.Background(), transaction, entityCounters)
+}
+
+// SplitMinisterContext is like SplitMinister but stops issuing API calls once ctx is done
+func (c *Client) SplitMinisterContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeSplitTx(transaction, "minister")
+	if err != nil {
+		return 0, err
+	}
+	return c.SplitMinisterTx(ctx, tx, entityCounters)
+}
+
+// SplitMinisterTx is like SplitMinisterContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) SplitMinisterTx(ctx context.Context, tx *SplitTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("minister"); err != nil {
+		return 0, tx.Source.locate(err)
+	}
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	// A split applied before has already recorded the lineage
+	key := transactionKey(ChangeSplit, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "minister"}, tx.Old, "SPLIT_INTO", key)
+	if err != nil {
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("split of minister '%s'", tx.Old))
+		return entityCounters["minister"], nil
+	}
+
+	// Resolve the old minister, its departments and people, and the mapping
+	oldMinister, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, tx.Old, dateISO)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get old minister: %w", err)
+	}
+	departments, err := c.getHeldChildrenContext(ctx, oldMinister.ID, "AS_DEPARTMENT", dateISO)
+	if err != nil {
+		return 0, err
+	}
+	people, err := c.getHeldChildrenContext(ctx, oldMinister.ID, "AS_APPOINTED", dateISO)
+	if err != nil {
+		return 0, err
+	}
+	successorOf, err := assignSuccessors(tx, departments, people)
+	if err != nil {
+		return 0, tx.Source.locate(err)
+	}
+	for _, name := range tx.New {
+		_, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, name, dateISO)
+		if err == nil {
+			return 0, existsf("minister '%s' already exists under president '%s'", name, presidentName)
+		}
+		if !errors.Is(err, ErrEntityNotFound) {
+			return 0, fmt.Errorf("failed to check new minister '%s': %w", name, err)
+		}
+	}
+	presidentEntity, err := c.GetPresidentByGovernmentContext(ctx, presidentName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get president entity: %w", err)
+	}
+	presidentRelations, err := c.GetRelatedEntitiesContext(ctx, presidentEntity.ID, &models.Relationship{
+		Name:            "AS_MINISTER",
+		RelatedEntityID: oldMinister.ID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get relationship between president and minister: %w", err)
+	}
+	var ministerRel *models.Relationship
+	for _, rel := range presidentRelations {
+		if activeAt(rel, dateISO) {
+			ministerRel = &rel
+			break
+		}
+	}
+	if ministerRel == nil {
+		return 0, notFoundf("no active relationship found between president and minister")
+	}
+
+	// 1. Create the successors
+	successorIDs := make(map[string]string, len(tx.New))
+	newMinisterCounter := 0
+	for i, name := range tx.New {
+		newMinisterCounter, err = c.addOrgEntity(ctx, &AddTx{
+			TransactionID: tx.TransactionID,
+			Parent:        presidentName,
+			ParentType:    "president",
+			Child:         name,
+			ChildType:     "minister",
+			RelType:       "AS_MINISTER",
+			Date:          tx.Date,
+			President:     presidentName,
+		}, key, fmt.Sprintf("minister_%d", i+1), entityCounters)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create new minister: %w", err)
+		}
+		entityCounters["minister"] = newMinisterCounter
+
+		successor, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, name, dateISO)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get new minister: %w", err)
+		}
+		successorIDs[name] = successor.ID
+	}
+
+	// 2. Hand the departments and people over
+	if err := c.handOverChildren(ctx, key, oldMinister.ID, append(departments, people...), successorOf, successorIDs, dateISO); err != nil {
+		return 0, err
+	}
+
+	// 3. End the old minister's relationship with the president
+	if err := c.endRelationship(ctx, presidentEntity.ID, ministerRel.ID, dateISO); err != nil {
+		return 0, fmt.Errorf("failed to terminate old minister's government relationship: %w", err)
+	}
+
+	// 4. Record the lineage
+	if err := c.recordSplit(ctx, tx, oldMinister.ID, successorIDs, dateISO); err != nil {
+		return 0, err
+	}
+
+	return newMinisterCounter, nil
+}
+
+// SplitDepartment splits a department into new departments under the minister named by the
+// transaction's parent, or under the minister of the old department. The people named in the mapping
+// move to the successor they are mapped to; the appointments of other people end with the old
+// department, and its documents stay with it. The old department gets a SPLIT_INTO relationship to each successor.
+// The whole split is resolved before anything is written.
+func (c *Client) SplitDepartment(transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	return c.SplitDepartmentContext(context.Background(), transaction, entityCounters)
+}
+
+// SplitDepartmentContext is like SplitDepartment but stops issuing API calls once ctx is done
+func (c *Client) SplitDepartmentContext(ctx context.Context, transaction map[string]interface{}, entityCounters map[string]int) (int, error) {
+	tx, err := decodeSplitTx(transaction, "department")
+	if err != nil {
+		return 0, err
+	}
+	return c.SplitDepartmentTx(ctx, tx, entityCounters)
+}
+
+// SplitDepartmentTx is like SplitDepartmentContext but takes a decoded transaction. tx.Type is not consulted.
+func (c *Client) SplitDepartmentTx(ctx context.Context, tx *SplitTx, entityCounters map[string]int) (int, error) {
+	if err := tx.validate("department"); err != nil {
+		return 0, tx.Source.locate(err)
+	}
+	presidentName := tx.President
+	dateISO := tx.Date.Format(time.RFC3339)
+
+	// A split applied before has already recorded the lineage
+	key := transactionKey(ChangeSplit, tx.TransactionID, tx.Date)
+	applied, err := c.lineageRecorded(ctx, models.Kind{Major: "Organisation", Minor: "department"}, tx.Old, "SPLIT_INTO", key)
+	if err != nil {
+		return 0, err
+	}
+	if applied {
+		c.skipApplied(key, fmt.Sprintf("split of department '%s'", tx.Old))
+		return entityCounters["department"], nil
+	}
+
+	// Resolve the old department, its people, the mapping and the minister of the successors
+	oldDepartment, err := c.getHeldDepartmentContext(ctx, tx.Old, presidentName, dateISO)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get old department: %w", err)
+	}
+	people, err := c.getHeldChildrenContext(ctx, oldDepartment.id, "AS_APPOINTED", dateISO)
+	if err != nil {
+		return 0, err
+	}
+	successorOf, err := assignSuccessors(tx, nil, people)
+	if err != nil {
+		return 0, tx.Source.locate(err)
+	}
+	ministerName := oldDepartment.ministerName
+	if tx.Parent != "" {
+		if _, err := c.GetActiveMinisterByPresidentContext(ctx, presidentName, tx.Parent, dateISO); err != nil {
+			return 0, fmt.Errorf("failed to get parent minister entity: %w", err)
+		}
+		ministerName = tx.Parent
+	}
+	for _, name := range tx.New {
+		existing, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+			Kind: &models.Kind{
+				Major: "Organisation",
+				Minor: "department",
+			},
+			Name: name,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to search for existing department: %w", err)
+		}
+		if len(existing) > 0 {
+			return 0, existsf("department with name '%s' already exists", name)
+		}
+	}
+
+	// 1. Create the successors
+	successorIDs := make(map[string]string, len(tx.New))
+	newDepartmentCounter := 0
+	for i, name := range tx.New {
+		newDepartmentCounter, err = c.addOrgEntity(ctx, &AddTx{
+			TransactionID: tx.TransactionID,
+			Parent:        ministerName,
+			ParentType:    "minister",
+			Child:         name,
+			ChildType:     "department",
+			RelType:       "AS_DEPARTMENT",
+			Date:          tx.Date,
+			President:     presidentName,
+		}, key, fmt.Sprintf("department_%d", i+1), entityCounters)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create new department: %w", err)
+		}
+		entityCounters["department"] = newDepartmentCounter
+
+		results, err := c.SearchEntitiesContext(ctx, &models.SearchCriteria{
+			Kind: &models.Kind{
+				Major: "Organisation",
+				Minor: "department",
+			},
+			Name: name,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to search for new department: %w", err)
+		}
+		if len(results) != 1 {
+			return 0, notFoundf("new department not found: %s", name)
+		}
+		successorIDs[name] = results[0].ID
+	}
+
+	// 2. Hand the people over
+	if err := c.handOverChildren(ctx, key, oldDepartment.id, people, successorOf, successorIDs, dateISO); err != nil {
+		return 0, err
+	}
+
+	// 3. End the old department's relationship with its minister
+	if err := c.endRelationship(ctx, oldDepartment.ministerID, oldDepartment.relationshipID, dateISO); err != nil {
+		return 0, fmt.Errorf("failed to terminate old department's minister relationship: %w", err)
+	}
+
+	// 4. Record the lineage
+	if err := c.recordSplit(ctx, tx, oldDepartment.id, successorIDs, dateISO); err != nil {
+		return 0, err
+	}
+
+	return newDepartmentCounter, nil
+}
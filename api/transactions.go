@@ -0,0 +1,673 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of the date column of transaction files
+const dateLayout = "2006-01-02"
+
+// sourceKey is the key under which loadTransactions records where a transaction was read from
+const sourceKey = "source"
+
+// TxSource locates a transaction in the CSV file it was read from. Decoders use it to name the file,
+// line and column of invalid fields; it is empty for transactions built in code.
+type TxSource struct {
+	File string
+	Line int
+	// Columns is the header of the file, in order
+	Columns []string
+}
+
+// column returns the 1-based column number of field in the file, or 0 if the file has no such column
+func (s TxSource) column(field string) int {
+	for i, column := range s.Columns {
+		if column == field {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// locate adds the position of the invalid field to an InvalidTransactionError
+func (s TxSource) locate(err error) error {
+	var invalid *InvalidTransactionError
+	if s.File == "" || !errors.As(err, &invalid) {
+		return err
+	}
+	invalid.File = s.File
+	invalid.Line = s.Line
+	invalid.Column = s.column(invalid.Field)
+	return err
+}
+
+// AddTx adds a minister, department or citizen under a parent entity (ADD files)
+type AddTx struct {
+	TransactionID string
+	Parent        string
+	ParentType    string
+	Child         string
+	ChildType     string
+	RelType       string
+	Date          time.Time
+	// President is the president the parent minister serves under; required for departments and people under ministers
+	President string
+	Source    TxSource
+}
+
+// TerminateTx ends the relationship between a parent and a child entity (TERMINATE files)
+type TerminateTx struct {
+	TransactionID string
+	Parent        string
+	ParentType    string
+	Child         string
+	ChildType     string
+	RelType       string
+	Date          time.Time
+	// President is required when the parent is a minister or the child a department
+	President string
+	Source    TxSource
+}
+
+// MoveTx moves a department or a citizen to another minister, or a minister to another president (MOVE files)
+type MoveTx struct {
+	TransactionID string
+	OldParent     string
+	NewParent     string
+	Child         string
+	// Type is the kind of the moved entity: department, minister or citizen
+	Type string
+	Date time.Time
+	// OldPresident and NewPresident are the presidents of the old and new minister of a department
+	OldPresident string
+	NewPresident string
+	// President is the president of both ministers of a citizen
+	President string
+	Source    TxSource
+}
+
+// RenameTx renames a minister or a department (RENAME files)
+type RenameTx struct {
+	TransactionID string
+	Old           string
+	New           string
+	// Type is minister or department
+	Type      string
+	Date      time.Time
+	President string
+	Source    TxSource
+}
+
+// MergeTx merges ministers or departments into a new one (MERGE files)
+type MergeTx struct {
+	TransactionID string
+	// Old lists the merged entities, written as "[Minister of A;Minister of B]" in the file
+	Old []string
+	New string
+	// Type is minister or department; empty means minister
+	Type      string
+	Date      time.Time
+	President string
+	// Parent is the minister of a new department. Empty means the minister of the first old department.
+	Parent string
+	// People and Documents say what happens to the people appointed to, and the documents of, merged
+	// departments. Empty means MergeMove for people and MergeCopy for documents.
+	People    MergePolicy
+	Documents MergePolicy
+	Source    TxSource
+}
+
+// SplitTx splits a minister or a department into several new ones (SPLIT files)
+type SplitTx struct {
+	TransactionID string
+	Old           string
+	// New lists the successors, written as "[Minister of A;Minister of B]" in the file
+	New []string
+	// Type is minister or department
+	Type      string
+	Date      time.Time
+	President string
+	// Parent is the minister of new departments. Empty means the minister of the old department.
+	Parent string
+	// Mapping maps the names of the departments and people of the old entity to the successor taking
+	// them over, written as "[Department of X=Minister of A;Jane Doe=Minister of B]" in the file.
+	// Every department of a split minister must be mapped; people that are not mapped leave with it.
+	Mapping map[string]string
+	Source  TxSource
+}
+
+// DocumentTx adds a gazette document under a parent organisation (document ADD files)
+type DocumentTx struct {
+	TransactionID string
+	Parent        string
+	ParentType    string
+	Child         string
+	ChildType     string
+	URL           string
+	Description   string
+	Date          time.Time
+	Source        TxSource
+}
+
+// relTypesByKind lists the relationship types an entity kind can be added or terminated with
+var relTypesByKind = map[string][]string{
+	"minister":   {"AS_MINISTER"},
+	"department": {"AS_DEPARTMENT"},
+	"citizen":    {"AS_APPOINTED", "AS_PRESIDENT", "AS_PRIME_MINISTER"},
+}
+
+// Validate checks that the transaction can be applied
+func (tx *AddTx) Validate() error {
+	if err := requireFields(map[string]string{
+		"transaction_id": tx.TransactionID,
+		"parent":         tx.Parent,
+		"parent_type":    tx.ParentType,
+		"child":          tx.Child,
+	}); err != nil {
+		return err
+	}
+	if err := validateRelationship(tx.ChildType, tx.RelType, tx.Date); err != nil {
+		return err
+	}
+	if tx.President == "" && (tx.ChildType == "department" || tx.ParentType == "minister") {
+		return invalidField("president", fmt.Sprintf("president name is required and must be a non-empty string when adding a %s to a minister", tx.ChildType), nil)
+	}
+	return nil
+}
+
+// Validate checks that the transaction can be applied
+func (tx *TerminateTx) Validate() error {
+	if err := requireFields(map[string]string{
+		"parent":      tx.Parent,
+		"parent_type": tx.ParentType,
+		"child":       tx.Child,
+	}); err != nil {
+		return err
+	}
+	if err := validateRelationship(tx.ChildType, tx.RelType, tx.Date); err != nil {
+		return err
+	}
+	if tx.President == "" && (tx.ChildType == "department" || tx.ParentType == "minister") {
+		return invalidField("president", fmt.Sprintf("president name is required and must be a non-empty string when terminating %s relationships", tx.ChildType), nil)
+	}
+	return nil
+}
+
+// Validate checks that the transaction can be applied
+func (tx *MoveTx) Validate() error {
+	return tx.validate(tx.Type)
+}
+
+// validate checks the transaction as a move of the given kind of entity. The Client methods move a
+// single kind, whatever Type says.
+func (tx *MoveTx) validate(kind string) error {
+	if err := requireFields(map[string]string{
+		"new_parent": tx.NewParent,
+		"child":      tx.Child,
+	}); err != nil {
+		return err
+	}
+	if err := validateDate(tx.Date); err != nil {
+		return err
+	}
+	switch kind {
+	case "department":
+		if tx.NewPresident == "" {
+			return invalidField("new_president_name", "new_president_name is required and must be a non-empty string", nil)
+		}
+	case "minister":
+		if tx.OldParent == "" {
+			return invalidField("old_parent", "old_parent is required and must be a non-empty string", nil)
+		}
+	case "citizen":
+		if tx.OldParent == "" {
+			return invalidField("old_parent", "old_parent is required and must be a non-empty string", nil)
+		}
+		if tx.President == "" {
+			return invalidField("president", "president name is required and must be a non-empty string", nil)
+		}
+	default:
+		return invalidField("type", fmt.Sprintf("unknown child type for MOVE transaction: %s", kind), nil)
+	}
+	return nil
+}
+
+// Validate checks that the transaction can be applied
+func (tx *RenameTx) Validate() error {
+	return tx.validate(tx.Type)
+}
+
+// validate checks the transaction as a rename of the given kind of entity
+func (tx *RenameTx) validate(kind string) error {
+	if err := requireFields(map[string]string{
+		"transaction_id": tx.TransactionID,
+		"old":            tx.Old,
+		"new":            tx.New,
+		"president":      tx.President,
+	}); err != nil {
+		return err
+	}
+	if kind != "minister" && kind != "department" {
+		return invalidField("type", fmt.Sprintf("unknown type for RENAME transaction: %s", kind), nil)
+	}
+	return validateDate(tx.Date)
+}
+
+// Validate checks that the transaction can be applied
+func (tx *MergeTx) Validate() error {
+	return tx.validate(tx.Type)
+}
+
+// validate checks the transaction as a merge of the given kind of entity
+func (tx *MergeTx) validate(kind string) error {
+	if err := requireFields(map[string]string{
+		"transaction_id": tx.TransactionID,
+		"new":            tx.New,
+		"president":      tx.President,
+	}); err != nil {
+		return err
+	}
+	if len(tx.Old) == 0 {
+		return invalidField("old", "old is required and must list the merged entities", nil)
+	}
+	for _, old := range tx.Old {
+		if old == "" {
+			return invalidField("old", "old lists an empty name", nil)
+		}
+	}
+	switch kind {
+	case "", "minister":
+	case "department":
+		if err := validatePolicy("people", tx.People); err != nil {
+			return err
+		}
+		if err := validatePolicy("documents", tx.Documents); err != nil {
+			return err
+		}
+	default:
+		return invalidField("type", fmt.Sprintf("unknown type for MERGE transaction: %s", kind), nil)
+	}
+	return validateDate(tx.Date)
+}
+
+// Validate checks that the transaction can be applied
+func (tx *SplitTx) Validate() error {
+	return tx.validate(tx.Type)
+}
+
+// validate checks the transaction as a split of the given kind of entity
+func (tx *SplitTx) validate(kind string) error {
+	if err := requireFields(map[string]string{
+		"transaction_id": tx.TransactionID,
+		"old":            tx.Old,
+		"president":      tx.President,
+	}); err != nil {
+		return err
+	}
+	if kind != "minister" && kind != "department" {
+		return invalidField("type", fmt.Sprintf("unknown type for SPLIT transaction: %s", kind), nil)
+	}
+	if len(tx.New) < 2 {
+		return invalidField("new", "new must list at least two successors", nil)
+	}
+	successors := make(map[string]bool, len(tx.New))
+	for _, name := range tx.New {
+		if name == "" || successors[name] || name == tx.Old {
+			return invalidField("new", fmt.Sprintf("successor '%s' must be named once and differ from the old entity", name), nil)
+		}
+		successors[name] = true
+	}
+	children := make([]string, 0, len(tx.Mapping))
+	for child := range tx.Mapping {
+		children = append(children, child)
+	}
+	sort.Strings(children)
+	for _, child := range children {
+		if !successors[tx.Mapping[child]] {
+			return invalidField("mapping", fmt.Sprintf("'%s' is mapped to '%s', which is not a successor", child, tx.Mapping[child]), nil)
+		}
+	}
+	return validateDate(tx.Date)
+}
+
+// Validate checks that the transaction can be applied
+func (tx *DocumentTx) Validate() error {
+	if err := requireFields(map[string]string{
+		"transaction_id": tx.TransactionID,
+		"parent":         tx.Parent,
+		"parent_type":    tx.ParentType,
+		"child":          tx.Child,
+		"child_type":     tx.ChildType,
+	}); err != nil {
+		return err
+	}
+	return validateDate(tx.Date)
+}
+
+// requireFields reports the first empty field, in the order of the transaction files' columns
+func requireFields(fields map[string]string) error {
+	for _, field := range []string{"transaction_id", "parent", "parent_type", "child", "child_type", "old", "new_parent", "new", "president"} {
+		if value, ok := fields[field]; ok && value == "" {
+			return invalidField(field, fmt.Sprintf("%s is required and must be a non-empty string", field), nil)
+		}
+	}
+	return nil
+}
+
+// validateRelationship checks the child_type and rel_type of ADD and TERMINATE transactions and their date
+func validateRelationship(childType, relType string, date time.Time) error {
+	relTypes, ok := relTypesByKind[childType]
+	if !ok {
+		return invalidField("child_type", fmt.Sprintf("unknown child type: %s", childType), nil)
+	}
+	if !containsString(relTypes, relType) {
+		return invalidField("rel_type", fmt.Sprintf("%s cannot be related with %s, expected one of %s", childType, relType, strings.Join(relTypes, ", ")), nil)
+	}
+	return validateDate(date)
+}
+
+func validateDate(date time.Time) error {
+	if date.IsZero() {
+		return invalidField("date", "date is required", nil)
+	}
+	return nil
+}
+
+// fieldReader reads the columns of a transaction loaded from a CSV file, keeping the first error
+type fieldReader struct {
+	transaction map[string]interface{}
+	err         error
+}
+
+// fail records an invalid field unless an earlier one was recorded
+func (r *fieldReader) fail(field, reason string, err error) {
+	if r.err == nil {
+		r.err = invalidField(field, reason, err)
+	}
+}
+
+// optional returns the value of the first of the given columns that is present, or "" if there is none
+func (r *fieldReader) optional(fields ...string) string {
+	for _, field := range fields {
+		if value, ok := r.transaction[field]; ok {
+			text, isString := value.(string)
+			if !isString {
+				r.fail(field, fmt.Sprintf("%s must be a string", field), nil)
+				return ""
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+// required returns the value of a column that must be present
+func (r *fieldReader) required(field string) string {
+	if _, ok := r.transaction[field]; !ok {
+		r.fail(field, "column is missing", nil)
+		return ""
+	}
+	return r.optional(field)
+}
+
+// kind returns the trimmed value of a column holding a type name
+func (r *fieldReader) kind(field string) string {
+	return strings.TrimSpace(r.required(field))
+}
+
+// date parses the date column
+func (r *fieldReader) date() time.Time {
+	value := strings.TrimSpace(r.required("date"))
+	if r.err != nil {
+		return time.Time{}
+	}
+	if value == "" {
+		r.fail("date", "date is required", nil)
+		return time.Time{}
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		r.fail("date", "failed to parse date", err)
+	}
+	return date
+}
+
+// mapping parses an optional column of "name=successor" pairs, written like the names of MERGE files
+func (r *fieldReader) mapping(field string) map[string]string {
+	pairs := splitMergedNames(r.optional(field))
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, successor, ok := strings.Cut(pair, "=")
+		name, successor = strings.TrimSpace(name), strings.TrimSpace(successor)
+		if !ok || name == "" || successor == "" {
+			r.fail(field, fmt.Sprintf("'%s' must be written as name=successor", pair), nil)
+			return nil
+		}
+		if _, duplicate := mapping[name]; duplicate {
+			r.fail(field, fmt.Sprintf("'%s' is mapped more than once", name), nil)
+			return nil
+		}
+		mapping[name] = successor
+	}
+	return mapping
+}
+
+// decode finishes decoding: it validates tx unless a column was unusable and locates any error
+func decode(source TxSource, readErr error, validate func() error) error {
+	err := readErr
+	if err == nil {
+		err = validate()
+	}
+	if err != nil {
+		return source.locate(err)
+	}
+	return nil
+}
+
+// transactionSource returns where transaction was loaded from
+func transactionSource(transaction map[string]interface{}) TxSource {
+	source, _ := transaction[sourceKey].(TxSource)
+	return source
+}
+
+// DecodeAddTx decodes and validates an ADD transaction. Errors name the file, line and column when
+// the transaction was loaded from a CSV file.
+func DecodeAddTx(transaction map[string]interface{}) (*AddTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &AddTx{
+		TransactionID: r.required("transaction_id"),
+		Parent:        r.required("parent"),
+		ParentType:    r.kind("parent_type"),
+		Child:         r.required("child"),
+		ChildType:     r.kind("child_type"),
+		RelType:       r.kind("rel_type"),
+		Date:          r.date(),
+		President:     r.optional("president"),
+		Source:        transactionSource(transaction),
+	}
+	if err := decode(tx.Source, r.err, tx.Validate); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeTerminateTx decodes and validates a TERMINATE transaction
+func DecodeTerminateTx(transaction map[string]interface{}) (*TerminateTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &TerminateTx{
+		TransactionID: r.optional("transaction_id"),
+		Parent:        r.required("parent"),
+		ParentType:    r.kind("parent_type"),
+		Child:         r.required("child"),
+		ChildType:     r.kind("child_type"),
+		RelType:       r.kind("rel_type"),
+		Date:          r.date(),
+		President:     r.optional("president"),
+		Source:        transactionSource(transaction),
+	}
+	if err := decode(tx.Source, r.err, tx.Validate); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeMoveTx decodes and validates a MOVE transaction. The presidents of department moves may be
+// given as old_president_name/new_president_name or old_parent_pres/new_parent_pres.
+func DecodeMoveTx(transaction map[string]interface{}) (*MoveTx, error) {
+	return decodeMoveTx(transaction, "")
+}
+
+// decodeMoveTx decodes a move of the given kind of entity; an empty kind is read from the type column
+func decodeMoveTx(transaction map[string]interface{}, kind string) (*MoveTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &MoveTx{
+		TransactionID: r.optional("transaction_id"),
+		OldParent:     r.optional("old_parent"),
+		NewParent:     r.required("new_parent"),
+		Child:         r.required("child"),
+		Date:          r.date(),
+		OldPresident:  r.optional("old_president_name", "old_parent_pres"),
+		NewPresident:  r.optional("new_president_name", "new_parent_pres"),
+		President:     r.optional("president"),
+		Source:        transactionSource(transaction),
+	}
+	if kind == "" {
+		tx.Type = r.kind("type")
+	} else {
+		tx.Type = strings.TrimSpace(r.optional("type"))
+	}
+	if err := decode(tx.Source, r.err, func() error { return tx.validate(orKind(kind, tx.Type)) }); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeRenameTx decodes and validates a RENAME transaction
+func DecodeRenameTx(transaction map[string]interface{}) (*RenameTx, error) {
+	return decodeRenameTx(transaction, "")
+}
+
+// decodeRenameTx decodes a rename of the given kind of entity; an empty kind is read from the type column
+func decodeRenameTx(transaction map[string]interface{}, kind string) (*RenameTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &RenameTx{
+		TransactionID: r.required("transaction_id"),
+		Old:           r.required("old"),
+		New:           r.required("new"),
+		Date:          r.date(),
+		President:     r.optional("president"),
+		Source:        transactionSource(transaction),
+	}
+	if kind == "" {
+		tx.Type = r.kind("type")
+	} else {
+		tx.Type = strings.TrimSpace(r.optional("type"))
+	}
+	if err := decode(tx.Source, r.err, func() error { return tx.validate(orKind(kind, tx.Type)) }); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// orKind returns kind, or the kind named by the transaction when kind is empty
+func orKind(kind, txKind string) string {
+	if kind == "" {
+		return txKind
+	}
+	return kind
+}
+
+// DecodeMergeTx decodes and validates a MERGE transaction. Department merges may name the minister
+// of the new department in a parent column and their policies in people and documents columns.
+func DecodeMergeTx(transaction map[string]interface{}) (*MergeTx, error) {
+	return decodeMergeTx(transaction, "")
+}
+
+// decodeMergeTx decodes a merge of the given kind of entity; an empty kind is read from the type column
+func decodeMergeTx(transaction map[string]interface{}, kind string) (*MergeTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &MergeTx{
+		TransactionID: r.required("transaction_id"),
+		Old:           splitMergedNames(r.required("old")),
+		New:           r.required("new"),
+		Type:          strings.TrimSpace(r.optional("type")),
+		Date:          r.date(),
+		President:     r.optional("president"),
+		Parent:        r.optional("parent"),
+		People:        MergePolicy(strings.ToLower(strings.TrimSpace(r.optional("people")))),
+		Documents:     MergePolicy(strings.ToLower(strings.TrimSpace(r.optional("documents")))),
+		Source:        transactionSource(transaction),
+	}
+	if err := decode(tx.Source, r.err, func() error { return tx.validate(orKind(kind, tx.Type)) }); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeSplitTx decodes and validates a SPLIT transaction
+func DecodeSplitTx(transaction map[string]interface{}) (*SplitTx, error) {
+	return decodeSplitTx(transaction, "")
+}
+
+// decodeSplitTx decodes a split of the given kind of entity; an empty kind is read from the type column
+func decodeSplitTx(transaction map[string]interface{}, kind string) (*SplitTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &SplitTx{
+		TransactionID: r.required("transaction_id"),
+		Old:           strings.TrimSpace(r.required("old")),
+		New:           splitMergedNames(r.required("new")),
+		Date:          r.date(),
+		President:     r.optional("president"),
+		Parent:        r.optional("parent"),
+		Mapping:       r.mapping("mapping"),
+		Source:        transactionSource(transaction),
+	}
+	if kind == "" {
+		tx.Type = r.kind("type")
+	} else {
+		tx.Type = strings.TrimSpace(r.optional("type"))
+	}
+	if err := decode(tx.Source, r.err, func() error { return tx.validate(orKind(kind, tx.Type)) }); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// DecodeDocumentTx decodes and validates a document ADD transaction. The description may be given
+// as description or desc.
+func DecodeDocumentTx(transaction map[string]interface{}) (*DocumentTx, error) {
+	r := &fieldReader{transaction: transaction}
+	tx := &DocumentTx{
+		TransactionID: r.required("transaction_id"),
+		Parent:        r.required("parent"),
+		ParentType:    r.kind("parent_type"),
+		Child:         r.required("child"),
+		ChildType:     r.kind("child_type"),
+		URL:           r.optional("url"),
+		Description:   r.optional("description", "desc"),
+		Date:          r.date(),
+		Source:        transactionSource(transaction),
+	}
+	if err := decode(tx.Source, r.err, tx.Validate); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// splitMergedNames parses the old column of MERGE files. Names are separated by semicolons so that
+// they may contain commas.
+func splitMergedNames(value string) []string {
+	trimmed := strings.TrimSpace(strings.Trim(strings.TrimSpace(value), "[]"))
+	if trimmed == "" {
+		return nil
+	}
+	names := strings.Split(trimmed, ";")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names
+}
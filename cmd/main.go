@@ -20,6 +20,82 @@
 //	      Endpoint for the Update API (default "http://localhost:8080/entities")
 //	-query_endpoint string
 //	      Endpoint for the Query API (default "http://localhost:8081/v1/entities")
+//	-retry_attempts int
+//	      Attempts per API call before giving up on transient failures, 1 disables retries (default 4)
+//	-cache
+//	      Cache lookups of the government, presidents, entities and relations during the load (default false).
+//	      Only use it when nothing else writes to the same database at the same time, as the load scripts do.
+//	-batch string
+//	      Merge relationship writes into one update per entity: 'off', 'transaction' or 'file' (default "off")
+//	-journal
+//	      Record the transactions applied, with the entities and relationships they created, in
+//	      .orgchart_journal.jsonl in the data directory (default false). A load with -journal starts the
+//	      journal afresh, so continue a failed one with -resume instead.
+//	-resume
+//	      Skip the transactions the journal records as applied and continue from the one a failed load stopped on.
+//	      The load goes on recording in the same journal.
+//	-dependencies string
+//	      What to do when a transaction needs a minister or department that a later transaction of the
+//	      data directory adds: 'warn', 'reorder' to move the ADD ahead when both have the same date,
+//	      or 'fail' before anything is loaded (default "warn")
+//	-timeout duration
+//	      Timeout of each API request (default 30s)
+//	-token_file string
+//	      File holding a bearer token for the APIs. The token can also be given in NEXOAN_BEARER_TOKEN.
+//	-ca_cert string
+//	      PEM file of the CA that signed the API servers' certificates (env NEXOAN_CA_CERT)
+//	-client_cert string, -client_key string
+//	      PEM certificate and key for mutual TLS (env NEXOAN_CLIENT_CERT, NEXOAN_CLIENT_KEY)
+//	-header value
+//	      Extra request header as 'Name: value', e.g. 'User-Agent: orgchart-loader'; may be repeated
+//	-lineage string
+//	      Print the renames, merges and splits of the named minister or department instead of
+//	      processing transactions. Requires -president; -data is not needed.
+//	-snapshot
+//	      Print the org chart on -date instead of processing transactions; -data is not needed
+//	-diff string
+//	      Print the changes to the org chart between this date (YYYY-MM-DD) and -date instead of
+//	      processing transactions; -data is not needed
+//	-president string
+//	      President whose minister or department -lineage names, or the only president -snapshot
+//	      and -diff show
+//	-date string
+//	      Date (YYYY-MM-DD) of the -snapshot or end of the -diff (default now), or picking between
+//	      ministers or departments of the same name for -lineage
+//	-plan
+//	      Apply the transactions of -data to an in-memory copy of the graph and print the entities and
+//	      relationships the load would create or end, and the transactions that would fail, without
+//	      writing to the APIs. The copy is taken from the Query API, from -chart, or is empty with -init.
+//	-history
+//	      Treat -data as a data root and load its documents, orgchart and people folders in one run:
+//	      every folder holding transaction files, in the order the data root's history.yaml lists
+//	      them. Folders it does not list go by date and gazette number, with documents, then new
+//	      presidents, then organisation, then people for the same date. -president limits it to a
+//	      comma-separated list of presidents; -type is not used.
+//	-manifest string
+//	      Load the steps of a YAML or JSON manifest instead of -data: directories in order, each with
+//	      its type, init, president, continueOnError, skip and expected counts; -data is not needed
+//	-generate_manifest string
+//	      Print a manifest of the loads in the comma-separated load scripts, e.g. load_gr_data.sh,
+//	      as YAML, or JSON with -format json, instead of loading anything
+//	-format string
+//	      Output format of -lineage, -snapshot, -diff and -plan: 'text' or 'json', or 'csv' for -diff (default "text")
+//	-out string
+//	      Directory the CSV files of -diff -format csv are written to
+//	-export string
+//	      Draw the org chart on -date as 'dot', 'mermaid' or 'graphml' instead of processing transactions
+//	-chart string
+//	      Org chart saved by -snapshot -format json that -export draws, or -plan starts from, without calling the APIs
+//	-minister string
+//	      Only minister -export draws
+//	-departments, -people
+//	      Draw departments and appointed people in -export (default true)
+//	-label_length int
+//	      Shorten names longer than this in -export; 0 keeps whole names
+//	-group_by_kind
+//	      Group presidents, ministers, departments and people in -export
+//
+// Proxies are taken from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
 //
 // Examples:
 //
@@ -38,19 +114,61 @@
 //  4. Use custom API endpoints:
 //     go run cmd/main.go -data /path/to/data/directory -update_endpoint http://custom:8080/entities -query_endpoint http://custom:8081/v1/entities
 //
+//  5. Keep a journal of a load, and continue it without applying its first transactions again if it fails part way:
+//     go run cmd/main.go -data /path/to/data/directory -journal
+//     go run cmd/main.go -data /path/to/data/directory -resume
+//
+//  6. Use a secured deployment with a private CA and a token kept in a file:
+//     go run cmd/main.go -data /path/to/data/directory -update_endpoint https://staging:8080/entities -query_endpoint https://staging:8081/v1/entities -ca_cert ca.pem -token_file token.txt
+//
+//  7. Print what a department was called before and is called today:
+//     go run cmd/main.go -lineage "Credit Information Bureau" -president "Ranil Wickremesinghe"
+//
+//  8. Print the org chart as it stood on a date:
+//     go run cmd/main.go -snapshot -date 2023-01-01 -format json
+//
+//  9. Write the changes made by a gazette as transaction files:
+//     go run cmd/main.go -diff 2023-10-22 -date 2023-10-23 -format csv -out review/
+//
+//  10. Draw one ministry from a saved snapshot:
+//     go run cmd/main.go -export dot -chart chart.json -minister "Minister of Finance" -people=false > finance.dot
+//
+//  11. Check what a gazette directory would do before loading it:
+//     go run cmd/main.go -data /path/to/data/directory -plan
+//
+//  12. Load the whole history of two presidents in date order:
+//     go run cmd/main.go -data data -history -init -president "Gotabaya Rajapaksa,Ranil Wickremesinghe"
+//
+//  13. Turn the load scripts into a manifest, then load it:
+//     go run cmd/main.go -generate_manifest load_gr_data.sh,load_rw_data.sh,load_ak_data.sh > load.yaml
+//     go run cmd/main.go -manifest load.yaml -cache
+//
 // Process Types:
 //   - organisation: Processes minister and department entities
 //   - person: Processes citizen entities
+//
+// Interrupting the process (Ctrl-C) lets the transaction in flight finish and then stops,
+// reporting the transaction it stopped on. A second interrupt exits immediately.
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/memgraph"
 )
 
 func main() {
@@ -60,6 +178,37 @@ func main() {
 	updateEndpoint := flag.String("update_endpoint", "http://localhost:8080/entities", "Endpoint for the Update API (default: http://localhost:8080/entities)")
 	queryEndpoint := flag.String("query_endpoint", "http://localhost:8081/v1/entities", "Endpoint for the Query API (default: http://localhost:8081/v1/entities)")
 	processType := flag.String("type", "organisation", "Type of data to process: 'organisation' or 'person' or 'document' (default: organisation)")
+	retryAttempts := flag.Int("retry_attempts", api.DefaultRetryPolicy().MaxAttempts, "Attempts per API call before giving up on transient failures, 1 disables retries")
+	useCache := flag.Bool("cache", false, "Cache government, president, entity and relation lookups during the load")
+	batch := flag.String("batch", "off", "Merge relationship writes into one update per entity: 'off', 'transaction' or 'file'")
+	journal := flag.Bool("journal", false, "Record the transactions applied in "+api.JournalFileName+" in the data directory")
+	resume := flag.Bool("resume", false, "Skip the transactions the journal records as applied and continue from the one a failed load stopped on")
+	dependencies := flag.String("dependencies", "warn", "What to do when a transaction needs an entity a later transaction adds: 'warn', 'reorder' or 'fail'")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout of each API request")
+	tokenFile := flag.String("token_file", "", "File holding a bearer token for the APIs (or set NEXOAN_BEARER_TOKEN)")
+	caCert := flag.String("ca_cert", os.Getenv("NEXOAN_CA_CERT"), "PEM file of the CA that signed the API servers' certificates (env NEXOAN_CA_CERT)")
+	clientCert := flag.String("client_cert", os.Getenv("NEXOAN_CLIENT_CERT"), "PEM client certificate for mutual TLS (env NEXOAN_CLIENT_CERT)")
+	clientKey := flag.String("client_key", os.Getenv("NEXOAN_CLIENT_KEY"), "PEM client key for mutual TLS (env NEXOAN_CLIENT_KEY)")
+	var headers headerFlags
+	flag.Var(&headers, "header", "Extra request header as 'Name: value'; may be repeated")
+	lineageName := flag.String("lineage", "", "Print the renames, merges and splits of the named minister or department instead of processing transactions (requires -president)")
+	snapshot := flag.Bool("snapshot", false, "Print the org chart on -date instead of processing transactions")
+	diffFrom := flag.String("diff", "", "Print the changes to the org chart between this date (YYYY-MM-DD) and -date instead of processing transactions")
+	presidentName := flag.String("president", "", "President whose minister or department -lineage names, the only president -snapshot and -diff show, or the presidents -history loads")
+	date := flag.String("date", "", "Date (YYYY-MM-DD) of the -snapshot or end of the -diff, or picking between ministers or departments of the same name for -lineage; empty means now or any date")
+	plan := flag.Bool("plan", false, "Print what loading -data would create and end, and which transactions would fail, without writing to the APIs")
+	history := flag.Bool("history", false, "Load every documents, orgchart and people folder under -data in the order of its history.yaml, then date and gazette order, limited to the comma-separated -president list if set")
+	manifestFile := flag.String("manifest", "", "Load the steps of this YAML or JSON manifest instead of -data")
+	generateManifest := flag.String("generate_manifest", "", "Print a manifest of the loads in these comma-separated load scripts instead of loading anything")
+	format := flag.String("format", "text", "Output format of -lineage, -snapshot, -diff and -plan: 'text' or 'json', or 'csv' for -diff")
+	outDir := flag.String("out", "", "Directory the CSV files of -diff -format csv are written to")
+	exportFormat := flag.String("export", "", "Draw the org chart on -date as 'dot', 'mermaid' or 'graphml' instead of processing transactions")
+	chartFile := flag.String("chart", "", "Org chart saved by -snapshot -format json that -export draws, or -plan starts from, without calling the APIs")
+	ministerName := flag.String("minister", "", "Only minister -export draws")
+	withDepartments := flag.Bool("departments", true, "Draw departments in -export")
+	withPeople := flag.Bool("people", true, "Draw appointed people in -export")
+	labelLength := flag.Int("label_length", 0, "Shorten names longer than this in -export; 0 keeps whole names")
+	groupByKind := flag.Bool("group_by_kind", false, "Group presidents, ministers, departments and people in -export")
 
 	// Custom usage message
 	flag.Usage = func() {
@@ -76,24 +225,258 @@ func main() {
 		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -init\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  4. Use custom API endpoints:\n")
 		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -update_endpoint http://custom:8080/entities -query_endpoint http://custom:8081/v1/entities\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  5. Keep a journal of a load, and continue it without applying its first transactions again if it fails part way:\n")
+		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -journal\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -resume\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  6. Use a secured deployment with a private CA and a token kept in a file:\n")
+		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -update_endpoint https://staging:8080/entities -query_endpoint https://staging:8081/v1/entities -ca_cert ca.pem -token_file token.txt\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  7. Print what a department was called before and is called today:\n")
+		fmt.Fprintf(os.Stderr, "     %s -lineage \"Credit Information Bureau\" -president \"Ranil Wickremesinghe\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  8. Print the org chart as it stood on a date:\n")
+		fmt.Fprintf(os.Stderr, "     %s -snapshot -date 2023-01-01 -format json\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  9. Write the changes made by a gazette as transaction files:\n")
+		fmt.Fprintf(os.Stderr, "     %s -diff 2023-10-22 -date 2023-10-23 -format csv -out review/\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  10. Draw one ministry from a saved snapshot:\n")
+		fmt.Fprintf(os.Stderr, "     %s -export dot -chart chart.json -minister \"Minister of Finance\" -people=false > finance.dot\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  11. Check what a gazette directory would do before loading it:\n")
+		fmt.Fprintf(os.Stderr, "     %s -data /path/to/data/directory -plan\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  12. Load the whole history of two presidents in date order:\n")
+		fmt.Fprintf(os.Stderr, "     %s -data data -history -init -president \"Gotabaya Rajapaksa,Ranil Wickremesinghe\"\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  13. Turn the load scripts into a manifest, then load it:\n")
+		fmt.Fprintf(os.Stderr, "     %s -generate_manifest load_gr_data.sh,load_rw_data.sh,load_ak_data.sh > load.yaml\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "     %s -manifest load.yaml -cache\n\n", os.Args[0])
 	}
 
 	flag.Parse()
 
+	// Print a manifest of the load scripts instead of loading anything
+	if *generateManifest != "" {
+		if err := printGeneratedManifest(strings.Split(*generateManifest, ","), *format); err != nil {
+			log.Fatalf("Failed to generate manifest: %v", err)
+		}
+		return
+	}
+
+	// Validate the lineage, snapshot, diff and export queries
+	queries := 0
+	for _, set := range []bool{*lineageName != "", *snapshot, *diffFrom != "", *exportFormat != ""} {
+		if set {
+			queries++
+		}
+	}
+	query := queries > 0
+	if queries > 1 {
+		fmt.Fprintf(os.Stderr, "Error: -lineage, -snapshot, -diff and -export cannot be combined\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *plan && query {
+		fmt.Fprintf(os.Stderr, "Error: -plan cannot be combined with -lineage, -snapshot, -diff or -export\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *history && (*plan || query) {
+		fmt.Fprintf(os.Stderr, "Error: -history cannot be combined with -plan, -lineage, -snapshot, -diff or -export\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *manifestFile != "" && (*history || *plan || query) {
+		fmt.Fprintf(os.Stderr, "Error: -manifest cannot be combined with -history, -plan, -lineage, -snapshot, -diff or -export\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *plan && *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json' with -plan\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *lineageName != "" && *presidentName == "" {
+		fmt.Fprintf(os.Stderr, "Error: -president is required with -lineage\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if query && *format != "text" && *format != "json" && (*diffFrom == "" || *format != "csv") {
+		fmt.Fprintf(os.Stderr, "Error: Invalid format. Must be 'text' or 'json', or 'csv' with -diff\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *exportFormat != "" && *exportFormat != api.ExportDOT && *exportFormat != api.ExportMermaid && *exportFormat != api.ExportGraphML {
+		fmt.Fprintf(os.Stderr, "Error: Invalid export format. Must be 'dot', 'mermaid' or 'graphml'\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *format == "csv" && *outDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: -out is required with -format csv\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	dateISO, err := parseDate(*date)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+	diffFromISO, err := parseDate(*diffFrom)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	// Validate data directory
-	if *dataDir == "" {
+	if !query && *manifestFile == "" && *dataDir == "" {
 		fmt.Fprintf(os.Stderr, "Error: Data directory path is required\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// Validate process type
-	if *processType != "organisation" && *processType != "person" && *processType != "document" {
-		fmt.Fprintf(os.Stderr, "Error: Invalid process type. Must be 'organisation' or 'person' or 'document'\n\n")
+	// Validate process type against the registered transaction handlers
+	handlers := api.BuiltinHandlers()
+	if len(handlers.Kinds(*processType)) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: Invalid process type. Must be one of '%s'\n\n", strings.Join(handlers.ProcessTypes(), "', '"))
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	// Validate batch scope
+	batchScopes := map[string]api.BatchScope{
+		"off":         api.BatchOff,
+		"transaction": api.BatchPerTransaction,
+		"file":        api.BatchPerFile,
+	}
+	batchScope, ok := batchScopes[*batch]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: Invalid batch scope. Must be 'off', 'transaction' or 'file'\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Validate dependency policy
+	dependencyPolicy, err := api.ParseDependencyPolicy(*dependencies)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Invalid dependency policy. Must be 'warn', 'reorder' or 'fail'\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Create API client with configurable endpoints, authentication and transport
+	options := []api.Option{api.WithTimeout(*timeout), api.WithHandlers(handlers)}
+	for _, header := range headers {
+		options = append(options, api.WithHeader(header[0], header[1]))
+	}
+	token, err := readBearerToken(*tokenFile)
+	if err != nil {
+		log.Fatalf("Failed to read bearer token: %v", err)
+	}
+	if token != "" {
+		options = append(options, api.WithBearerToken(token))
+	}
+	tlsConfig, err := loadTLSConfig(*caCert, *clientCert, *clientKey)
+	if err != nil {
+		log.Fatalf("Failed to load TLS configuration: %v", err)
+	}
+	if tlsConfig != nil {
+		options = append(options, api.WithTLSConfig(tlsConfig))
+	}
+	client, err := api.NewClient(*updateEndpoint, *queryEndpoint, options...)
+	if err != nil {
+		log.Fatalf("Failed to create API client: %v", err)
+	}
+	retryPolicy := api.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *retryAttempts
+	client.SetRetryPolicy(retryPolicy)
+	client.SetCacheEnabled(*useCache)
+	if err := client.SetBatchScope(batchScope); err != nil {
+		log.Fatalf("Failed to set batch scope: %v", err)
+	}
+	switch {
+	case *resume:
+		client.SetJournalMode(api.JournalResume)
+	case *journal:
+		client.SetJournalMode(api.JournalRecord)
+	}
+	client.SetDependencyPolicy(dependencyPolicy)
+
+	// Stop between transactions on the first interrupt; restore default handling so a second one exits
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "Interrupt received, stopping after the current transaction...")
+		cancel()
+	}()
+
+	// Print the lineage instead of processing transactions
+	if *lineageName != "" {
+		if err := printLineage(ctx, client, *presidentName, *lineageName, dateISO, *format); err != nil {
+			log.Fatalf("Failed to get lineage: %v", err)
+		}
+		return
+	}
+
+	// Print the org chart instead of processing transactions
+	if *snapshot {
+		if err := printSnapshot(ctx, client, *presidentName, dateISO, *format); err != nil {
+			log.Fatalf("Failed to build org chart: %v", err)
+		}
+		return
+	}
+
+	// Draw the org chart instead of processing transactions
+	if *exportFormat != "" {
+		exportOptions := &api.ExportOptions{
+			Format:          *exportFormat,
+			President:       *presidentName,
+			Minister:        *ministerName,
+			SkipDepartments: !*withDepartments,
+			SkipPeople:      !*withPeople,
+			MaxLabelLength:  *labelLength,
+			GroupByKind:     *groupByKind,
+		}
+		if err := printExport(ctx, client, *chartFile, dateISO, exportOptions); err != nil {
+			log.Fatalf("Failed to export org chart: %v", err)
+		}
+		return
+	}
+
+	// Print the changes between two dates instead of processing transactions
+	if *diffFrom != "" {
+		if err := printDiff(ctx, client, *presidentName, diffFromISO, dateISO, *format, *outDir); err != nil {
+			log.Fatalf("Failed to compare org charts: %v", err)
+		}
+		return
+	}
+
+	// Load the steps of a manifest instead of -data
+	if *manifestFile != "" {
+		manifest, err := api.ReadManifest(*manifestFile)
+		if err != nil {
+			log.Fatalf("Failed to read manifest: %v", err)
+		}
+		if *initDB && len(manifest.Steps) > 0 {
+			manifest.Steps[0].Init = true
+		}
+		result, err := client.RunManifestContext(ctx, manifest)
+		if writeErr := result.WriteText(os.Stdout); writeErr != nil {
+			log.Fatalf("Failed to print summary: %v", writeErr)
+		}
+		if *useCache {
+			printCacheStats(client)
+		}
+		if err != nil {
+			log.Fatalf("Failed to run manifest: %v", err)
+		}
+		if failed := result.Count(api.StepFailed); failed > 0 {
+			fmt.Printf("Finished with %d failed steps that continue on error\n", failed)
+			return
+		}
+		fmt.Println("Successfully processed all transactions")
+		return
+	}
+
 	// Ensure the data directory exists
 	if _, err := os.Stat(*dataDir); os.IsNotExist(err) {
 		log.Fatalf("Data directory does not exist: %s", *dataDir)
@@ -105,13 +488,36 @@ func main() {
 		log.Fatalf("Failed to get absolute path: %v", err)
 	}
 
-	// Create API client with configurable endpoints
-	client := api.NewClient(*updateEndpoint, *queryEndpoint)
+	// Plan the load against an in-memory copy of the graph instead of processing transactions
+	if *plan {
+		if err := printPlan(ctx, client, handlers, absDataDir, *processType, *chartFile, *initDB, *format); err != nil {
+			log.Fatalf("Failed to plan transactions: %v", err)
+		}
+		return
+	}
+
+	// Find the folders of the history before writing anything
+	var historySteps []api.HistoryStep
+	if *history {
+		var presidents []string
+		for _, president := range strings.Split(*presidentName, ",") {
+			if president = strings.TrimSpace(president); president != "" {
+				presidents = append(presidents, president)
+			}
+		}
+		historySteps, err = client.FindHistory(absDataDir, presidents)
+		if err != nil {
+			log.Fatalf("Failed to find the folders to load: %v", err)
+		}
+		if len(historySteps) == 0 {
+			log.Fatalf("No folders with transaction files found under %s", absDataDir)
+		}
+	}
 
 	// Initialize database if requested
 	if *initDB {
 		fmt.Println("Initializing database with government node...")
-		government, err := client.CreateGovernmentNode()
+		government, err := client.CreateGovernmentNodeContext(ctx)
 		if err != nil {
 			log.Fatalf("Failed to create government node: %v", err)
 		}
@@ -119,11 +525,24 @@ func main() {
 	}
 
 	// Process transactions
-	fmt.Printf("Processing %s transactions from directory: %s\n", *processType, absDataDir)
-	if *processType == "document" {
-		err = client.ProcessDocumentTransactions(absDataDir, *processType)
-	} else {
-		err = client.ProcessTransactions(absDataDir, *processType)
+	var summary *api.HistorySummary
+	switch {
+	case *history:
+		fmt.Printf("Loading %d folders from: %s\n", len(historySteps), absDataDir)
+		summary, err = client.LoadHistoryContext(ctx, historySteps)
+	default:
+		fmt.Printf("Processing %s transactions from directory: %s\n", *processType, absDataDir)
+		err = client.ProcessTransactionsContext(ctx, absDataDir, *processType)
+	}
+
+	if summary != nil {
+		if writeErr := summary.WriteText(os.Stdout); writeErr != nil {
+			log.Fatalf("Failed to print summary: %v", writeErr)
+		}
+	}
+
+	if *useCache {
+		printCacheStats(client)
 	}
 
 	if err != nil {
@@ -132,3 +551,259 @@ func main() {
 
 	fmt.Println("Successfully processed all transactions")
 }
+
+// printLineage prints the lineage of the named minister or department under presidentName to stdout
+func printLineage(ctx context.Context, client *api.Client, presidentName, name, dateISO, format string) error {
+	lineage, err := client.GetLineageByNameContext(ctx, presidentName, name, dateISO, nil)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return printJSON(lineage)
+	}
+	return lineage.WriteText(os.Stdout)
+}
+
+// printSnapshot prints the org chart on dateISO to stdout, limited to presidentName when it is set
+func printSnapshot(ctx context.Context, client *api.Client, presidentName, dateISO, format string) error {
+	chart, err := client.GetOrgChartContext(ctx, dateISO, &api.SnapshotOptions{President: presidentName})
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		return printJSON(chart)
+	}
+	return chart.WriteText(os.Stdout)
+}
+
+// printDiff prints the changes to the org chart between fromISO and toISO, limited to presidentName
+// when it is set. The csv format writes one transaction file per type of change into outDir.
+func printDiff(ctx context.Context, client *api.Client, presidentName, fromISO, toISO, format, outDir string) error {
+	diff, err := client.DiffOrgChartContext(ctx, fromISO, toISO, &api.SnapshotOptions{President: presidentName})
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "json":
+		return printJSON(diff)
+	case "csv":
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		for _, changeType := range diff.ChangeTypes() {
+			path := filepath.Join(outDir, "diff_"+changeType+".csv")
+			file, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", path, err)
+			}
+			err = diff.WriteCSV(file, changeType, "diff")
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			fmt.Printf("Wrote %s\n", path)
+		}
+		return nil
+	}
+	return diff.WriteText(os.Stdout)
+}
+
+// printExport draws the org chart saved in chartFile, or the one on dateISO when chartFile is empty, to stdout
+func printExport(ctx context.Context, client *api.Client, chartFile, dateISO string, opts *api.ExportOptions) error {
+	var chart *api.OrgChart
+	if chartFile != "" {
+		var err error
+		chart, err = readChart(chartFile)
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		chart, err = client.GetOrgChartContext(ctx, dateISO, &api.SnapshotOptions{
+			President:       opts.President,
+			SkipDepartments: opts.SkipDepartments,
+			SkipPeople:      opts.SkipPeople,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return api.ExportOrgChart(os.Stdout, chart, opts)
+}
+
+// printPlan applies the transactions of dataDir to an in-memory copy of the graph and prints what
+// the load would do. The copy starts from the graph of live, from chartFile when it is set, or from
+// just the government node when empty is set. Transactions that would fail make it return an error
+// after printing.
+func printPlan(ctx context.Context, live *api.Client, handlers *api.HandlerRegistry, dataDir, processType, chartFile string, empty bool, format string) error {
+	// The copy is reached in-process, without a server. Progress goes to stderr so that the plan can
+	// be redirected on its own.
+	sandbox := memgraph.New()
+	planner, err := api.NewClient(memgraph.UpdateURL, memgraph.QueryURL,
+		api.WithHTTPClient(&http.Client{Transport: sandbox.Transport()}),
+		api.WithHandlers(handlers), api.WithLogger(log.New(os.Stderr, "", 0)))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case empty:
+		if _, err := planner.CreateGovernmentNodeContext(ctx); err != nil {
+			return err
+		}
+	case chartFile != "":
+		chart, err := readChart(chartFile)
+		if err != nil {
+			return err
+		}
+		if err := planner.SeedFromOrgChartContext(ctx, chart); err != nil {
+			return err
+		}
+	default:
+		if err := planner.SeedFromClientContext(ctx, live); err != nil {
+			return err
+		}
+	}
+
+	loadPlan, err := planner.PlanTransactionsContext(ctx, dataDir, processType)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		err = printJSON(loadPlan)
+	} else {
+		err = loadPlan.WriteText(os.Stdout)
+	}
+	if err != nil {
+		return err
+	}
+	if len(loadPlan.Failures) > 0 {
+		return fmt.Errorf("%d of %d transactions would fail", len(loadPlan.Failures), loadPlan.Transactions)
+	}
+	return nil
+}
+
+// printGeneratedManifest prints a manifest of the loads of the given scripts, one after another,
+// as YAML or, with the json format, as JSON
+func printGeneratedManifest(scripts []string, format string) error {
+	manifest := &api.Manifest{Steps: []api.ManifestStep{}}
+	for _, script := range scripts {
+		file, err := os.Open(strings.TrimSpace(script))
+		if err != nil {
+			return err
+		}
+		parsed, err := api.ParseLoadScript(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", script, err)
+		}
+		manifest.Steps = append(manifest.Steps, parsed.Steps...)
+	}
+	if format == "json" {
+		return printJSON(manifest)
+	}
+	return manifest.WriteYAML(os.Stdout)
+}
+
+// printCacheStats prints the lookup cache statistics of the load
+func printCacheStats(client *api.Client) {
+	stats := client.CacheStats()
+	fmt.Printf("Lookup cache: %d hits, %d misses, %d invalidations\n", stats.Hits, stats.Misses, stats.Invalidations)
+}
+
+// readChart reads an org chart saved by -snapshot -format json
+func readChart(path string) (*api.OrgChart, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return api.ReadOrgChart(file)
+}
+
+// parseDate converts a YYYY-MM-DD flag value to RFC3339; an empty value stays empty
+func parseDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q: must be YYYY-MM-DD", value)
+	}
+	return parsed.Format(time.RFC3339), nil
+}
+
+// printJSON prints value to stdout as indented JSON
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// headerFlags collects repeated -header flags as name/value pairs
+type headerFlags [][2]string
+
+func (h *headerFlags) String() string {
+	return fmt.Sprint(*h)
+}
+
+func (h *headerFlags) Set(value string) error {
+	name, headerValue, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("header must look like 'Name: value', got %q", value)
+	}
+	*h = append(*h, [2]string{strings.TrimSpace(name), strings.TrimSpace(headerValue)})
+	return nil
+}
+
+// readBearerToken reads the token from tokenFile, falling back to NEXOAN_BEARER_TOKEN,
+// so the token never has to appear on the command line
+func readBearerToken(tokenFile string) (string, error) {
+	if tokenFile == "" {
+		return strings.TrimSpace(os.Getenv("NEXOAN_BEARER_TOKEN")), nil
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// loadTLSConfig builds a TLS configuration from a CA file and a client certificate and key.
+// It returns nil when none are given.
+func loadTLSConfig(caCert, clientCert, clientKey string) (*tls.Config, error) {
+	if caCert == "" && clientCert == "" && clientKey == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if clientCert != "" || clientKey != "" {
+		if clientCert == "" || clientKey == "" {
+			return nil, fmt.Errorf("both -client_cert and -client_key are required for mutual TLS")
+		}
+		certificate, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
@@ -1,142 +1,142 @@
 # Load Gota's people and presidency gazettes
-./orgchart -data "$(pwd)/data/documents/Gotabaya Rajapaksa/person/" -init -type document
+./orgchart -cache -data "$(pwd)/data/documents/Gotabaya Rajapaksa/person/" -init -type document
 
 # Load Gota's org gazettes
-./orgchart -data "$(pwd)/data/documents/Gotabaya Rajapaksa/organisation/" -type document
+./orgchart -cache -data "$(pwd)/data/documents/Gotabaya Rajapaksa/organisation/" -type document
 
 # Load Gota's presidency data
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-17/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-21/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-17/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-21/" -type person
 
 # Load Gota's org data
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-11-27/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-11-27/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-27/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-11-27/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-10/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-10/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-21/"
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-12-21/" -type person
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-21/"
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2019-12-21/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-31/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-09/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-13/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2019-12-31/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-09/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-13/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-22/2159_15/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-22/2159_21/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-22/2159_15/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-22/2159_21/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-24/2159_47/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-24/2159_48/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-24/2159_47/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-01-24/2159_48/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-02-01/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-02-01/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-02-07/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-02-07/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-03-17/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-04-08/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-03-17/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-04-08/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-06-18/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-06-18/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-08-09/"
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-42-1/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-42-2/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-43/" -type person
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-08-09/"
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-42-1/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-42-2/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-08-13/2188-43/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-09-25/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-09-25/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-10-06/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-10-06/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-10-06/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-10-06/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-11-20/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-11-20/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-11-26/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-11-26/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-12-04/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-12-04/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-12-04/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2020-12-04/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-12-11/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-02-16/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2020-12-11/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-02-16/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-02-18/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-02-18/" -type person
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-02-23/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-02-23/" -type person
 
 # checked till here
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-05-03/"
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-05-07/" -type person
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-05-17/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-05-03/"
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-05-07/" -type person
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-05-17/"
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-06-03/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-06-03/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-06-09/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-06-09/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-06-18/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-07/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-08/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-06-18/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-07/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-08/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-07-16/2236-56/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-07-16/2236-57/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-07-16/2236-56/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-07-16/2236-57/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-29/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-08-16/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-07-29/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-08-16/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-09-09/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2021-09-09/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-10-06/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-11-17/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-12-02/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-01-09/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-10-06/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-11-17/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2021-12-02/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-01-09/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-02-07/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-02-07/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-02-23/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-02-23/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-07/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-07/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-03-08/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-03-08/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-08/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-09/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-08/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-09/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-03-14/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-03-14/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-15/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-03-15/" -type person
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-07/2274-25/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-07/2274-26/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-07/2274-25/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-07/2274-26/" -type person
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-42/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-60/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-61/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-62/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-63/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-64/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-42/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-60/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-61/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-62/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-63/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-64/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-04-28/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-04-28/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-63/2276-63-2/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-64/2276-64-2/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-63/2276-63-2/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-04-22/2276-64/2276-64-2/" -type person
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-04/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-09/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-12/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-04/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-09/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-12/" -type person
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-14/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-24/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-26/2281-31/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-14/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-24/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-26/2281-31/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-05-27/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-05-27/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-24/2281-09-02/" -type person
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-26/2281-32/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-24/2281-09-02/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-05-26/2281-32/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-06-09/"
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-06-27/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-06-09/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-06-27/"
 
-./orgchart -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-06-27/" -type person
+./orgchart -cache -data "$(pwd)/data/people/Gotabaya Rajapaksa/2022-06-27/" -type person
 
-./orgchart -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-07-07/"
+./orgchart -cache -data "$(pwd)/data/orgchart/Gotabaya Rajapaksa/2022-07-07/"
 
@@ -17,6 +17,9 @@ type Entity struct {
 	Metadata      []MetadataEntry     `json:"metadata,omitempty"`
 	Attributes    []AttributeEntry    `json:"attributes,omitempty"`
 	Relationships []RelationshipEntry `json:"relationships,omitempty"`
+	// NameHistory lists every name the entity has had, oldest first and ending with Name. It is filled
+	// in by Client.GetEntity from RENAMED_TO relationships and never sent to the Update API.
+	NameHistory []TimeBasedValue `json:"-"`
 }
 
 // Kind represents the entity kind structure
@@ -4,7 +4,6 @@ import (
 	"encoding/csv"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
@@ -121,7 +120,7 @@ func createDocumentRelationship(client *api.Client, link DocumentLink) error {
 	}
 
 	// Create the relationship
-	err = createRelationship(client, parentEntity.ID, childEntity.ID, link.Relationship, dateISO)
+	err = createRelationship(client, link.StartDate, parentEntity.ID, childEntity.ID, link.Relationship, dateISO)
 	if err != nil {
 		return fmt.Errorf("failed to create relationship: %w", err)
 	}
@@ -173,11 +172,10 @@ func findDocumentByName(client *api.Client, documentName string) (*models.Entity
 	return entity, nil
 }
 
-// createRelationship creates a relationship between two entities
-func createRelationship(client *api.Client, parentID, childID, relationshipType, startTime string) error {
-	// Generate unique relationship ID with random number- rand package automatically seeds with a random number
-	randomNum := rand.Intn(999999)
-	uniqueRelationshipID := fmt.Sprintf("%s_%s_%06d", parentID, childID, randomNum)
+// createRelationship creates a relationship between two entities. The relationship ID is derived
+// from the link, keyed by its start date, so linking the same CSV again rewrites the same relationships.
+func createRelationship(client *api.Client, key, parentID, childID, relationshipType, startTime string) error {
+	uniqueRelationshipID := api.RelationshipID(key, relationshipType, parentID, childID)
 
 	// Create the relationship entity update
 	parentEntity := &models.Entity{
@@ -254,11 +252,14 @@ func main() {
 	queryURL := "http://localhost:8081/v1/entities"
 
 	// Create API client
-	client := api.NewClient(updateURL, queryURL)
+	client, err := api.NewClient(updateURL, queryURL)
+	if err != nil {
+		log.Fatalf("Failed to create API client: %v", err)
+	}
 
 	// Test connection and wait for API to be ready
 	fmt.Println("Testing API connection...")
-	err := testAPIConnection(client)
+	err = testAPIConnection(client)
 	if err != nil {
 		log.Fatalf("Failed to connect to API: %v", err)
 	}
@@ -0,0 +1,200 @@
+package tests
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
+	"orgchart_nexoan/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfilledGazettesResolveByDate(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Archives", "minister", "9010-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Archives", "Department of Records", "department", "9010-01_tr_02", map[string]int{"department": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Heritage", "minister", "9010-01_tr_03", map[string]int{"minister": 1})
+
+	// The newer gazette is loaded first
+	_, err := isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Archives",
+		"new":            "Minister of Archives and Records",
+		"type":           "minister",
+		"date":           "2024-01-01",
+		"transaction_id": "9010-02_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 2})
+	require.NoError(t, err)
+
+	// Lookups resolve against the date, not against what is active now
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Archives", "")
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	archives, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Archives", "2022-01-01T00:00:00Z")
+	require.NoError(t, err)
+	renamed, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Archives and Records", "2024-06-01T00:00:00Z")
+	require.NoError(t, err)
+	heritage, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Heritage", "2022-01-01T00:00:00Z")
+	require.NoError(t, err)
+
+	// Back-fill an appointment and a department move from an older gazette
+	_, err = isolated.AddPersonEntity(map[string]interface{}{
+		"parent":         "Minister of Archives",
+		"child":          "Backfilled Person",
+		"date":           "2022-01-01",
+		"parent_type":    "minister",
+		"child_type":     "citizen",
+		"rel_type":       "AS_APPOINTED",
+		"transaction_id": "9010-03_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"citizen": 0})
+	require.NoError(t, err)
+	require.NoError(t, isolated.MoveDepartment(map[string]interface{}{
+		"old_parent":         "Minister of Archives",
+		"new_parent":         "Minister of Heritage",
+		"child":              "Department of Records",
+		"type":               "department",
+		"date":               "2022-06-01",
+		"old_president_name": "Ranil Wickremesinghe",
+		"new_president_name": "Ranil Wickremesinghe",
+	}))
+
+	departments, err := isolated.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "department"},
+		Name: "Department of Records",
+	})
+	require.NoError(t, err)
+	require.Len(t, departments, 1)
+	heldBy := func(date string) []string {
+		relations, err := isolated.GetAllRelatedEntities(departments[0].ID, &api.RelationFilter{
+			Direction: api.DirectionIncoming,
+			Names:     []string{"AS_DEPARTMENT"},
+			ActiveAt:  date,
+		})
+		require.NoError(t, err)
+		ids := []string{}
+		for _, rel := range relations {
+			ids = append(ids, rel.RelatedEntityID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{archives.ID}, heldBy("2021-01-01T00:00:00Z"))
+	assert.Equal(t, []string{heritage.ID}, heldBy("2023-01-01T00:00:00Z"))
+	// The move already loaded from the newer gazette still stands
+	assert.Equal(t, []string{renamed.ID}, heldBy("2024-06-01T00:00:00Z"))
+
+	// Terminating the back-filled appointment ends it at its own date
+	require.NoError(t, isolated.TerminatePersonEntity(map[string]interface{}{
+		"parent":      "Minister of Archives",
+		"child":       "Backfilled Person",
+		"date":        "2023-01-01",
+		"parent_type": "minister",
+		"child_type":  "citizen",
+		"rel_type":    "AS_APPOINTED",
+		"president":   "Ranil Wickremesinghe",
+	}))
+	appointments, err := isolated.GetRelatedEntities(archives.ID, &models.Relationship{Name: "AS_APPOINTED"})
+	require.NoError(t, err)
+	require.Len(t, appointments, 1)
+	assert.Equal(t, "2023-01-01T00:00:00Z", appointments[0].EndTime)
+}
+
+func TestGazettesNamingMinistersByTheirLaterName(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Yesterday", "minister", "9010-10_tr_01", map[string]int{"minister": 0})
+	yesterday, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Yesterday", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	_, err = isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Yesterday",
+		"new":            "Minister of Tomorrow",
+		"type":           "minister",
+		"date":           "2021-01-01",
+		"transaction_id": "9010-11_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 1})
+	require.NoError(t, err)
+	tomorrow, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Tomorrow", "2021-01-01T00:00:00Z")
+	require.NoError(t, err)
+
+	// Nothing stands in for a minister before it starts
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Tomorrow", "2020-06-01T00:00:00Z")
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+
+	// An appointment naming the minister by its later name goes to the minister it was renamed from,
+	// and continues on the renamed one as the rename would have carried it over
+	appoint := func(parent, child, date, transactionID string) error {
+		_, err := isolated.AddPersonEntity(map[string]interface{}{
+			"parent":         parent,
+			"child":          child,
+			"date":           date,
+			"parent_type":    "minister",
+			"child_type":     "citizen",
+			"rel_type":       "AS_APPOINTED",
+			"transaction_id": transactionID,
+			"president":      "Ranil Wickremesinghe",
+		}, map[string]int{"citizen": 0})
+		return err
+	}
+	require.NoError(t, appoint("Minister of Tomorrow", "Early Appointee", "2020-06-01", "9010-12_tr_01"))
+	appointees := func(ministerID, date string) []string {
+		return relatedIDs(t, isolated, ministerID, api.DirectionOutgoing, "AS_APPOINTED", date)
+	}
+	appointee := appointees(yesterday.ID, "2020-06-01T00:00:00Z")
+	require.Len(t, appointee, 1)
+	assert.Empty(t, appointees(yesterday.ID, "2021-06-01T00:00:00Z"))
+	assert.Equal(t, appointee, appointees(tomorrow.ID, "2021-06-01T00:00:00Z"))
+
+	// Terminating the appointment before the rename ends the relationship it was carried over to as well
+	require.NoError(t, isolated.TerminatePersonEntity(map[string]interface{}{
+		"parent":         "Minister of Tomorrow",
+		"child":          "Early Appointee",
+		"date":           "2020-09-01",
+		"parent_type":    "minister",
+		"child_type":     "citizen",
+		"rel_type":       "AS_APPOINTED",
+		"transaction_id": "9010-12_tr_02",
+		"president":      "Ranil Wickremesinghe",
+	}))
+	assert.Equal(t, appointee, appointees(yesterday.ID, "2020-06-01T00:00:00Z"))
+	assert.Empty(t, appointees(yesterday.ID, "2020-09-01T00:00:00Z"))
+	assert.Empty(t, appointees(tomorrow.ID, "2021-06-01T00:00:00Z"))
+
+	// A minister with no earlier name has nothing to resolve to before it starts
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Later", "minister", "9010-13_tr_01", map[string]int{"minister": 2})
+	assert.ErrorIs(t, appoint("Minister of Later", "Too Early Appointee", "2019-06-01", "9010-14_tr_01"), api.ErrEntityNotFound)
+}
+
+// TestLoadShippedData loads the data folders of load_gr_data.sh in the order the script does
+func TestLoadShippedData(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loading the shipped data takes several seconds")
+	}
+	server := apitest.NewServer()
+	t.Cleanup(server.Close)
+	client := newClient(t, server.UpdateURL(), server.QueryURL(), api.WithLogger(log.New(io.Discard, "", 0)))
+	client.SetCacheEnabled(true)
+
+	script, err := os.Open("../load_gr_data.sh")
+	require.NoError(t, err)
+	defer script.Close()
+	manifest, err := api.ParseLoadScript(script)
+	require.NoError(t, err)
+	manifest.Dir = ".."
+
+	result, err := client.RunManifest(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, len(manifest.Steps), result.Count(api.StepLoaded))
+	assert.Equal(t, 953, server.EntityCount())
+
+	// Loading the folders again, as after an interrupted load, writes nothing new
+	relationships := server.RelationshipCount()
+	result, err = client.RunManifest(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, len(manifest.Steps), result.Count(api.StepLoaded))
+	assert.Equal(t, 953, server.EntityCount())
+	assert.Equal(t, relationships, server.RelationshipCount())
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"net/http"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchPerFileMergesRelationshipWrites(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	require.NoError(t, isolated.SetBatchScope(api.BatchPerFile))
+	updatesBefore := server.RequestCount(http.MethodPut, "/entities/")
+	relationshipsBefore := server.RelationshipCount()
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9006-01_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9006-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Batching,minister,AS_MINISTER,2020-01-01\n" +
+			"9006-01_tr_02,Minister of Batching,minister,Department of Batch One,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9006-01_tr_03,Minister of Batching,minister,Department of Batch Two,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9006-01_tr_04,Minister of Batching,minister,Department of Batch Three,department,AS_DEPARTMENT,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	// The AS_MINISTER write and the three AS_DEPARTMENT writes go out at the end of the file,
+	// one update for the president and one for the minister
+	assert.Equal(t, updatesBefore+2, server.RequestCount(http.MethodPut, "/entities/"))
+	assert.Equal(t, relationshipsBefore+4, server.RelationshipCount())
+
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Batching", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	departments, err := isolated.GetRelatedEntities(minister.ID, &models.Relationship{Name: "AS_DEPARTMENT"})
+	require.NoError(t, err)
+	assert.Len(t, departments, 3)
+}
+
+func TestBatchPerFileWithInterleavedFiles(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	require.NoError(t, isolated.SetBatchScope(api.BatchPerFile))
+	updatesBefore := server.RequestCount(http.MethodPut, "/entities/")
+	relationshipsBefore := server.RelationshipCount()
+
+	// Sorted by date the rows of the two files alternate
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9006-02_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9006-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Interleaving,minister,AS_MINISTER,2020-01-01\n" +
+			"9006-02_tr_02,Minister of Interleaving,minister,Department of Odd Days,department,AS_DEPARTMENT,2020-01-03\n" +
+			"9006-02_tr_03,Minister of Interleaving,minister,Department of Odd Weeks,department,AS_DEPARTMENT,2020-01-05\n",
+		"9006-03_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9006-03_tr_01,Minister of Interleaving,minister,Department of Even Days,department,AS_DEPARTMENT,2020-01-02\n" +
+			"9006-03_tr_02,Minister of Interleaving,minister,Department of Even Weeks,department,AS_DEPARTMENT,2020-01-04\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	// The first batch goes out after the last row of 9006-03, on 2020-01-04, with an update for the
+	// president and one for the minister; the second, at the end of 9006-02, updates the minister
+	assert.Equal(t, updatesBefore+3, server.RequestCount(http.MethodPut, "/entities/"))
+	assert.Equal(t, relationshipsBefore+5, server.RelationshipCount())
+}
+
+func TestBatchReadsSeePendingWrites(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	require.NoError(t, isolated.SetBatchScope(api.BatchPerTransaction))
+	relationshipsBefore := server.RelationshipCount()
+
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Pending Writes", "minister", "9006-02_tr_01", map[string]int{"minister": 0})
+	assert.Equal(t, relationshipsBefore, server.RelationshipCount(), "the relationship write is held back")
+
+	// The lookup goes through the president's pending AS_MINISTER relationship without flushing it
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Pending Writes", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, relationshipsBefore, server.RelationshipCount())
+	incoming, err := isolated.GetRelatedEntities(minister.ID, &models.Relationship{Name: "AS_MINISTER", Direction: "INCOMING"})
+	require.NoError(t, err)
+	require.Len(t, incoming, 1)
+	assert.Equal(t, "2020-01-01T00:00:00Z", incoming[0].StartTime)
+
+	require.NoError(t, isolated.FlushRelationships())
+	assert.Equal(t, relationshipsBefore+1, server.RelationshipCount())
+}
+
+func TestBatchReportsErrorsPerRelationship(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Failures", "minister", "9006-03_tr_01", map[string]int{"minister": 0})
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Failures", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	presidentRelations, err := isolated.GetRelatedEntities("gov_01", &models.Relationship{Name: "AS_PRESIDENT"})
+	require.NoError(t, err)
+	require.Len(t, presidentRelations, 1)
+	relationshipsBefore := server.RelationshipCount()
+
+	require.NoError(t, isolated.SetBatchScope(api.BatchPerTransaction))
+	valid := models.RelationshipEntry{
+		Key: "9006-03_valid",
+		Value: models.Relationship{
+			RelatedEntityID: "gov_01",
+			StartTime:       "2020-01-01T00:00:00Z",
+			ID:              "9006-03_valid",
+			Name:            "REPORTS_TO",
+		},
+	}
+	// The AS_PRESIDENT relationship belongs to the government node, so the minister cannot end it
+	invalid := models.RelationshipEntry{
+		Key: presidentRelations[0].ID,
+		Value: models.Relationship{
+			EndTime: "2021-01-01T00:00:00Z",
+			ID:      presidentRelations[0].ID,
+		},
+	}
+	_, err = isolated.UpdateEntity(minister.ID, &models.Entity{ID: minister.ID, Relationships: []models.RelationshipEntry{valid}})
+	require.NoError(t, err)
+	_, err = isolated.UpdateEntity(minister.ID, &models.Entity{ID: minister.ID, Relationships: []models.RelationshipEntry{invalid}})
+	require.NoError(t, err)
+
+	err = isolated.FlushRelationships()
+	var batchErr *api.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Len(t, batchErr.Failures, 1)
+	assert.Equal(t, minister.ID, batchErr.Failures[0].EntityID)
+	assert.Equal(t, presidentRelations[0].ID, batchErr.Failures[0].RelationshipID)
+	assert.True(t, api.IsHTTPStatus(err, http.StatusBadRequest))
+
+	// The valid relationship was written exactly once
+	assert.Equal(t, relationshipsBefore+1, server.RelationshipCount())
+}
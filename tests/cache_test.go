@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"net/http"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func addCacheEntity(t *testing.T, client *api.Client, parent, child, childType, txID string, counters map[string]int) {
+	t.Helper()
+	transaction := map[string]interface{}{
+		"parent":         parent,
+		"child":          child,
+		"date":           "2020-01-01",
+		"parent_type":    "citizen",
+		"child_type":     childType,
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": txID,
+		"president":      "Ranil Wickremesinghe",
+	}
+	if childType == "department" {
+		transaction["parent_type"] = "minister"
+		transaction["rel_type"] = "AS_DEPARTMENT"
+	}
+	_, err := client.AddOrgEntity(transaction, counters)
+	require.NoError(t, err)
+}
+
+func TestCacheAvoidsRepeatedLookups(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	counters := map[string]int{"minister": 0, "department": 0}
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Caching", "minister", "9005-01_tr_01", counters)
+
+	queriesBefore := server.RequestCount(http.MethodPost, "/v1/entities/")
+	_, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Caching", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	uncachedQueries := server.RequestCount(http.MethodPost, "/v1/entities/") - queriesBefore
+
+	isolated.SetCacheEnabled(true)
+	for i := 0; i < 2; i++ {
+		_, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Caching", "2020-01-01T00:00:00Z")
+		require.NoError(t, err)
+	}
+
+	// The first cached call fills the cache, the second is answered from it
+	queriesBefore = server.RequestCount(http.MethodPost, "/v1/entities/")
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Caching", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, 0, server.RequestCount(http.MethodPost, "/v1/entities/")-queriesBefore)
+	assert.Greater(t, uncachedQueries, 0)
+
+	stats := isolated.CacheStats()
+	assert.Greater(t, stats.Hits, 0)
+	assert.Greater(t, stats.Misses, 0)
+
+	isolated.SetCacheEnabled(false)
+	assert.Equal(t, api.CacheStats{}, isolated.CacheStats())
+}
+
+func TestCacheInvalidatedByWrites(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	isolated.SetCacheEnabled(true)
+	counters := map[string]int{"minister": 0, "department": 0}
+
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Caching", "minister", "9005-02_tr_01", counters)
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Caching", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+
+	// A department added afterwards shows up in the minister's cached relation list
+	relations, err := isolated.GetRelatedEntities(minister.ID, &models.Relationship{Name: "AS_DEPARTMENT"})
+	require.NoError(t, err)
+	assert.Empty(t, relations)
+	addCacheEntity(t, isolated, "Minister of Caching", "Department of Caching", "department", "9005-02_tr_02", counters)
+	relations, err = isolated.GetRelatedEntities(minister.ID, &models.Relationship{Name: "AS_DEPARTMENT"})
+	require.NoError(t, err)
+	assert.Len(t, relations, 1)
+
+	// Terminating the minister is seen by the next lookup
+	err = isolated.TerminateOrgEntity(map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Caching",
+		"date":           "2021-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9005-02_tr_03",
+	})
+	require.NoError(t, err)
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Caching", "2021-01-01T00:00:00Z")
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	assert.Greater(t, isolated.CacheStats().Invalidations, 0)
+}
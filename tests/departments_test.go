@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// departmentID returns the ID of the only department with the given name
+func departmentID(t *testing.T, client *api.Client, name string) string {
+	t.Helper()
+	results, err := client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "department"},
+		Name: name,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	return results[0].ID
+}
+
+// relatedIDs lists the entities related to entityID through relations with the given name and direction active at date
+func relatedIDs(t *testing.T, client *api.Client, entityID, direction, name, date string) []string {
+	t.Helper()
+	relations, err := client.GetAllRelatedEntities(entityID, &api.RelationFilter{
+		Direction: direction,
+		Names:     []string{name},
+		ActiveAt:  date,
+	})
+	require.NoError(t, err)
+	ids := []string{}
+	for _, rel := range relations {
+		ids = append(ids, rel.RelatedEntityID)
+	}
+	return ids
+}
+
+func TestMergeDepartments(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Mergers", "minister", "9013-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Mergers", "Department of Rails", "department", "9013-01_tr_02", map[string]int{"department": 0})
+	addCacheEntity(t, isolated, "Minister of Mergers", "Department of Roads", "department", "9013-01_tr_03", map[string]int{"department": 1})
+
+	_, err := isolated.AddPersonEntity(map[string]interface{}{
+		"parent":         "Department of Rails",
+		"child":          "Merged Person",
+		"date":           "2020-01-01",
+		"parent_type":    "department",
+		"child_type":     "citizen",
+		"rel_type":       "AS_APPOINTED",
+		"transaction_id": "9013-02_tr_01",
+	}, map[string]int{"citizen": 0})
+	require.NoError(t, err)
+	_, err = isolated.AddDocumentEntity(map[string]interface{}{
+		"parent":         "Department of Roads",
+		"child":          "9013-02",
+		"date":           "2020-01-01",
+		"parent_type":    "department",
+		"child_type":     "extgzt:org",
+		"transaction_id": "9013-02",
+	}, map[string]int{"document": 0})
+	require.NoError(t, err)
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9013-03_MERGE.csv": "transaction_id,old,new,type,date\n" +
+			"9013-03_tr_01,[Department of Rails;Department of Roads],Department of Transport,department,2021-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Mergers", "")
+	require.NoError(t, err)
+	rails := departmentID(t, isolated, "Department of Rails")
+	roads := departmentID(t, isolated, "Department of Roads")
+	transport := departmentID(t, isolated, "Department of Transport")
+
+	// The new department replaces the old ones under the minister
+	assert.ElementsMatch(t, []string{rails, roads}, relatedIDs(t, isolated, minister.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2020-06-01T00:00:00Z"))
+	assert.Equal(t, []string{transport}, relatedIDs(t, isolated, minister.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2021-06-01T00:00:00Z"))
+
+	// Lineage is recorded from each old department
+	assert.Equal(t, []string{transport}, relatedIDs(t, isolated, rails, api.DirectionOutgoing, "MERGED_INTO", ""))
+	assert.Equal(t, []string{transport}, relatedIDs(t, isolated, roads, api.DirectionOutgoing, "MERGED_INTO", ""))
+
+	// People move by default, documents are copied
+	people := relatedIDs(t, isolated, transport, api.DirectionOutgoing, "AS_APPOINTED", "")
+	require.Len(t, people, 1)
+	assert.Empty(t, relatedIDs(t, isolated, rails, api.DirectionOutgoing, "AS_APPOINTED", "2021-06-01T00:00:00Z"))
+	documents := relatedIDs(t, isolated, transport, api.DirectionOutgoing, "AS_DOCUMENT", "")
+	require.Len(t, documents, 1)
+	assert.Equal(t, documents, relatedIDs(t, isolated, roads, api.DirectionOutgoing, "AS_DOCUMENT", "2021-06-01T00:00:00Z"))
+}
+
+func TestMergeDepartmentsPolicies(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Old Things", "minister", "9013-04_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of New Things", "minister", "9013-04_tr_02", map[string]int{"minister": 1})
+	addCacheEntity(t, isolated, "Minister of Old Things", "Department of Clay", "department", "9013-04_tr_03", map[string]int{"department": 0})
+	_, err := isolated.AddPersonEntity(map[string]interface{}{
+		"parent":         "Department of Clay",
+		"child":          "Retired Person",
+		"date":           "2020-01-01",
+		"parent_type":    "department",
+		"child_type":     "citizen",
+		"rel_type":       "AS_APPOINTED",
+		"transaction_id": "9013-05_tr_01",
+	}, map[string]int{"citizen": 0})
+	require.NoError(t, err)
+
+	_, err = isolated.MergeDepartments(map[string]interface{}{
+		"transaction_id": "9013-06_tr_01",
+		"old":            "[Department of Clay]",
+		"new":            "Department of Ceramics",
+		"date":           "2021-01-01",
+		"president":      "Ranil Wickremesinghe",
+		"parent":         "Minister of New Things",
+		"people":         "terminate",
+	}, map[string]int{"department": 1})
+	require.NoError(t, err)
+
+	newMinister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of New Things", "")
+	require.NoError(t, err)
+	ceramics := departmentID(t, isolated, "Department of Ceramics")
+	assert.Equal(t, []string{ceramics}, relatedIDs(t, isolated, newMinister.ID, api.DirectionOutgoing, "AS_DEPARTMENT", ""))
+	assert.Empty(t, relatedIDs(t, isolated, ceramics, api.DirectionOutgoing, "AS_APPOINTED", ""))
+	clay := departmentID(t, isolated, "Department of Clay")
+	assert.Empty(t, relatedIDs(t, isolated, clay, api.DirectionOutgoing, "AS_APPOINTED", "2021-06-01T00:00:00Z"))
+
+	// Unknown policies and departments are rejected before anything is written
+	_, err = isolated.MergeDepartments(map[string]interface{}{
+		"transaction_id": "9013-06_tr_02",
+		"old":            "[Department of Ceramics]",
+		"new":            "Department of Pottery",
+		"date":           "2022-01-01",
+		"president":      "Ranil Wickremesinghe",
+		"documents":      "shred",
+	}, map[string]int{"department": 2})
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "documents", invalid.Field)
+
+	_, err = isolated.MergeDepartments(map[string]interface{}{
+		"transaction_id": "9013-06_tr_03",
+		"old":            "[Department of Ceramics;Department of Nothing]",
+		"new":            "Department of Pottery",
+		"date":           "2022-01-01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"department": 2})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	results, err := isolated.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "department"},
+		Name: "Department of Pottery",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
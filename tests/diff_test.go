@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffOrgChart(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Roads", "minister", "9017-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Ports", "minister", "9017-01_tr_02", map[string]int{"minister": 1})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Water", "minister", "9017-01_tr_03", map[string]int{"minister": 2})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Lakes", "minister", "9017-01_tr_04", map[string]int{"minister": 3})
+	addCacheEntity(t, isolated, "Minister of Roads", "Department of Tolls", "department", "9017-01_tr_05", map[string]int{"department": 0})
+	addCacheEntity(t, isolated, "Minister of Water", "Department of Rain", "department", "9017-01_tr_06", map[string]int{"department": 1})
+	addCacheEntity(t, isolated, "Minister of Ports", "Department of Harbours", "department", "9017-01_tr_07", map[string]int{"department": 2})
+
+	// A gazette of 2021-01-01 renames, merges, adds, moves and terminates
+	_, err := isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Roads",
+		"new":            "Minister of Highways",
+		"type":           "minister",
+		"date":           "2021-01-01",
+		"transaction_id": "9017-02_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 4})
+	require.NoError(t, err)
+	_, err = isolated.MergeMinisters(map[string]interface{}{
+		"old":            "[Minister of Water;Minister of Lakes]",
+		"new":            "Minister of Water Resources",
+		"type":           "minister",
+		"date":           "2021-01-01",
+		"transaction_id": "9017-02_tr_02",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 5})
+	require.NoError(t, err)
+	_, err = isolated.AddOrgEntity(map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Space",
+		"date":           "2021-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9017-02_tr_03",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 6})
+	require.NoError(t, err)
+	require.NoError(t, isolated.MoveDepartment(map[string]interface{}{
+		"old_parent":         "Minister of Ports",
+		"new_parent":         "Minister of Space",
+		"child":              "Department of Harbours",
+		"type":               "department",
+		"date":               "2021-01-01",
+		"transaction_id":     "9017-02_tr_04",
+		"new_president_name": "Ranil Wickremesinghe",
+	}))
+	require.NoError(t, isolated.TerminateOrgEntity(map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Ports",
+		"date":           "2021-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9017-02_tr_05",
+	}))
+
+	diff, err := isolated.DiffOrgChart("2020-06-01T00:00:00Z", "2021-06-01T00:00:00Z", nil)
+	require.NoError(t, err)
+
+	var text bytes.Buffer
+	require.NoError(t, diff.WriteText(&text))
+	assert.Equal(t, "Changes from 2020-06-01 to 2021-06-01:\n"+
+		"  ADD minister 'Minister of Space' under 'Ranil Wickremesinghe' on 2021-01-01\n"+
+		"  MOVE department 'Department of Harbours' from 'Minister of Ports' to 'Minister of Space' on 2021-01-01\n"+
+		"  RENAME minister 'Minister of Roads' to 'Minister of Highways' on 2021-01-01\n"+
+		"  MERGE minister 'Minister of Lakes', 'Minister of Water' into 'Minister of Water Resources' on 2021-01-01\n"+
+		"  TERMINATE minister 'Minister of Ports' under 'Ranil Wickremesinghe' on 2021-01-01\n",
+		text.String())
+
+	// The CSV files are shaped like the transaction files
+	assert.Equal(t, []string{api.ChangeAdd, api.ChangeMove, api.ChangeRename, api.ChangeMerge, api.ChangeTerminate}, diff.ChangeTypes())
+	var csv bytes.Buffer
+	require.NoError(t, diff.WriteCSV(&csv, api.ChangeMove, "9017-03"))
+	assert.Equal(t, "transaction_id,old_parent,old_president_name,new_parent,new_president_name,child,type,date\n"+
+		"9017-03_tr_01,Minister of Ports,Ranil Wickremesinghe,Minister of Space,Ranil Wickremesinghe,Department of Harbours,department,2021-01-01\n",
+		csv.String())
+	csv.Reset()
+	require.NoError(t, diff.WriteCSV(&csv, api.ChangeMerge, "9017-03"))
+	assert.Equal(t, "transaction_id,old,new,type,date\n"+
+		"9017-03_tr_01,[Minister of Lakes;Minister of Water],Minister of Water Resources,minister,2021-01-01\n",
+		csv.String())
+	csv.Reset()
+	require.NoError(t, diff.WriteCSV(&csv, api.ChangeTerminate, "9017-03"))
+	assert.Equal(t, "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"+
+		"9017-03_tr_01,Ranil Wickremesinghe,citizen,Minister of Ports,minister,AS_MINISTER,2021-01-01\n",
+		csv.String())
+
+	// Nothing changed before the gazette
+	diff, err = isolated.DiffOrgChart("2020-02-01T00:00:00Z", "2020-06-01T00:00:00Z", nil)
+	require.NoError(t, err)
+	assert.Empty(t, diff.Changes)
+
+	_, err = isolated.DiffOrgChart("2021-06-01T00:00:00Z", "2020-06-01T00:00:00Z", nil)
+	assert.Error(t, err)
+}
+
+func TestDiffOrgChartsPeople(t *testing.T) {
+	person := func(id, since string) api.ChartEntity {
+		return api.ChartEntity{ID: id, Name: "Person " + id, Kind: "citizen", Since: since}
+	}
+	chart := func(date string, health, education []api.ChartEntity) *api.OrgChart {
+		return &api.OrgChart{
+			Date:       date,
+			Government: api.ChartEntity{ID: "gov_01", Name: "Government of Sri Lanka", Kind: "government"},
+			Presidents: []*api.ChartPresident{{
+				ChartEntity: api.ChartEntity{ID: "president", Name: "President", Kind: "citizen"},
+				Ministers: []*api.ChartMinister{
+					{ChartEntity: api.ChartEntity{ID: "health", Name: "Minister of Health", Kind: "minister"}, People: health},
+					{ChartEntity: api.ChartEntity{ID: "education", Name: "Minister of Education", Kind: "minister"}, People: education},
+				},
+			}},
+		}
+	}
+
+	from := chart("2020-01-01T00:00:00Z", []api.ChartEntity{person("a", "2019-01-01T00:00:00Z"), person("b", "2019-01-01T00:00:00Z")}, nil)
+	to := chart("2021-01-01T00:00:00Z", []api.ChartEntity{person("b", "2019-01-01T00:00:00Z")},
+		[]api.ChartEntity{person("a", "2020-06-01T00:00:00Z"), person("c", "2020-07-01T00:00:00Z")})
+	diff := api.DiffOrgCharts(from, to)
+	require.Len(t, diff.Changes, 2)
+	assert.Equal(t, api.ChangeAdd, diff.Changes[0].Type)
+	assert.Equal(t, "Person c", diff.Changes[0].Name)
+	assert.Equal(t, "Minister of Education", diff.Changes[0].Parent)
+	assert.Equal(t, api.ChangeMove, diff.Changes[1].Type)
+	assert.Equal(t, "Person a", diff.Changes[1].Name)
+	assert.Equal(t, "Minister of Health", diff.Changes[1].OldParent)
+	assert.Equal(t, "2020-06-01T00:00:00Z", diff.Changes[1].Date)
+}
@@ -7,6 +7,8 @@ import (
 // TODO: Please add more tests cases when we cover other angels about gazette tracking. 
 
 func TestAddDocumentEntity(t *testing.T) {
+	client := newTestClient(t)
+
 	// Test cases
 	tests := []struct {
 		name           string
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorEntityNotFound(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	_, err := isolated.GetPresidentByGovernment("Nobody In Particular")
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+
+	// The lookup error survives the wrapping done by the operations
+	_, err = isolated.AddOrgEntity(map[string]interface{}{
+		"parent":         "Nobody In Particular",
+		"child":          "Minister of Nothing",
+		"date":           "2020-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9004-01_tr_01",
+	}, map[string]int{"minister": 0})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+
+	// A 404 from the Update API also counts as not found
+	_, err = isolated.UpdateEntity("no_such_entity", &models.Entity{ID: "no_such_entity"})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	assert.True(t, api.IsHTTPStatus(err, http.StatusNotFound))
+}
+
+func TestErrorAmbiguousMatch(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	for _, id := range []string{"9004-02_cit_1", "9004-02_cit_2"} {
+		_, err := isolated.CreateEntity(&models.Entity{
+			ID:      id,
+			Kind:    models.Kind{Major: "Person", Minor: "citizen"},
+			Created: "2020-01-01T00:00:00Z",
+			Name: models.TimeBasedValue{
+				StartTime: "2020-01-01T00:00:00Z",
+				Value:     "Twin Name",
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	_, err := isolated.AddPersonEntity(map[string]interface{}{
+		"parent":         "Government of Sri Lanka",
+		"child":          "Twin Name",
+		"date":           "2020-01-02",
+		"parent_type":    "government",
+		"child_type":     "citizen",
+		"rel_type":       "AS_PRESIDENT",
+		"transaction_id": "9004-02_tr_01",
+	}, map[string]int{"citizen": 0})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, api.ErrAmbiguousMatch)
+
+	var ambiguous *api.AmbiguousMatchError
+	require.ErrorAs(t, err, &ambiguous)
+	assert.ElementsMatch(t, []string{"9004-02_cit_1", "9004-02_cit_2"}, ambiguous.CandidateIDs)
+}
+
+func TestErrorHTTPStatus(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetRetryPolicy(api.NoRetryPolicy())
+
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/v1/entities/search", Status: http.StatusInternalServerError, Count: 1})
+
+	_, err := isolated.GetPresidentByGovernment("Ranil Wickremesinghe")
+	var httpErr *api.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+	assert.Equal(t, http.MethodPost, httpErr.Method)
+	assert.False(t, errors.Is(err, api.ErrEntityNotFound))
+}
+
+func TestErrorInvalidTransaction(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	_, err := isolated.AddOrgEntity(map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Bad Dates",
+		"date":           "01/01/2020",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9004-03_tr_01",
+	}, map[string]int{"minister": 0})
+	assert.ErrorIs(t, err, api.ErrInvalidTransaction)
+
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "date", invalid.Field)
+
+	_, err = isolated.AddOrgEntity(map[string]interface{}{
+		"parent":         "Minister of Nothing",
+		"child":          "Department of Nothing",
+		"date":           "2020-01-01",
+		"parent_type":    "minister",
+		"child_type":     "department",
+		"rel_type":       "AS_DEPARTMENT",
+		"transaction_id": "9004-03_tr_02",
+	}, map[string]int{"department": 0})
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "president", invalid.Field)
+}
@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"orgchart_nexoan/api"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exportChart is a small saved org chart
+func exportChart(t *testing.T) *api.OrgChart {
+	t.Helper()
+	chart := &api.OrgChart{
+		Date:       "2021-01-01T00:00:00Z",
+		Government: api.ChartEntity{ID: "gov_01", Name: "Government of Sri Lanka", Kind: "government"},
+		Presidents: []*api.ChartPresident{{
+			ChartEntity: api.ChartEntity{ID: "cit_1", Name: "Ranil Wickremesinghe", Kind: "citizen", Since: "2019-12-01T00:00:00Z"},
+			Ministers: []*api.ChartMinister{
+				{
+					ChartEntity: api.ChartEntity{ID: "min_1", Name: `Minister of Finance, Economic Stabilization and "National" Policies`, Kind: "minister"},
+					Departments: []*api.ChartDepartment{{
+						ChartEntity: api.ChartEntity{ID: "dep_1", Name: "Department of Treasury", Kind: "department"},
+						People:      []api.ChartEntity{{ID: "cit_2", Name: "Treasury Secretary", Kind: "citizen"}},
+					}},
+					People: []api.ChartEntity{},
+				},
+				{
+					ChartEntity: api.ChartEntity{ID: "min_2", Name: "Minister of Health", Kind: "minister"},
+					Departments: []*api.ChartDepartment{},
+					People:      []api.ChartEntity{{ID: "cit_3", Name: "Health Person", Kind: "citizen"}},
+				},
+			},
+		}},
+	}
+
+	// Round trip through the saved form, as -chart reads it
+	saved, err := json.Marshal(chart)
+	require.NoError(t, err)
+	read, err := api.ReadOrgChart(bytes.NewReader(saved))
+	require.NoError(t, err)
+	return read
+}
+
+func TestExportOrgChartDOT(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, api.ExportOrgChart(&out, exportChart(t), &api.ExportOptions{Format: api.ExportDOT, MaxLabelLength: 20}))
+	dot := out.String()
+
+	assert.True(t, strings.HasPrefix(dot, "digraph orgchart {\n"))
+	assert.Contains(t, dot, `[label="Minister of Finan...", shape=box, tooltip="Minister of Finance, Economic Stabilization and \"National\" Policies"];`)
+	assert.Contains(t, dot, `[label="Treasury Secretary", shape=plaintext`)
+	// government -> president -> 2 ministers -> department -> person, and the minister's person
+	assert.Equal(t, 6, strings.Count(dot, " -> "))
+}
+
+func TestExportOrgChartMermaidFilters(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, api.ExportOrgChart(&out, exportChart(t), &api.ExportOptions{
+		Format:      api.ExportMermaid,
+		Minister:    "Minister of Health",
+		SkipPeople:  true,
+		GroupByKind: true,
+	}))
+	mermaid := out.String()
+
+	assert.True(t, strings.HasPrefix(mermaid, "flowchart LR\n"))
+	assert.Contains(t, mermaid, `subgraph minister ["Ministers"]`)
+	assert.Contains(t, mermaid, `["Minister of Health"]`)
+	assert.NotContains(t, mermaid, "Finance")
+	assert.NotContains(t, mermaid, "Health Person")
+	assert.Equal(t, 2, strings.Count(mermaid, " --> "))
+
+	err := api.ExportOrgChart(&out, exportChart(t), &api.ExportOptions{Format: api.ExportMermaid, Minister: "Minister of Nothing"})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	err = api.ExportOrgChart(&out, exportChart(t), &api.ExportOptions{Format: "svg"})
+	assert.Error(t, err)
+}
+
+func TestExportOrgChartGraphML(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, api.ExportOrgChart(&out, exportChart(t), &api.ExportOptions{
+		Format:          api.ExportGraphML,
+		SkipDepartments: true,
+		GroupByKind:     true,
+	}))
+
+	var document struct {
+		Graph struct {
+			Nodes []struct {
+				ID    string `xml:"id,attr"`
+				Graph struct {
+					Nodes []struct {
+						ID string `xml:"id,attr"`
+					} `xml:"node"`
+				} `xml:"graph"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	require.NoError(t, xml.Unmarshal(out.Bytes(), &document))
+
+	groups := map[string]int{}
+	for _, node := range document.Graph.Nodes {
+		groups[node.ID] = len(node.Graph.Nodes)
+	}
+	assert.Equal(t, map[string]int{"group_government": 1, "group_president": 1, "group_minister": 2, "group_person": 1}, groups)
+	assert.Len(t, document.Graph.Edges, 4)
+	assert.Contains(t, out.String(), `<data key="name">Minister of Finance, Economic Stabilization and &#34;National&#34; Policies</data>`)
+}
+
+func TestExportOrgChartFromAPI(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Drawings", "minister", "9018-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Drawings", "Department of Lines", "department", "9018-01_tr_02", map[string]int{"department": 0})
+
+	chart, err := isolated.GetOrgChart("2020-06-01T00:00:00Z", nil)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, api.ExportOrgChart(&out, chart, &api.ExportOptions{Format: api.ExportDOT, President: "Ranil Wickremesinghe"}))
+	assert.Contains(t, out.String(), `[label="Department of Lines", shape=ellipse`)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEntityReturnsFullEntity(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	_, err := isolated.CreateEntity(&models.Entity{
+		ID:      "9008-01_dep_1",
+		Kind:    models.Kind{Major: "Organisation", Minor: "department"},
+		Created: "2020-01-01T00:00:00Z",
+		Name: models.TimeBasedValue{
+			StartTime: "2020-01-01T00:00:00Z",
+			Value:     "Department of Full Fetch",
+		},
+		Metadata: []models.MetadataEntry{
+			{Key: "source", Value: "gazette"},
+			{Key: "code", Value: "DFF"},
+		},
+		Attributes: []models.AttributeEntry{{
+			Key: "budget",
+			Value: models.AttributeValueCollection{Values: []models.TimeBasedValue{
+				{StartTime: "2020-01-01T00:00:00Z", EndTime: "2021-01-01T00:00:00Z", Value: "100"},
+				{StartTime: "2021-01-01T00:00:00Z", Value: "200"},
+			}},
+		}},
+		Relationships: []models.RelationshipEntry{},
+	})
+	require.NoError(t, err)
+
+	entity, err := isolated.GetEntity("9008-01_dep_1", &api.GetEntityOptions{Attributes: []string{"budget"}})
+	require.NoError(t, err)
+	assert.Equal(t, "Department of Full Fetch", entity.Name.Value)
+	assert.Equal(t, "department", entity.Kind.Minor)
+
+	// Metadata comes back sorted by key
+	require.Len(t, entity.Metadata, 2)
+	assert.Equal(t, "code", entity.Metadata[0].Key)
+	assert.Equal(t, "DFF", entity.Metadata[0].Value)
+
+	require.Len(t, entity.Attributes, 1)
+	assert.Len(t, entity.Attributes[0].Value.Values, 2)
+
+	// As of a date only the value active then is returned
+	entity, err = isolated.GetEntity("9008-01_dep_1", &api.GetEntityOptions{AsOf: "2020-06-01T00:00:00Z", Attributes: []string{"budget"}})
+	require.NoError(t, err)
+	require.Len(t, entity.Attributes[0].Value.Values, 1)
+	assert.Equal(t, "100", entity.Attributes[0].Value.Values[0].Value)
+
+	_, err = isolated.GetEntity("9008-01_dep_1", &api.GetEntityOptions{AsOf: "2019-01-01T00:00:00Z"})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+	_, err = isolated.GetEntity("9008-01_missing", nil)
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+}
+
+func TestGetEntityRelationshipsAndNameHistory(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Old Names", "minister", "9008-02_tr_01", map[string]int{"minister": 0})
+
+	_, err := isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Old Names",
+		"new":            "Minister of New Names",
+		"type":           "minister",
+		"date":           "2022-01-01",
+		"transaction_id": "9008-02_tr_02",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 1})
+	require.NoError(t, err)
+
+	renamed, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of New Names", "2022-01-01T00:00:00Z")
+	require.NoError(t, err)
+	entity, err := isolated.GetEntity(renamed.ID, nil)
+	require.NoError(t, err)
+
+	// Both directions are included: the president's AS_MINISTER and the old minister's RENAMED_TO
+	names := map[string]string{}
+	for _, entry := range entity.Relationships {
+		names[entry.Value.Name] = entry.Value.Direction
+	}
+	assert.Equal(t, "INCOMING", names["AS_MINISTER"])
+	assert.Equal(t, "INCOMING", names["RENAMED_TO"])
+
+	require.Len(t, entity.NameHistory, 2)
+	assert.Equal(t, "Minister of Old Names", entity.NameHistory[0].Value)
+	assert.Equal(t, "2022-01-01T00:00:00Z", entity.NameHistory[0].EndTime)
+	assert.Equal(t, "Minister of New Names", entity.NameHistory[1].Value)
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"context"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinHandlers(t *testing.T) {
+	handlers := api.BuiltinHandlers()
+	assert.Equal(t, []string{"document", "organisation", "person"}, handlers.ProcessTypes())
+	assert.Equal(t, []string{"ADD", "MERGE", "MOVE", "RENAME", "SPLIT", "TERMINATE"}, handlers.TransactionTypes())
+
+	for _, kind := range []string{"minister", "department", "citizen"} {
+		_, ok := handlers.Lookup("ADD", kind)
+		assert.True(t, ok, kind)
+	}
+	_, ok := handlers.Lookup("MERGE", "department")
+	assert.True(t, ok)
+	_, ok = handlers.Lookup("MERGE", "citizen")
+	assert.False(t, ok)
+
+	add, _ := handlers.Lookup("add", "minister")
+	transaction := map[string]interface{}{
+		"transaction_id": "9011-01_tr_01",
+		"parent":         "Ranil Wickremesinghe",
+		"parent_type":    "citizen",
+		"child":          "Minister of Handlers",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"date":           "2020-01-01",
+	}
+	require.NoError(t, add.Validate(transaction))
+	assert.Equal(t, "add minister 'Minister of Handlers' under 'Ranil Wickremesinghe' on 2020-01-01", add.Describe(transaction))
+
+	delete(transaction, "rel_type")
+	err := add.Validate(transaction)
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "rel_type", invalid.Field)
+}
+
+func TestCustomTransactionHandler(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	// A CORRECT transaction fixes the name of a minister in place
+	var corrected []string
+	isolated.TransactionHandlers().Register("CORRECT", "minister", api.FuncHandler{
+		Required: []string{"old", "new", "date"},
+		Run: func(ctx context.Context, c *api.Client, tx map[string]interface{}, counters map[string]int) error {
+			minister, err := c.GetActiveMinisterByPresidentContext(ctx, tx["president"].(string), tx["old"].(string), "")
+			if err != nil {
+				return err
+			}
+			_, err = c.UpdateEntityContext(ctx, minister.ID, &models.Entity{
+				ID:   minister.ID,
+				Name: models.TimeBasedValue{StartTime: minister.Created, Value: tx["new"]},
+			})
+			corrected = append(corrected, minister.ID)
+			return err
+		},
+	})
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9011-02_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9011-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Typos,minister,AS_MINISTER,2020-01-01\n",
+		"9011-02_CORRECT.csv": "transaction_id,old,new,type,date\n" +
+			"9011-02_tr_02,Minister of Typos,Minister of Types,minister,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	require.Len(t, corrected, 1)
+
+	// A CORRECT row without its date is rejected before the handler runs
+	dataDir = writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9011-03_CORRECT.csv": "transaction_id,old,new,type,date\n" +
+			"9011-03_tr_01,Minister of Types,Minister of Tropes,minister,\n",
+	})
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	assert.ErrorIs(t, err, api.ErrInvalidTransaction)
+	assert.Len(t, corrected, 1)
+}
+
+func TestCustomProcessType(t *testing.T) {
+	handlers := api.BuiltinHandlers()
+	handlers.RegisterProcessType("organisation", "agency")
+	var applied []string
+	handlers.Register("ADD", "agency", api.FuncHandler{
+		Required: []string{"child"},
+		Run: func(ctx context.Context, c *api.Client, tx map[string]interface{}, counters map[string]int) error {
+			counters["agency"]++
+			applied = append(applied, tx["child"].(string))
+			return nil
+		},
+	})
+
+	custom := newClient(t, "unused", "unused", api.WithHandlers(handlers))
+	assert.Same(t, handlers, custom.TransactionHandlers())
+	assert.NotSame(t, handlers, newClient(t, "unused", "unused").TransactionHandlers())
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9011-04_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9011-04_tr_01,Minister of Nothing,minister,Agency of Handlers,agency,AS_AGENCY,2020-01-01\n",
+	})
+	require.NoError(t, custom.ProcessTransactions(dataDir, "organisation"))
+	assert.Equal(t, []string{"Agency of Handlers"}, applied)
+}
+
+func TestDocumentLoadsRejectOtherTransactions(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	require.NoError(t, isolated.ProcessTransactions(writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9011-05_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9011-05_tr_01,Ranil Wickremesinghe,citizen,Minister of Paperwork,minister,AS_MINISTER,2020-01-01\n",
+	}), "organisation"))
+	relationships := server.RelationshipCount()
+
+	// A TERMINATE of a minister in a documents folder is not handed to the organisation handlers
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9011-06_TERMINATE.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9011-06_tr_01,Ranil Wickremesinghe,citizen,Minister of Paperwork,minister,AS_MINISTER,2020-02-01\n",
+	})
+	err := isolated.ProcessDocumentTransactions(dataDir, "document")
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Reason, "document loads have no TERMINATE handler for minister")
+	assert.Equal(t, relationships, server.RelationshipCount())
+	assert.Len(t, relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-03-01T00:00:00Z"), 1)
+}
@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDataRoot writes files, keyed by their path under the data root, and returns the data root
+func writeDataRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+const historyHeader = "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+
+// historyFiles is the history of a president appointed on 2021-01-01 by gazette 9022-01
+var historyFiles = map[string]string{
+	"documents/Test President/organisation/9022_ADD.csv": "transaction_id,date,url,description,child_type,child,parent_type,parent\n" +
+		"9022-01,2021-01-01,,Test President,extgztorg,9022-01,government,Government of Sri Lanka\n",
+	"people/Test President/2021-01-01/9022-01-1/9022-01_ADD.csv": historyHeader +
+		"9022-01_tr_01,Government of Sri Lanka,government,Test President,citizen,AS_PRESIDENT,2021-01-01\n",
+	"people/Test President/2021-01-01/9022-01-2/9022-01-2_ADD.csv": historyHeader +
+		"9022-01-2_tr_01,Minister of History,minister,Chronicler,citizen,AS_APPOINTED,2021-01-01\n",
+	"orgchart/Test President/2021-01-01/9022-01_ADD.csv": historyHeader +
+		"9022-01_tr_02,Test President,citizen,Minister of History,minister,AS_MINISTER,2021-01-01\n",
+	"orgchart/Test President/2021-02-01/9022-10/9022-10_ADD.csv": historyHeader +
+		"9022-10_tr_01,Minister of History,minister,Department of Archives,department,AS_DEPARTMENT,2021-02-01\n",
+	"orgchart/Test President/2021-02-01/9022-9/9022-9_ADD.csv": historyHeader +
+		"9022-9_tr_01,Minister of History,minister,Department of Annals,department,AS_DEPARTMENT,2021-02-01\n",
+	"orgchart/Other President/2020-06-01/9021-50_ADD.csv": historyHeader +
+		"9021-50_tr_01,Other President,citizen,Minister of Elsewhere,minister,AS_MINISTER,2020-06-01\n",
+	"orgchart/Test President/2021-02-01/.DS_Store": "",
+}
+
+func historyPaths(t *testing.T, root string, steps []api.HistoryStep) []string {
+	t.Helper()
+	paths := []string{}
+	for _, step := range steps {
+		relative, err := filepath.Rel(root, step.Path)
+		require.NoError(t, err)
+		paths = append(paths, filepath.ToSlash(relative))
+	}
+	return paths
+}
+
+func TestFindHistoryOrdersByDateKindAndGazette(t *testing.T) {
+	root := writeDataRoot(t, historyFiles)
+	client := newClient(t, "", "")
+
+	steps, err := client.FindHistory(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"orgchart/Other President/2020-06-01",
+		"documents/Test President/organisation",
+		"people/Test President/2021-01-01/9022-01-1",
+		"orgchart/Test President/2021-01-01",
+		"people/Test President/2021-01-01/9022-01-2",
+		"orgchart/Test President/2021-02-01/9022-9",
+		"orgchart/Test President/2021-02-01/9022-10",
+	}, historyPaths(t, root, steps))
+
+	// Document folders take the first date of their president
+	assert.Equal(t, "document", steps[1].ProcessType)
+	assert.Equal(t, "2021-01-01", steps[1].Date)
+	assert.True(t, steps[2].Presidency)
+	assert.Equal(t, "9022-01-1", steps[2].Gazette)
+	assert.Equal(t, "9022-01", steps[3].Gazette)
+	assert.Equal(t, 1, steps[3].Transactions)
+
+	steps, err = client.FindHistory(root, []string{"Other President"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orgchart/Other President/2020-06-01"}, historyPaths(t, root, steps))
+}
+
+func TestFindHistoryFollowsTheOrderFile(t *testing.T) {
+	files := map[string]string{
+		// The second people folder is listed after the folder of the next date, and the other
+		// president's folder is left out
+		api.HistoryOrderFileName: "steps:\n" +
+			"  - path: documents/Test President/organisation\n" +
+			"  - path: people/Test President/2021-01-01/9022-01-1\n" +
+			"  - path: orgchart/Test President/2021-01-01\n" +
+			"  - path: orgchart/Test President/2021-02-01/9022-9\n" +
+			"  - path: people/Test President/2021-01-01/9022-01-2\n" +
+			"  - path: orgchart/Other President/2020-06-01\n" +
+			"    skip: true\n",
+	}
+	for name, content := range historyFiles {
+		files[name] = content
+	}
+	root := writeDataRoot(t, files)
+
+	// The folder the file does not list comes before the first listed folder that sorts after it
+	steps, err := newClient(t, "", "").FindHistory(root, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"documents/Test President/organisation",
+		"people/Test President/2021-01-01/9022-01-1",
+		"orgchart/Test President/2021-01-01",
+		"orgchart/Test President/2021-02-01/9022-9",
+		"people/Test President/2021-01-01/9022-01-2",
+		"orgchart/Test President/2021-02-01/9022-10",
+	}, historyPaths(t, root, steps))
+}
+
+// TestLoadShippedHistory loads the presidents of the load scripts with -history and checks that it
+// loads the folders the scripts load, in the same order
+func TestLoadShippedHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("loading the shipped data takes several seconds")
+	}
+	var scriptPaths []string
+	for _, name := range []string{"load_gr_data.sh", "load_rw_data.sh", "load_ak_data.sh"} {
+		script, err := os.Open(filepath.Join("..", name))
+		require.NoError(t, err)
+		manifest, err := api.ParseLoadScript(script)
+		script.Close()
+		require.NoError(t, err, name)
+		for _, step := range manifest.Steps {
+			if !step.Skip {
+				scriptPaths = append(scriptPaths, strings.TrimPrefix(filepath.ToSlash(step.Path), "data/"))
+			}
+		}
+	}
+
+	server := apitest.NewServer()
+	t.Cleanup(server.Close)
+	client := newClient(t, server.UpdateURL(), server.QueryURL(), api.WithLogger(log.New(io.Discard, "", 0)))
+	client.SetCacheEnabled(true)
+
+	root := filepath.Join("..", "data")
+	steps, err := client.FindHistory(root, []string{"Gotabaya Rajapaksa", "Ranil Wickremesinghe", "Anura Kumara Dissanayake"})
+	require.NoError(t, err)
+	require.Equal(t, scriptPaths, historyPaths(t, root, steps))
+
+	_, err = client.CreateGovernmentNode()
+	require.NoError(t, err)
+	summary, err := client.LoadHistory(steps)
+	require.NoError(t, err)
+	assert.Nil(t, summary.Failed)
+	assert.Len(t, summary.Loaded, len(scriptPaths))
+	assert.Equal(t, 1212, server.EntityCount())
+}
+
+func TestFindHistoryRejectsUndatedFolders(t *testing.T) {
+	root := writeDataRoot(t, map[string]string{
+		"orgchart/Test President/9022-01/9022-01_ADD.csv": historyHeader,
+	})
+
+	_, err := newClient(t, "", "").FindHistory(root, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not in a YYYY-MM-DD folder")
+}
+
+func TestLoadHistory(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	root := writeDataRoot(t, historyFiles)
+
+	steps, err := isolated.FindHistory(root, []string{"Test President"})
+	require.NoError(t, err)
+	summary, err := isolated.LoadHistory(steps)
+	require.NoError(t, err)
+	assert.Len(t, summary.Loaded, 6)
+	assert.Nil(t, summary.Failed)
+	assert.Equal(t, 6, summary.Transactions())
+
+	ministerIDs := relatedIDs(t, isolated, "ADD/9022-01_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2021-02-01T00:00:00Z")
+	require.Len(t, ministerIDs, 1)
+	assert.Len(t, relatedIDs(t, isolated, ministerIDs[0], api.DirectionOutgoing, "AS_APPOINTED", "2021-02-01T00:00:00Z"), 1)
+	assert.Len(t, relatedIDs(t, isolated, ministerIDs[0], api.DirectionOutgoing, "AS_DEPARTMENT", "2021-02-01T00:00:00Z"), 2)
+}
+
+func TestLoadHistoryStopsOnTheFailingStep(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	root := writeDataRoot(t, map[string]string{
+		"orgchart/Ranil Wickremesinghe/2021-01-01/9022-20_ADD.csv": historyHeader +
+			"9022-20_tr_01,Minister of Nowhere,minister,Department of Gaps,department,AS_DEPARTMENT,2021-01-01\n",
+		"orgchart/Ranil Wickremesinghe/2021-02-01/9022-21_ADD.csv": historyHeader +
+			"9022-21_tr_01,Ranil Wickremesinghe,citizen,Minister of Later,minister,AS_MINISTER,2021-02-01\n",
+	})
+	entities := server.EntityCount()
+
+	steps, err := isolated.FindHistory(root, nil)
+	require.NoError(t, err)
+	summary, err := isolated.LoadHistory(steps)
+	require.Error(t, err)
+	assert.Empty(t, summary.Loaded)
+	require.NotNil(t, summary.Failed)
+	assert.Equal(t, "2021-01-01", summary.Failed.Date)
+	assert.Equal(t, entities, server.EntityCount())
+}
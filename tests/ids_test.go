@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"testing"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayingTransactionsIsANoOp(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-01_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9021-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Replays,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-01_tr_02,Ranil Wickremesinghe,citizen,Minister of Reruns,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-01_tr_03,Minister of Replays,minister,Department of Tapes,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9021-01_tr_04,Minister of Replays,minister,Department of Reels,department,AS_DEPARTMENT,2020-01-01\n",
+		"9021-01_MOVE.csv": "transaction_id,old_parent,old_president_name,new_parent,new_president_name,child,type,date\n" +
+			"9021-01_tr_05,Minister of Replays,Ranil Wickremesinghe,Minister of Reruns,Ranil Wickremesinghe,Department of Tapes,department,2020-02-01\n",
+		"9021-01_RENAME.csv": "transaction_id,old,new,type,date\n" +
+			"9021-01_tr_06,Minister of Reruns,Minister of Repeats,minister,2020-03-01\n",
+		"9021-01_TERMINATE.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9021-01_tr_07,Minister of Replays,minister,Department of Reels,department,AS_DEPARTMENT,2020-04-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	entities, relationships := server.EntityCount(), server.RelationshipCount()
+
+	// Entity and relationship IDs derive from the transaction and the role in it
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-01-01T00:00:00Z")
+	require.Equal(t, []string{"ADD/9021-01_tr_01_minister", "ADD/9021-01_tr_02_minister"}, ministerIDs)
+	relations, err := isolated.GetRelatedEntities("ADD/2152-12_tr_01_citizen", &models.Relationship{ID: api.RelationshipID("ADD/9021-01_tr_01", "AS_MINISTER", "ADD/2152-12_tr_01_citizen", "ADD/9021-01_tr_01_minister")})
+	require.NoError(t, err)
+	assert.Len(t, relations, 1)
+
+	// Loading the directory again writes nothing new
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	assert.Equal(t, entities, server.EntityCount())
+	assert.Equal(t, relationships, server.RelationshipCount())
+	assert.Equal(t, []string{"ADD/9021-01_tr_03_department"}, relatedIDs(t, isolated, "ADD/9021-01_tr_02_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-02-01T00:00:00Z"))
+}
+
+func TestReplayingAReshuffleIsANoOp(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+
+	before := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-03_ADD.csv": header +
+			"9021-03_tr_01,Ranil Wickremesinghe,citizen,Minister of Harbours,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-03_tr_02,Ranil Wickremesinghe,citizen,Minister of Ferries,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-03_tr_03,Minister of Harbours,minister,Department of Piers,department,AS_DEPARTMENT,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(before, "organisation"))
+	appointed := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-03_ADD.csv": header +
+			"9021-03_tr_04,Minister of Harbours,minister,Harbour Master,citizen,AS_APPOINTED,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(appointed, "person"))
+
+	// The gazette ends the ministers its first transactions name: one is replaced by a new minister of
+	// the same name, the other renamed
+	reshuffle := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-04_TERMINATE.csv": header +
+			"9021-04_tr_01,Minister of Harbours,minister,Department of Piers,department,AS_DEPARTMENT,2020-02-01\n" +
+			"9021-04_tr_02,Ranil Wickremesinghe,citizen,Minister of Harbours,minister,AS_MINISTER,2020-02-01\n",
+		"9021-04_ADD.csv": header +
+			"9021-04_tr_03,Ranil Wickremesinghe,citizen,Minister of Harbours,minister,AS_MINISTER,2020-02-01\n" +
+			"9021-04_tr_04,Minister of Harbours,minister,Department of Wharves,department,AS_DEPARTMENT,2020-02-01\n" +
+			"9021-04_tr_05,Minister of Ferries,minister,Department of Sails,department,AS_DEPARTMENT,2020-02-01\n",
+		"9021-04_RENAME.csv": "transaction_id,old,new,type,date\n" +
+			"9021-04_tr_06,Minister of Ferries,Minister of Boats,minister,2020-02-01\n",
+	})
+	reappointed := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-04_TERMINATE.csv": header +
+			"9021-04_tr_07,Minister of Harbours,minister,Harbour Master,citizen,AS_APPOINTED,2020-02-01\n",
+		"9021-04_ADD.csv": header +
+			"9021-04_tr_08,Minister of Harbours,minister,Harbour Master,citizen,AS_APPOINTED,2020-02-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(reshuffle, "organisation"))
+	require.NoError(t, isolated.ProcessTransactions(reappointed, "person"))
+	entities, relationships := server.EntityCount(), server.RelationshipCount()
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-03-01T00:00:00Z")
+	require.Equal(t, []string{"ADD/9021-04_tr_03_minister", "RENAME/9021-04_tr_06_minister"}, ministerIDs)
+
+	// Loading the gazette again neither fails on the ended ministers nor ends the new one
+	require.NoError(t, isolated.ProcessTransactions(reshuffle, "organisation"))
+	require.NoError(t, isolated.ProcessTransactions(reappointed, "person"))
+	assert.Equal(t, entities, server.EntityCount())
+	assert.Equal(t, relationships, server.RelationshipCount())
+	assert.Equal(t, ministerIDs, relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-03-01T00:00:00Z"))
+	assert.Equal(t, []string{"ADD/9021-04_tr_04_department"}, relatedIDs(t, isolated, "ADD/9021-04_tr_03_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-03-01T00:00:00Z"))
+	assert.Len(t, relatedIDs(t, isolated, "ADD/9021-04_tr_03_minister", api.DirectionOutgoing, "AS_APPOINTED", "2020-03-01T00:00:00Z"), 1)
+}
+
+func TestEntityIDsKeepTransactionsOfDifferentFilesApart(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+
+	// A RENAME shares its transaction_id with an ADD, and a second directory of the gazette adds
+	// another department
+	first := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-02_ADD.csv": header +
+			"9021-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Counters,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-02_tr_02,Minister of Counters,minister,Department of Tallies,department,AS_DEPARTMENT,2020-01-01\n",
+		"9021-02_RENAME.csv": "transaction_id,old,new,type,date\n" +
+			"9021-02_tr_01,Minister of Counters,Minister of Abacuses,minister,2020-02-01\n",
+	})
+	second := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-02_ADD.csv": header +
+			"9021-02_tr_03,Minister of Abacuses,minister,Department of Totals,department,AS_DEPARTMENT,2020-03-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(first, "organisation"))
+	require.NoError(t, isolated.ProcessTransactions(second, "organisation"))
+	entities, relationships := server.EntityCount(), server.RelationshipCount()
+
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "")
+	assert.ElementsMatch(t, []string{"ADD/9021-02_tr_01_minister", "RENAME/9021-02_tr_01_minister"}, ministerIDs)
+	departmentIDs := relatedIDs(t, isolated, "RENAME/9021-02_tr_01_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-03-01T00:00:00Z")
+	assert.ElementsMatch(t, []string{"ADD/9021-02_tr_02_department", "ADD/9021-02_tr_03_department"}, departmentIDs)
+
+	// Loading both directories again recognises every transaction by its IDs
+	require.NoError(t, isolated.ProcessTransactions(first, "organisation"))
+	require.NoError(t, isolated.ProcessTransactions(second, "organisation"))
+	assert.Equal(t, entities, server.EntityCount())
+	assert.Equal(t, relationships, server.RelationshipCount())
+}
+
+func TestAppointmentCarriedOverByARenameIsNotAddedAgain(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+
+	appointed := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-05_ADD.csv": header +
+			"9021-05_tr_01,Ranil Wickremesinghe,citizen,Minister of Ledgers,minister,AS_MINISTER,2020-01-01\n" +
+			"9021-05_tr_02,Minister of Ledgers,minister,Ledger Keeper,citizen,AS_APPOINTED,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(appointed, "organisation"))
+	require.NoError(t, isolated.ProcessTransactions(appointed, "person"))
+
+	// The rename carries the appointment over, and the gazette's people file, sharing the rename's
+	// transaction_id, appoints the same person again
+	renamed := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-06_RENAME.csv": "transaction_id,old,new,type,date\n" +
+			"9021-06_tr_01,Minister of Ledgers,Minister of Books,minister,2020-02-01\n",
+	})
+	reappointed := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9021-06_ADD.csv": header +
+			"9021-06_tr_01,Minister of Books,minister,Ledger Keeper,citizen,AS_APPOINTED,2020-02-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(renamed, "organisation"))
+	relationships := server.RelationshipCount()
+	require.NoError(t, isolated.ProcessTransactions(reappointed, "person"))
+	assert.Equal(t, relationships, server.RelationshipCount())
+	assert.Len(t, relatedIDs(t, isolated, "RENAME/9021-06_tr_01_minister", api.DirectionOutgoing, "AS_APPOINTED", "2020-03-01T00:00:00Z"), 1)
+}
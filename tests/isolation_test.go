@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsolatedGraph(t *testing.T) {
+	other := newTestClient(t)
+
+	isolated, server := newIsolatedClient(t)
+
+	// Government and president nodes plus the AS_PRESIDENT relationship
+	assert.Equal(t, 2, server.EntityCount())
+	assert.Equal(t, 1, server.RelationshipCount())
+
+	transaction := map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Isolation",
+		"date":           "2020-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9999-01_tr_01",
+	}
+	_, err := isolated.AddOrgEntity(transaction, map[string]int{"minister": 0})
+	assert.NoError(t, err)
+
+	criteria := &models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: "minister",
+		},
+		Name: "Minister of Isolation",
+	}
+
+	// The minister exists in the isolated graph only
+	results, err := isolated.SearchEntities(criteria)
+	assert.NoError(t, err)
+	if !assert.Len(t, results, 1) {
+		return
+	}
+	ministerID := results[0].ID
+
+	results, err = other.SearchEntities(criteria)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	// Relationships are reported in both directions
+	relations, err := isolated.GetRelatedEntities(ministerID, &models.Relationship{Name: "AS_MINISTER"})
+	assert.NoError(t, err)
+	assert.Len(t, relations, 1)
+	assert.Equal(t, "INCOMING", relations[0].Direction)
+}
+
+func TestFakeServerRejectsPartialWrites(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	entities, relationships := server.EntityCount(), server.RelationshipCount()
+
+	relationship := func(id, relatedID string) models.RelationshipEntry {
+		return models.RelationshipEntry{Key: id, Value: models.Relationship{
+			ID: id, RelatedEntityID: relatedID, Name: "AS_DEPARTMENT", StartTime: "2020-01-01T00:00:00Z",
+		}}
+	}
+
+	// A create whose second relationship names a missing entity creates neither the entity nor the first relationship
+	_, err := isolated.CreateEntity(&models.Entity{
+		ID:   "9999-02_min_1",
+		Kind: models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: models.TimeBasedValue{StartTime: "2020-01-01T00:00:00Z", Value: "Minister of Halves"},
+		Relationships: []models.RelationshipEntry{
+			relationship("9999-02_rel_1", "ADD/2152-12_tr_01_citizen"),
+			relationship("9999-02_rel_2", "9999-02_dep_missing"),
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, entities, server.EntityCount())
+	assert.Equal(t, relationships, server.RelationshipCount())
+
+	// The same goes for an update
+	_, err = isolated.UpdateEntity("ADD/2152-12_tr_01_citizen", &models.Entity{
+		ID: "ADD/2152-12_tr_01_citizen",
+		Relationships: []models.RelationshipEntry{
+			relationship("9999-02_rel_3", "gov_01"),
+			relationship("9999-02_rel_4", "9999-02_dep_missing"),
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, relationships, server.RelationshipCount())
+}
+
+func TestFakeServerRejectsRelationshipsEndingBeforeTheyStart(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	relationships := server.RelationshipCount()
+
+	relationship := func(startTime, endTime string) models.RelationshipEntry {
+		return models.RelationshipEntry{Key: "9999-03_rel_1", Value: models.Relationship{
+			ID: "9999-03_rel_1", RelatedEntityID: "gov_01", Name: "AS_DEPARTMENT", StartTime: startTime, EndTime: endTime,
+		}}
+	}
+	update := func(entry models.RelationshipEntry) error {
+		_, err := isolated.UpdateEntity("ADD/2152-12_tr_01_citizen", &models.Entity{
+			ID:            "ADD/2152-12_tr_01_citizen",
+			Relationships: []models.RelationshipEntry{entry},
+		})
+		return err
+	}
+
+	assert.Error(t, update(relationship("2020-01-01T00:00:00Z", "2019-01-01T00:00:00Z")))
+	assert.Equal(t, relationships, server.RelationshipCount())
+
+	// Ending an existing relationship is checked against its start as well
+	require.NoError(t, update(relationship("2020-01-01T00:00:00Z", "")))
+	assert.Error(t, update(relationship("", "2019-01-01T00:00:00Z")))
+	require.NoError(t, update(relationship("", "2020-01-01T00:00:00Z")))
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"orgchart_nexoan/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeFromJournal(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetJournalMode(api.JournalRecord)
+
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9019-01_ADD.csv": header +
+			"9019-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Journals,minister,AS_MINISTER,2020-01-01\n" +
+			"9019-01_tr_02,Minister of Jurnals,minister,Department of Entries,department,AS_DEPARTMENT,2020-01-01\n",
+	})
+
+	// The second transaction names a minister that does not exist
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "9019-01_tr_02")
+
+	entries, err := api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "9019-01_tr_01", entries[0].TransactionID)
+	assert.Equal(t, []string{"ADD/9019-01_tr_01_minister"}, entries[0].Entities)
+	assert.Len(t, entries[0].Relationships, 1)
+	assert.Equal(t, 1, entries[0].Counters["minister"])
+	assert.Equal(t, 3, server.EntityCount())
+
+	// Fix the failed row and resume: the minister is not added twice
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "9019-01_ADD.csv"), []byte(header+
+		"9019-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Journals,minister,AS_MINISTER,2020-01-01\n"+
+		"9019-01_tr_02,Minister of Journals,minister,Department of Entries,department,AS_DEPARTMENT,2020-01-01\n"), 0o644))
+	isolated.SetJournalMode(api.JournalResume)
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	assert.Equal(t, 4, server.EntityCount())
+	departmentIDs := relatedIDs(t, isolated, "ADD/9019-01_tr_01_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "")
+	assert.Len(t, departmentIDs, 1)
+
+	entries, err = api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "9019-01_tr_02", entries[1].TransactionID)
+	assert.Equal(t, departmentIDs, entries[1].Entities)
+
+	// Resuming a finished load applies nothing
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	assert.Equal(t, 4, server.EntityCount())
+}
+
+func TestResumeRejectsChangedTransactions(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetJournalMode(api.JournalRecord)
+
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9019-02_ADD.csv": header +
+			"9019-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Hashes,minister,AS_MINISTER,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	// The recorded row is edited after it was applied
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "9019-02_ADD.csv"), []byte(header+
+		"9019-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Hashing,minister,AS_MINISTER,2020-01-01\n"), 0o644))
+	isolated.SetJournalMode(api.JournalResume)
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "9019-02_tr_01 changed")
+	assert.Equal(t, 3, server.EntityCount())
+
+	// The journal still records the original row
+	entries, err := api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestJournalLeavesSkippedTransactions(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetJournalMode(api.JournalRecord)
+
+	header := "transaction_id,parent,parent_type,child,child_type,rel_type,date\n"
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9019-03_ADD.csv": header +
+			"9019-03_tr_01,Ranil Wickremesinghe,citizen,Minister of Skips,minister,AS_MINISTER,2020-01-01\n" +
+			"9019-03_tr_02,Minister of Skips,minister,Skip Tracer,citizen,AS_APPOINTED,2020-01-01\n",
+	})
+
+	// An organisation load skips the appointment, so the journal leaves it for a person load
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	entries, err := api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "9019-03_tr_01", entries[0].TransactionID)
+
+	isolated.SetJournalMode(api.JournalResume)
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "person"))
+	assert.Len(t, relatedIDs(t, isolated, "ADD/9019-03_tr_01_minister", api.DirectionOutgoing, "AS_APPOINTED", ""), 1)
+	assert.Equal(t, 4, server.EntityCount())
+
+	entries, err = api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "9019-03_tr_02", entries[1].TransactionID)
+}
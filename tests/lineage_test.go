@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lineageNames returns the names of nodes
+func lineageNames(nodes []*api.LineageNode) []string {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestGetLineage(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Credit", "minister", "9015-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Banking", "minister", "9015-01_tr_02", map[string]int{"minister": 1})
+
+	_, err := isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Credit",
+		"new":            "Minister of Credit Information",
+		"type":           "minister",
+		"date":           "2021-01-01",
+		"transaction_id": "9015-02_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 2})
+	require.NoError(t, err)
+	_, err = isolated.MergeMinisters(map[string]interface{}{
+		"old":            "[Minister of Credit Information;Minister of Banking]",
+		"new":            "Minister of Finance and Credit",
+		"type":           "minister",
+		"date":           "2022-01-01",
+		"transaction_id": "9015-03_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 3})
+	require.NoError(t, err)
+
+	// Forwards: what is the minister called today
+	lineage, err := isolated.GetLineageByName("Ranil Wickremesinghe", "Minister of Credit", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Minister of Credit", lineage.Entity.Name)
+	assert.Empty(t, lineage.Predecessors)
+	require.Len(t, lineage.Successors, 1)
+	assert.Equal(t, "RENAMED_TO", lineage.Successors[0].Relation)
+	assert.Equal(t, "2021-01-01T00:00:00Z", lineage.Successors[0].Date)
+	require.Len(t, lineage.Successors[0].Children, 1)
+	assert.Equal(t, "MERGED_INTO", lineage.Successors[0].Children[0].Relation)
+	assert.Equal(t, []string{"Minister of Finance and Credit"}, lineageNames(lineage.Current()))
+
+	// Backwards: the predecessors fan in to the merged minister
+	lineage, err = isolated.GetLineageByName("Ranil Wickremesinghe", "Minister of Finance and Credit", "", nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Minister of Credit Information", "Minister of Banking"}, lineageNames(lineage.Predecessors))
+	assert.ElementsMatch(t, []string{"Minister of Credit", "Minister of Banking"}, lineageNames(lineage.Origins()))
+	assert.Equal(t, []string{"Minister of Finance and Credit"}, lineageNames(lineage.Current()))
+
+	// AsOf leaves out later changes, and Direction limits the walk
+	lineage, err = isolated.GetLineageByName("Ranil Wickremesinghe", "Minister of Credit", "", &api.LineageOptions{AsOf: "2021-06-01T00:00:00Z"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Minister of Credit Information"}, lineageNames(lineage.Current()))
+	lineage, err = isolated.GetLineageByName("Ranil Wickremesinghe", "Minister of Credit Information", "", &api.LineageOptions{Direction: api.DirectionIncoming})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Minister of Credit"}, lineageNames(lineage.Predecessors))
+	assert.Empty(t, lineage.Successors)
+
+	var text bytes.Buffer
+	require.NoError(t, lineage.WriteText(&text))
+	assert.Contains(t, text.String(), "2021-01-01 RENAMED_TO Minister of Credit [minister ")
+
+	_, err = isolated.GetLineageByName("Ranil Wickremesinghe", "Minister of Nothing", "", nil)
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+}
+
+func TestGetLineageStopsAtCycles(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Going", "minister", "9015-04_tr_01", map[string]int{"minister": 0})
+	_, err := isolated.RenameMinister(map[string]interface{}{
+		"old":            "Minister of Going",
+		"new":            "Minister of Coming",
+		"type":           "minister",
+		"date":           "2021-01-01",
+		"transaction_id": "9015-05_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 1})
+	require.NoError(t, err)
+
+	going, err := isolated.GetMinisterByPresident("Ranil Wickremesinghe", "Minister of Going", "")
+	require.NoError(t, err)
+	coming, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Coming", "")
+	require.NoError(t, err)
+
+	// A bad gazette renames the minister back
+	_, err = isolated.UpdateEntity(coming.ID, &models.Entity{
+		ID: coming.ID,
+		Relationships: []models.RelationshipEntry{{
+			Key: "cycle",
+			Value: models.Relationship{
+				ID:              "cycle",
+				RelatedEntityID: going.ID,
+				Name:            "RENAMED_TO",
+				StartTime:       "2022-01-01T00:00:00Z",
+			},
+		}},
+	})
+	require.NoError(t, err)
+
+	lineage, err := isolated.GetLineage(going.ID, nil)
+	require.NoError(t, err)
+	require.Len(t, lineage.Successors, 1)
+	require.Len(t, lineage.Successors[0].Children, 1)
+	assert.Equal(t, going.ID, lineage.Successors[0].Children[0].ID)
+	assert.True(t, lineage.Successors[0].Children[0].Repeated)
+	assert.Empty(t, lineage.Successors[0].Children[0].Children)
+	require.Len(t, lineage.Predecessors, 1)
+	assert.Equal(t, coming.ID, lineage.Predecessors[0].ID)
+}
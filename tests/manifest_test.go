@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"orgchart_nexoan/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPointer(n int) *int {
+	return &n
+}
+
+func TestParseLoadScript(t *testing.T) {
+	script := `#!/bin/bash
+
+# Load the gazettes
+./orgchart -data "$(pwd)/data/documents/Test President/person/" -init -type document
+
+# Load the first cabinet
+./orgchart -cache -data "$(pwd)/data/orgchart/Test President/2021-01-01/"
+./orgchart -data $(pwd)/data/people/Test\ President/2021-01-01 -type person # appoint the ministers
+# ./orgchart -data "$(pwd)/data/orgchart/Test President/2021-02-01/" -type=organisation
+echo "done"
+`
+	manifest, err := api.ParseLoadScript(strings.NewReader(script))
+	require.NoError(t, err)
+	require.Len(t, manifest.Steps, 4)
+
+	assert.Equal(t, api.ManifestStep{Path: "data/documents/Test President/person", Type: "document", Init: true, Note: "Load the gazettes"}, manifest.Steps[0])
+	assert.Equal(t, api.ManifestStep{Path: "data/orgchart/Test President/2021-01-01", Type: "organisation", Note: "Load the first cabinet"}, manifest.Steps[1])
+	assert.Equal(t, "person", manifest.Steps[2].Type)
+	assert.Equal(t, "appoint the ministers", manifest.Steps[2].Note)
+	assert.True(t, manifest.Steps[3].Skip)
+	assert.Equal(t, "data/orgchart/Test President/2021-02-01", manifest.Steps[3].Path)
+
+	_, err = api.ParseLoadScript(strings.NewReader("./orgchart -data x -resume\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: unsupported flag -resume")
+}
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "load.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`steps:
+  - path: data/orgchart/Test President/2021-01-01
+    president: Ranil Wickremesinghe
+    continueOnError: true
+    expect:
+      transactions: 2
+      entities: 0
+`), 0o644))
+
+	manifest, err := api.ReadManifest(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, dir, manifest.Dir)
+	require.Len(t, manifest.Steps, 1)
+	assert.Equal(t, "Ranil Wickremesinghe", manifest.Steps[0].President)
+	assert.True(t, manifest.Steps[0].ContinueOnError)
+	assert.Equal(t, &api.ManifestCounts{Transactions: intPointer(2), Entities: intPointer(0)}, manifest.Steps[0].Expect)
+
+	jsonPath := filepath.Join(dir, "load.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"steps": [{"path": "a", "contineOnError": true}]}`), 0o644))
+	_, err = api.ReadManifest(jsonPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "contineOnError")
+}
+
+func TestRunManifest(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	root := writeDataRoot(t, map[string]string{
+		"orgchart/Anyone/2021-01-01/9023-01_ADD.csv": historyHeader +
+			"9023-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Manifests,minister,AS_MINISTER,2021-01-01\n" +
+			"9023-01_tr_02,Minister of Manifests,minister,Department of Steps,department,AS_DEPARTMENT,2021-01-01\n",
+		"orgchart/Ranil Wickremesinghe/2021-01-02/9023-02_ADD.csv": historyHeader +
+			"9023-02_tr_01,Minister of Nowhere,minister,Department of Gaps,department,AS_DEPARTMENT,2021-01-02\n",
+		"orgchart/Ranil Wickremesinghe/2021-01-03/9023-03_ADD.csv": "not,a,transaction\n",
+		"people/Ranil Wickremesinghe/2021-01-04/9023-04_ADD.csv": historyHeader +
+			"9023-04_tr_01,Minister of Manifests,minister,Stepper,citizen,AS_APPOINTED,2021-01-04\n",
+	})
+
+	manifest := &api.Manifest{Dir: root, Steps: []api.ManifestStep{
+		{Path: "orgchart/Anyone/2021-01-01", Init: true, President: "Ranil Wickremesinghe",
+			Expect: &api.ManifestCounts{Transactions: intPointer(2), Entities: intPointer(2), Relationships: intPointer(2)}},
+		{Path: "orgchart/Ranil Wickremesinghe/2021-01-02", ContinueOnError: true},
+		{Path: "orgchart/Ranil Wickremesinghe/2021-01-03", Skip: true},
+		{Path: "people/Ranil Wickremesinghe/2021-01-04"},
+	}}
+	result, err := isolated.RunManifest(manifest)
+	require.NoError(t, err)
+
+	statuses := []string{}
+	for _, step := range result.Steps {
+		statuses = append(statuses, step.Status)
+	}
+	assert.Equal(t, []string{api.StepLoaded, api.StepFailed, api.StepSkipped, api.StepLoaded}, statuses)
+	assert.Equal(t, "person", result.Steps[3].ProcessType)
+	assert.Equal(t, 2, result.Steps[0].Entities)
+	assert.Contains(t, result.Steps[1].Error, "Minister of Nowhere")
+	assert.Equal(t, "", isolated.President())
+
+	// Running it again applies nothing, so only the transactions are checked
+	result, err = isolated.RunManifest(manifest)
+	require.NoError(t, err)
+	assert.Equal(t, api.StepLoaded, result.Steps[0].Status)
+	assert.Equal(t, 0, result.Steps[0].Entities)
+}
+
+func TestRunManifestStopsOnUnexpectedCounts(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	root := writeDataRoot(t, map[string]string{
+		"orgchart/Ranil Wickremesinghe/2021-01-01/9023-10_ADD.csv": historyHeader +
+			"9023-10_tr_01,Ranil Wickremesinghe,citizen,Minister of Counts,minister,AS_MINISTER,2021-01-01\n",
+		"orgchart/Ranil Wickremesinghe/2021-01-02/9023-11_ADD.csv": historyHeader +
+			"9023-11_tr_01,Ranil Wickremesinghe,citizen,Minister of Later,minister,AS_MINISTER,2021-01-02\n",
+	})
+
+	manifest := &api.Manifest{Dir: root, Steps: []api.ManifestStep{
+		{Path: "orgchart/Ranil Wickremesinghe/2021-01-01", Expect: &api.ManifestCounts{Transactions: intPointer(3)}},
+		{Path: "orgchart/Ranil Wickremesinghe/2021-01-02"},
+	}}
+	result, err := isolated.RunManifest(manifest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected 3 transactions, got 1")
+	assert.Equal(t, api.StepFailed, result.Steps[0].Status)
+	assert.Equal(t, api.StepNotRun, result.Steps[1].Status)
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Later", "2021-01-03T00:00:00Z")
+	assert.Error(t, err)
+
+	// Steps are checked before anything is loaded
+	entities := server.EntityCount()
+	manifest.Steps = append(manifest.Steps, api.ManifestStep{Path: "orgchart/Ranil Wickremesinghe/2099-01-01"})
+	_, err = isolated.RunManifest(manifest)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "step 3: data directory does not exist")
+	assert.Equal(t, entities, server.EntityCount())
+}
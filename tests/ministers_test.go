@@ -3,28 +3,45 @@ package tests
 import (
 	"fmt"
 	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
 	"orgchart_nexoan/models"
 	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-var client *api.Client
+// liveClient is the client of the live Nexoan instance the suite runs against, if any
+var liveClient *api.Client
 
 func TestMain(m *testing.M) {
-	// Set up test environment with correct URLs
-	client = api.NewClient("http://localhost:8080/entities", "http://localhost:8081/v1/entities")
+	// Run against a live Nexoan instance when both endpoints are given. Its graph is seeded once and
+	// shared by every test; otherwise each test gets a fresh in-memory fake from newTestClient.
+	if os.Getenv("NEXOAN_UPDATE_URL") != "" && os.Getenv("NEXOAN_QUERY_URL") != "" {
+		var err error
+		liveClient, err = api.NewClient(os.Getenv("NEXOAN_UPDATE_URL"), os.Getenv("NEXOAN_QUERY_URL"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if err := seedGraph(liveClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(m.Run())
+}
 
+// seedGraph creates the government node and the president every test builds on
+func seedGraph(client *api.Client) error {
 	// Create government node using CreateGovernmentNode
 	government, err := client.CreateGovernmentNode()
 	if err != nil {
-		fmt.Printf("Failed to create government node: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create government node: %w", err)
 	}
 	if government == nil {
-		fmt.Println("Government node is nil")
-		os.Exit(1)
+		return fmt.Errorf("government node is nil")
 	}
 	fmt.Printf("Successfully created government node with ID: %s\n", government.ID)
 
@@ -41,17 +58,72 @@ func TestMain(m *testing.M) {
 	}
 	_, err = client.AddPersonEntity(presidentTransaction, entityCounters)
 	if err != nil {
-		fmt.Printf("Failed to create president node: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("failed to create president node: %w", err)
 	}
 	fmt.Println("Successfully created president node: Ranil Wickremesinghe")
 
-	// Run tests
-	code := m.Run()
-	os.Exit(code)
+	return nil
+}
+
+// newClient creates a client with the given options, failing the test if they cannot be combined
+func newClient(t *testing.T, updateURL, queryURL string, opts ...api.Option) *api.Client {
+	t.Helper()
+	client, err := api.NewClient(updateURL, queryURL, opts...)
+	require.NoError(t, err)
+	return client
+}
+
+// newIsolatedClient starts a fresh fake server seeded with the government and president nodes,
+// so a test can work on its own graph without seeing what other tests created
+func newIsolatedClient(t *testing.T) (*api.Client, *apitest.Server) {
+	t.Helper()
+	server := apitest.NewServer()
+	t.Cleanup(server.Close)
+
+	isolated := newClient(t, server.UpdateURL(), server.QueryURL())
+	if err := seedGraph(isolated); err != nil {
+		t.Fatal(err)
+	}
+	return isolated, server
 }
 
-func TestCreateMinisters(t *testing.T) {
+// newTestClient returns the client a test works with: that of a fresh fake server seeded with the
+// government and president nodes, or the live client when the suite runs against a live instance
+func newTestClient(t *testing.T) *api.Client {
+	t.Helper()
+	if liveClient != nil {
+		return liveClient
+	}
+	isolated, _ := newIsolatedClient(t)
+	return isolated
+}
+
+// TestMinisterLifecycle runs the minister and department steps in order on one graph, as each works
+// on the entities the steps before it left
+func TestMinisterLifecycle(t *testing.T) {
+	client := newTestClient(t)
+
+	steps := []struct {
+		name string
+		run  func(*testing.T, *api.Client)
+	}{
+		{"CreateMinisters", testCreateMinisters},
+		{"CreateDepartments", testCreateDepartments},
+		{"TerminateDepartment", testTerminateDepartment},
+		{"TerminateMinister", testTerminateMinister},
+		{"MoveDepartment", testMoveDepartment},
+		{"RenameMinister", testRenameMinister},
+		{"RenameDepartment", testRenameDepartment},
+		{"MergeMinisters", testMergeMinisters},
+	}
+	for _, step := range steps {
+		if !t.Run(step.name, func(t *testing.T) { step.run(t, client) }) {
+			return
+		}
+	}
+}
+
+func testCreateMinisters(t *testing.T, client *api.Client) {
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"minister": 0,
@@ -140,7 +212,7 @@ func TestCreateMinisters(t *testing.T) {
 	}
 }
 
-func TestCreateDepartments(t *testing.T) {
+func testCreateDepartments(t *testing.T, client *api.Client) {
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"department": 0,
@@ -247,7 +319,7 @@ func TestCreateDepartments(t *testing.T) {
 	}
 }
 
-func TestTerminateDepartment(t *testing.T) {
+func testTerminateDepartment(t *testing.T, client *api.Client) {
 	// Create transaction map for terminating the department
 	transaction := map[string]interface{}{
 		"parent":      "Minister of Defence",
@@ -297,7 +369,7 @@ func TestTerminateDepartment(t *testing.T) {
 	assert.Equal(t, "2024-01-01T00:00:00Z", relations[0].EndTime, "Relationship should be terminated")
 }
 
-func TestTerminateMinister(t *testing.T) {
+func testTerminateMinister(t *testing.T, client *api.Client) {
 	// Create transaction map for terminating the minister
 	transaction := map[string]interface{}{
 		"parent":      "Ranil Wickremesinghe",
@@ -366,7 +438,7 @@ func TestTerminateMinister(t *testing.T) {
 	assert.Equal(t, "2024-01-01T00:00:00Z", relations[0].EndTime, "Relationship should be terminated")
 }
 
-func TestMoveDepartment(t *testing.T) {
+func testMoveDepartment(t *testing.T, client *api.Client) {
 	// First create a new minister
 	entityCounters := map[string]int{
 		"minister": 2, // Since we already have 2 ministers from previous tests
@@ -467,7 +539,7 @@ func TestMoveDepartment(t *testing.T) {
 	assert.Equal(t, "2024-01-01T00:00:00Z", oldRelations[0].EndTime, "Relationship should be terminated")
 }
 
-func TestRenameMinister(t *testing.T) {
+func testRenameMinister(t *testing.T, client *api.Client) {
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"minister": 2,
@@ -594,7 +666,7 @@ func TestRenameMinister(t *testing.T) {
 	assert.Greater(t, len(activeNewDeptRelations), 0, "New minister should have active departments")
 }
 
-func TestRenameDepartment(t *testing.T) {
+func testRenameDepartment(t *testing.T, client *api.Client) {
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"department": 0,
@@ -713,7 +785,7 @@ func TestRenameDepartment(t *testing.T) {
 	assert.Equal(t, "2024-02-02T00:00:00Z", activeNewMinRelations[0].StartTime)
 }
 
-func TestMergeMinisters(t *testing.T) {
+func testMergeMinisters(t *testing.T, client *api.Client) {
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"minister": 0, // Since we already have 3 ministers from previous tests
@@ -886,6 +958,8 @@ func TestMergeMinisters(t *testing.T) {
 }
 
 func TestTerminateNonExistentMinister(t *testing.T) {
+	client := newTestClient(t)
+
 	// Create transaction map for terminating a non-existent minister
 	transaction := map[string]interface{}{
 		"parent":      "Ranil Wickremesinghe",
@@ -902,6 +976,8 @@ func TestTerminateNonExistentMinister(t *testing.T) {
 }
 
 func TestTerminateMinisterWithChildren(t *testing.T) {
+	client := newTestClient(t)
+
 	// First create a minister with a department
 	entityCounters := map[string]int{
 		"minister":   0,
@@ -965,14 +1041,17 @@ func TestTerminateMinisterWithChildren(t *testing.T) {
 		"rel_type":    "AS_MINISTER",
 	}
 
-	// fmt.Printf("Debug: Attempting to terminate minister with transaction: %+v\n", terminateTransaction)
+	// Terminating a minister with active departments is allowed, so that ministers can be moved. Only
+	// the AS_MINISTER relationship ends; the departments stay with the minister.
 	err = client.TerminateOrgEntity(terminateTransaction)
-	assert.Error(t, err)
-	// assert.Contains(t, err.Error(), "cannot terminate minister with active departments")
-
+	require.NoError(t, err)
+	assert.NotContains(t, relatedIDs(t, client, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2025-01-03T00:00:00Z"), ministerID)
+	assert.Len(t, relatedIDs(t, client, ministerID, api.DirectionOutgoing, "AS_DEPARTMENT", "2025-01-03T00:00:00Z"), 1)
 }
 
 func TestMoveDepartmentToNonExistentMinister(t *testing.T) {
+	client := newTestClient(t)
+
 	// Create transaction map for moving department to non-existent minister
 	transaction := map[string]interface{}{
 		"old_parent":         "Minister of Finance and Education",
@@ -990,6 +1069,8 @@ func TestMoveDepartmentToNonExistentMinister(t *testing.T) {
 }
 
 func TestMergeNonExistentMinister(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"minister": 0,
@@ -1011,6 +1092,8 @@ func TestMergeNonExistentMinister(t *testing.T) {
 }
 
 func TestCreateDuplicateMinister(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	entityCounters := map[string]int{
 		"minister": 0,
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
+	"orgchart_nexoan/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headerRecorder answers every search with no results and keeps the headers of the last request
+type headerRecorder struct {
+	mu      sync.Mutex
+	headers http.Header
+}
+
+func (h *headerRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	h.headers = r.Header.Clone()
+	h.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"body":[]}`))
+}
+
+func (h *headerRecorder) last() http.Header {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.headers
+}
+
+func TestOptionsHeadersAndToken(t *testing.T) {
+	recorder := &headerRecorder{}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	optioned := newClient(t, server.URL, server.URL,
+		api.WithBearerToken("secret-token"),
+		api.WithHeader("User-Agent", "orgchart-loader-test"),
+		api.WithHeader("X-Request-Source", "tests"),
+	)
+	_, err := optioned.SearchEntities(&models.SearchCriteria{ID: "anything"})
+	require.NoError(t, err)
+
+	headers := recorder.last()
+	assert.Equal(t, "Bearer secret-token", headers.Get("Authorization"))
+	assert.Equal(t, "orgchart-loader-test", headers.Get("User-Agent"))
+	assert.Equal(t, "tests", headers.Get("X-Request-Source"))
+	assert.Equal(t, "application/json", headers.Get("Content-Type"))
+}
+
+func TestOptionsTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(&headerRecorder{})
+	t.Cleanup(server.Close)
+
+	// The test server's certificate is not trusted by default
+	untrusted := newClient(t, server.URL, server.URL)
+	untrusted.SetRetryPolicy(api.NoRetryPolicy())
+	_, err := untrusted.SearchEntities(&models.SearchCriteria{ID: "anything"})
+	assert.Error(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	trusted := newClient(t, server.URL, server.URL, api.WithTLSConfig(&tls.Config{RootCAs: pool}))
+	_, err = trusted.SearchEntities(&models.SearchCriteria{ID: "anything"})
+	assert.NoError(t, err)
+}
+
+// roundTripperFunc is a transport that is not an *http.Transport, like a tracing or retrying wrapper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestOptionsTLSConfigNeedsHTTPTransport(t *testing.T) {
+	// A TLS configuration cannot be set on a wrapping transport, which is not silently replaced
+	wrapper := roundTripperFunc(http.DefaultTransport.RoundTrip)
+	_, err := api.NewClient("unused", "unused",
+		api.WithHTTPClient(&http.Client{Transport: wrapper}), api.WithTLSConfig(&tls.Config{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot apply the TLS configuration")
+
+	// An *http.Transport takes it, on a copy
+	transport := &http.Transport{}
+	config := &tls.Config{}
+	newClient(t, "unused", "unused", api.WithHTTPClient(&http.Client{Transport: transport}), api.WithTLSConfig(config))
+	assert.NotSame(t, config, transport.TLSClientConfig)
+}
+
+func TestOptionsTimeoutAndHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"body":[]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	// The timeout applies to a copy of the given HTTP client, which is left untouched
+	httpClient := &http.Client{Timeout: time.Minute}
+	optioned := newClient(t, server.URL, server.URL, api.WithHTTPClient(httpClient), api.WithTimeout(20*time.Millisecond))
+	optioned.SetRetryPolicy(api.NoRetryPolicy())
+	_, err := optioned.SearchEntities(&models.SearchCriteria{ID: "anything"})
+	assert.Error(t, err)
+	assert.Equal(t, time.Minute, httpClient.Timeout)
+}
+
+func TestOptionsLogger(t *testing.T) {
+	server := apitest.NewServer()
+	t.Cleanup(server.Close)
+
+	var output bytes.Buffer
+	optioned := newClient(t, server.UpdateURL(), server.QueryURL(), api.WithLogger(log.New(&output, "nexoan: ", 0)))
+	optioned.SetRetryPolicy(fastRetryPolicy())
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/v1/entities/search", Status: http.StatusServiceUnavailable, Count: 1})
+
+	_, err := optioned.SearchEntities(&models.SearchCriteria{ID: "anything"})
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "nexoan: Retrying POST")
+}
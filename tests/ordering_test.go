@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"testing"
+
+	"orgchart_nexoan/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTransactionID(t *testing.T) {
+	testCases := []struct {
+		id       string
+		expected api.TransactionID
+	}{
+		{"2403-38-2_tr_05", api.TransactionID{Gazette: []int{2403, 38}, Part: 2, Sequence: 5, Parsed: true}},
+		{"2156/15_tr_03", api.TransactionID{Gazette: []int{2156, 15}, Sequence: 3, Parsed: true}},
+		{"2156-15_tr_03", api.TransactionID{Gazette: []int{2156, 15}, Sequence: 3, Parsed: true}},
+		{"2289-34", api.TransactionID{Gazette: []int{2289, 34}, Parsed: true}},
+		{"1120_00_tr_1", api.TransactionID{Gazette: []int{1120, 0}, Sequence: 1, Parsed: true}},
+		{"", api.TransactionID{}},
+		{"gazette_tr_01", api.TransactionID{}},
+		{"2289-34_tr_x", api.TransactionID{}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, api.ParseTransactionID(tc.id), tc.id)
+	}
+
+	assert.Negative(t, api.ParseTransactionID("2156/9_tr_10").Compare(api.ParseTransactionID("2156-15_tr_02")))
+	assert.Negative(t, api.ParseTransactionID("2289-34").Compare(api.ParseTransactionID("2289-34_tr_01")))
+	assert.Negative(t, api.ParseTransactionID("2289-34_tr_99").Compare(api.ParseTransactionID("2289-34-1_tr_01")))
+	assert.Negative(t, api.ParseTransactionID("2289-34_tr_01").Compare(api.ParseTransactionID("unnumbered")))
+}
+
+func TestSortTransactions(t *testing.T) {
+	transaction := func(id, date, sequence string) map[string]interface{} {
+		row := map[string]interface{}{"transaction_id": id, "date": date}
+		if sequence != "" {
+			row[api.SequenceColumn] = sequence
+		}
+		return row
+	}
+	transactions := []map[string]interface{}{
+		transaction("2156-15_tr_02", "2020-02-01", ""),
+		transaction("", "2020-01-01", ""),
+		transaction("2156/15_tr_10", "2020-01-01", ""),
+		transaction("2289-34", "2020-01-01", ""),
+		transaction("2156/15_tr_09", "2020-01-01", ""),
+		transaction("2156-15_tr_01", "2020-02-01", "2"),
+		transaction("2156-15_tr_03", "2020-02-01", "1"),
+		transaction("2156/9_tr_01", "2020-01-01", ""),
+	}
+	require.NoError(t, api.SortTransactions(transactions))
+
+	ids := []string{}
+	for _, transaction := range transactions {
+		ids = append(ids, transaction["transaction_id"].(string))
+	}
+	assert.Equal(t, []string{
+		"2156/9_tr_01", "2156/15_tr_09", "2156/15_tr_10", "2289-34", "",
+		"2156-15_tr_02", "2156-15_tr_03", "2156-15_tr_01",
+	}, ids)
+
+	err := api.SortTransactions([]map[string]interface{}{transaction("2156-15_tr_01", "2020-01-01", "first")})
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, api.SequenceColumn, invalid.Field)
+}
+
+func TestSequenceColumn(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	// The department is added before its minister by ID, but the sequence column says otherwise
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9025-01_ADD.csv": "transaction_id,sequence,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9025-01_tr_01,2,Minister of Sequences,minister,Department of Steps,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9025-01_tr_02,1,Ranil Wickremesinghe,citizen,Minister of Sequences,minister,AS_MINISTER,2020-01-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-02-01T00:00:00Z")
+	require.Equal(t, []string{"ADD/9025-01_tr_02_minister"}, ministerIDs)
+	assert.Equal(t, []string{"ADD/9025-01_tr_01_department"}, relatedIDs(t, isolated, "ADD/9025-01_tr_02_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-02-01T00:00:00Z"))
+}
+
+func TestOrderingDependencies(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+
+	// The department moves into a minister that is only added after the move
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9025-02_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9025-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Origins,minister,AS_MINISTER,2020-01-01\n" +
+			"9025-02_tr_02,Minister of Origins,minister,Department of Travel,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9025-02_tr_04,Ranil Wickremesinghe,citizen,Minister of Destinations,minister,AS_MINISTER,2020-02-01\n",
+		"9025-02_MOVE.csv": "transaction_id,old_parent,old_president_name,new_parent,new_president_name,child,type,date\n" +
+			"9025-02_tr_03,Minister of Origins,Ranil Wickremesinghe,Minister of Destinations,Ranil Wickremesinghe,Department of Travel,department,2020-02-01\n",
+	})
+	entities := server.EntityCount()
+
+	isolated.SetDependencyPolicy(api.DependencyFail)
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transaction 9025-02_tr_03 needs 'Minister of Destinations', which transaction 9025-02_tr_04 only adds later")
+	assert.Equal(t, entities, server.EntityCount())
+
+	isolated.SetDependencyPolicy(api.DependencyReorder)
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-03-01T00:00:00Z")
+	require.Len(t, ministerIDs, 2)
+	assert.Len(t, relatedIDs(t, isolated, "ADD/9025-02_tr_04_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-03-01T00:00:00Z"), 1)
+	assert.Empty(t, relatedIDs(t, isolated, "ADD/9025-02_tr_01_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-03-01T00:00:00Z"))
+
+	// A TERMINATE followed by an ADD of the same name is a reshuffle, not a dependency
+	reshuffle := []map[string]interface{}{
+		{"transaction_id": "9025-03_tr_01", "file_type": "TERMINATE", "parent": "Ranil Wickremesinghe", "child": "Minister of Cycles"},
+		{"transaction_id": "9025-03_tr_02", "file_type": "ADD", "parent": "Ranil Wickremesinghe", "child": "Minister of Cycles"},
+		{"transaction_id": "9025-03_tr_03", "file_type": "ADD", "parent": "Minister of Cycles", "child": "Department of Wheels"},
+	}
+	assert.Empty(t, api.FindOrderingDependencies(reshuffle))
+
+	// Dependencies across dates cannot be reordered
+	dataDir = writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9025-04_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9025-04_tr_01,Minister of Tomorrow,minister,Department of Today,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9025-04_tr_02,Ranil Wickremesinghe,citizen,Minister of Tomorrow,minister,AS_MINISTER,2020-01-02\n",
+	})
+	err = isolated.ProcessTransactions(dataDir, "organisation")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Minister of Tomorrow")
+}
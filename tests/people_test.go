@@ -9,6 +9,8 @@ import (
 
 // Add your people-specific test functions here
 func TestCreatePeople(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
@@ -187,6 +189,8 @@ func TestCreatePeople(t *testing.T) {
 }
 
 func TestCreatePeopleWithManyMinisters(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
@@ -422,6 +426,8 @@ func TestCreatePeopleWithManyMinisters(t *testing.T) {
 }
 
 func TestTerminatePerson(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
@@ -640,6 +646,8 @@ func TestTerminatePerson(t *testing.T) {
 }
 
 func TestTerminateMultipleMinistersForPerson(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
@@ -891,6 +899,8 @@ func TestTerminateMultipleMinistersForPerson(t *testing.T) {
 }
 
 func TestMovePerson(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
@@ -1096,6 +1106,8 @@ func TestMovePerson(t *testing.T) {
 }
 
 func TestSwapMultiplePeople(t *testing.T) {
+	client := newTestClient(t)
+
 	// Initialize entity counters
 	ministerEntityCounters := map[string]int{
 		"minister": 0,
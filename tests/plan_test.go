@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/memgraph"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPlanner returns a client of an empty in-memory graph to plan loads against, reached
+// in-process as -plan does
+func newPlanner(t *testing.T) (*api.Client, *memgraph.Graph) {
+	t.Helper()
+	graph := memgraph.New()
+	return newClient(t, memgraph.UpdateURL, memgraph.QueryURL, api.WithHTTPClient(&http.Client{Transport: graph.Transport()})), graph
+}
+
+func TestPlanTransactions(t *testing.T) {
+	live, liveServer := newIsolatedClient(t)
+	addCacheEntity(t, live, "Ranil Wickremesinghe", "Minister of Plans", "minister", "9020-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, live, "Minister of Plans", "Department of Drafts", "department", "9020-01_tr_02", map[string]int{"department": 0})
+	liveEntities, liveRelationships := liveServer.EntityCount(), liveServer.RelationshipCount()
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9020-02_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9020-02_tr_01,Minister of Plans,minister,Department of Outlines,department,AS_DEPARTMENT,2021-01-01\n" +
+			"9020-02_tr_02,Minister of Nothing,minister,Department of Ghosts,department,AS_DEPARTMENT,2021-01-01\n",
+		"9020-02_TERMINATE.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9020-02_tr_03,Minister of Plans,minister,Department of Drafts,department,AS_DEPARTMENT,2021-01-01\n",
+	})
+
+	planner, graph := newPlanner(t)
+	require.NoError(t, planner.SeedFromClient(live))
+	assert.Equal(t, liveEntities, graph.EntityCount())
+	assert.Equal(t, liveRelationships, graph.RelationshipCount())
+	plan, err := planner.PlanTransactions(dataDir, "organisation")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, plan.Transactions)
+	require.Len(t, plan.Failures, 1)
+	assert.Equal(t, "9020-02_tr_02", plan.Failures[0].TransactionID)
+	assert.ErrorIs(t, plan.Failures[0].Err, api.ErrEntityNotFound)
+
+	require.Len(t, plan.Entities, 1)
+	assert.Equal(t, "Department of Outlines", plan.Entities[0].Name)
+	assert.Equal(t, "department", plan.Entities[0].Kind)
+	require.Len(t, plan.Relationships, 1)
+	assert.Equal(t, "AS_DEPARTMENT", plan.Relationships[0].Name)
+	assert.Equal(t, "Minister of Plans", plan.Relationships[0].Parent)
+	assert.Equal(t, "Department of Outlines", plan.Relationships[0].Child)
+	require.Len(t, plan.Ended, 1)
+	assert.Equal(t, "9020-02_tr_03", plan.Ended[0].TransactionID)
+	assert.Equal(t, "Department of Drafts", plan.Ended[0].Child)
+	assert.Equal(t, "2021-01-01T00:00:00Z", plan.Ended[0].EndTime)
+
+	var text bytes.Buffer
+	require.NoError(t, plan.WriteText(&text))
+	assert.Contains(t, text.String(), "Failures (1):\n  9020-02_tr_02: ")
+	assert.Contains(t, text.String(), "  9020-02_tr_03 AS_DEPARTMENT 'Minister of Plans' -> 'Department of Drafts' [")
+
+	// Nothing was written to the live graph
+	assert.Equal(t, liveEntities, liveServer.EntityCount())
+	assert.Equal(t, liveRelationships, liveServer.RelationshipCount())
+
+	// A saved org chart plans the same load without the live graph
+	chart, err := live.GetOrgChart("2020-06-01T00:00:00Z", nil)
+	require.NoError(t, err)
+	planner, _ = newPlanner(t)
+	require.NoError(t, planner.SeedFromOrgChart(chart))
+	fromChart, err := planner.PlanTransactions(dataDir, "organisation")
+	require.NoError(t, err)
+	assert.Len(t, fromChart.Failures, 1)
+	assert.Len(t, fromChart.Entities, 1)
+	assert.Len(t, fromChart.Ended, 1)
+}
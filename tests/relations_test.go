@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAllRelatedEntitiesAsOf(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Before", "minister", "9009-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of After", "minister", "9009-01_tr_02", map[string]int{"minister": 1})
+	addCacheEntity(t, isolated, "Minister of Before", "Department of Moves", "department", "9009-01_tr_03", map[string]int{"department": 0})
+
+	require.NoError(t, isolated.MoveDepartment(map[string]interface{}{
+		"old_parent":         "Minister of Before",
+		"new_parent":         "Minister of After",
+		"child":              "Department of Moves",
+		"type":               "department",
+		"date":               "2023-01-01",
+		"old_president_name": "Ranil Wickremesinghe",
+		"new_president_name": "Ranil Wickremesinghe",
+	}))
+
+	departments, err := isolated.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "department"},
+		Name: "Department of Moves",
+	})
+	require.NoError(t, err)
+	require.Len(t, departments, 1)
+	before, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Before", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+	after, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of After", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+
+	// Which minister held the department on a date, without naming the relation
+	heldBy := func(date string) []string {
+		relations, err := isolated.GetAllRelatedEntities(departments[0].ID, &api.RelationFilter{
+			Direction: api.DirectionIncoming,
+			ActiveAt:  date,
+		})
+		require.NoError(t, err)
+		ids := []string{}
+		for _, rel := range relations {
+			assert.Equal(t, api.DirectionIncoming, rel.Direction)
+			ids = append(ids, rel.RelatedEntityID)
+		}
+		return ids
+	}
+	assert.Equal(t, []string{before.ID}, heldBy("2022-06-01T00:00:00Z"))
+	assert.Equal(t, []string{after.ID}, heldBy("2023-05-30T00:00:00Z"))
+	assert.Empty(t, heldBy("2019-01-01T00:00:00Z"))
+
+	// The whole history in both directions, restricted to a set of names
+	relations, err := isolated.GetAllRelatedEntities(before.ID, &api.RelationFilter{Names: []string{"AS_MINISTER", "AS_DEPARTMENT"}})
+	require.NoError(t, err)
+	assert.Len(t, relations, 2)
+	relations, err = isolated.GetAllRelatedEntities(before.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, relations, 2)
+}
+
+func TestGetRelatedEntitiesDirection(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Directions", "minister", "9009-02_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Directions", "Department of Directions", "department", "9009-02_tr_02", map[string]int{"department": 0})
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Directions", "2020-01-01T00:00:00Z")
+	require.NoError(t, err)
+
+	outgoing, err := isolated.GetRelatedEntities(minister.ID, &models.Relationship{Direction: "outgoing"})
+	require.NoError(t, err)
+	require.Len(t, outgoing, 1)
+	assert.Equal(t, "AS_DEPARTMENT", outgoing[0].Name)
+
+	both, err := isolated.GetRelatedEntities(minister.ID, &models.Relationship{Direction: api.DirectionBoth})
+	require.NoError(t, err)
+	assert.Len(t, both, 2)
+
+	_, err = isolated.GetRelatedEntities(minister.ID, &models.Relationship{Direction: "sideways"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"net/http"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/api/apitest"
+	"orgchart_nexoan/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fastRetryPolicy keeps the default retryable status codes but backs off for milliseconds only
+func fastRetryPolicy() api.RetryPolicy {
+	policy := api.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func addRetryMinister(t *testing.T, client *api.Client) error {
+	t.Helper()
+	_, err := client.AddOrgEntity(map[string]interface{}{
+		"parent":         "Ranil Wickremesinghe",
+		"child":          "Minister of Retries",
+		"date":           "2020-01-01",
+		"parent_type":    "citizen",
+		"child_type":     "minister",
+		"rel_type":       "AS_MINISTER",
+		"transaction_id": "9002-01_tr_01",
+	}, map[string]int{"minister": 0})
+	return err
+}
+
+func countRetryMinisters(t *testing.T, client *api.Client) int {
+	t.Helper()
+	results, err := client.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{
+			Major: "Organisation",
+			Minor: "minister",
+		},
+		Name: "Minister of Retries",
+	})
+	require.NoError(t, err)
+	return len(results)
+}
+
+func TestRetryTransientFailure(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetRetryPolicy(fastRetryPolicy())
+	relationshipsBefore := server.RelationshipCount()
+	createsBefore := server.RequestCount(http.MethodPost, "/entities")
+
+	// Both the create and the relationship update fail twice before they get through
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/entities", Status: http.StatusBadGateway, Count: 2})
+	server.InjectFault(apitest.Fault{Method: http.MethodPut, PathPrefix: "/entities/", Status: http.StatusServiceUnavailable, Count: 2})
+
+	require.NoError(t, addRetryMinister(t, isolated))
+	assert.Equal(t, 1, countRetryMinisters(t, isolated))
+	assert.Equal(t, relationshipsBefore+1, server.RelationshipCount())
+	assert.Equal(t, createsBefore+3, server.RequestCount(http.MethodPost, "/entities"))
+}
+
+func TestRetryDoesNotDuplicateLandedWrites(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetRetryPolicy(fastRetryPolicy())
+	relationshipsBefore := server.RelationshipCount()
+	createsBefore := server.RequestCount(http.MethodPost, "/entities")
+	updatesBefore := server.RequestCount(http.MethodPut, "/entities/")
+
+	// Both writes are applied but the client only sees a 502
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/entities", Status: http.StatusBadGateway, Count: 1, AfterApply: true})
+	server.InjectFault(apitest.Fault{Method: http.MethodPut, PathPrefix: "/entities/", Status: http.StatusBadGateway, Count: 1, AfterApply: true})
+
+	require.NoError(t, addRetryMinister(t, isolated))
+	assert.Equal(t, 1, countRetryMinisters(t, isolated))
+	assert.Equal(t, relationshipsBefore+1, server.RelationshipCount(), "the AS_MINISTER relationship must not be duplicated")
+
+	// The landed writes were detected, so neither was sent a second time
+	assert.Equal(t, createsBefore+1, server.RequestCount(http.MethodPost, "/entities"))
+	assert.Equal(t, updatesBefore+1, server.RequestCount(http.MethodPut, "/entities/"))
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	policy := fastRetryPolicy()
+	policy.MaxAttempts = 3
+	isolated.SetRetryPolicy(policy)
+	createsBefore := server.RequestCount(http.MethodPost, "/entities")
+
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/entities", Status: http.StatusBadGateway, Count: 5})
+
+	assert.Error(t, addRetryMinister(t, isolated))
+	assert.Equal(t, createsBefore+3, server.RequestCount(http.MethodPost, "/entities"))
+}
+
+func TestRetrySkipsNonRetryableStatus(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	isolated.SetRetryPolicy(fastRetryPolicy())
+	createsBefore := server.RequestCount(http.MethodPost, "/entities")
+
+	server.InjectFault(apitest.Fault{Method: http.MethodPost, PathPrefix: "/entities", Status: http.StatusInternalServerError, Count: 1})
+
+	assert.Error(t, addRetryMinister(t, isolated))
+	assert.Equal(t, createsBefore+1, server.RequestCount(http.MethodPost, "/entities"))
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectTransactionType(t *testing.T) {
+	handlers := api.BuiltinHandlers()
+	relationship := strings.Split("transaction_id,parent,parent_type,child,child_type,rel_type,date", ",")
+	document := strings.Split("transaction_id,date,url,description,child_type,child,parent_type,parent", ",")
+	move := strings.Split("transaction_id,old_parent,new_parent,child,type,date,old_president_name,new_president_name", ",")
+
+	testCases := []struct {
+		fileName string
+		header   []string
+		expected string
+	}{
+		{"2412_08_TERMINATE.csv", relationship, "TERMINATE"},
+		{"2289-34-2-terminate.csv", relationship, "TERMINATE"},
+		{"ADD.csv", relationship, "ADD"},
+		{"gazettes.csv", document, "ADD"},
+		{"gazette-tracking-person-GR_ADD.csv", document, "ADD"},
+		{"2403-38.csv", move, "MOVE"},
+		{"2403-38_MOVE.csv", append(move, "comments"), "MOVE"},
+		{"mixed.csv", append(relationship, api.ActionColumn), ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.fileName, func(t *testing.T) {
+			transactionType, err := handlers.DetectTransactionType(tc.fileName, tc.header)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, transactionType)
+		})
+	}
+
+	rejected := []struct {
+		fileName string
+		header   []string
+		message  string
+	}{
+		{"2403-38_MOVE.csv", relationship, "is named as a MOVE file but its header is that of ADD or TERMINATE"},
+		{"2403-38.csv", relationship, "fits ADD and TERMINATE"},
+		{"2403-38_ADD.csv", []string{"transaction_id", "parent", "child"}, "matches no transaction schema"},
+		{"2403-38_ADD_TERMINATE.csv", relationship, "names several transaction types"},
+	}
+	for _, tc := range rejected {
+		_, err := handlers.DetectTransactionType(tc.fileName, tc.header)
+		assert.ErrorIs(t, err, api.ErrUnrecognisedFile)
+		assert.ErrorContains(t, err, tc.message)
+	}
+}
+
+func TestUnrecognisedFilesAreRejected(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+	entities := server.EntityCount()
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9024-01_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9024-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Headers,minister,AS_MINISTER,2020-01-01\n",
+		"9024-01_TERMINATE.csv": "transaction_id,old,new,type,date\n" +
+			"9024-01_tr_02,Minister of Headers,Minister of Footers,minister,2020-02-01\n",
+	})
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	assert.ErrorIs(t, err, api.ErrUnrecognisedFile)
+	assert.ErrorContains(t, err, "9024-01_TERMINATE.csv is named as a TERMINATE file but its header is that of MERGE or RENAME or SPLIT")
+	assert.Equal(t, entities, server.EntityCount())
+}
+
+func TestMixedTransactionFile(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9024-02.csv": "transaction_id,action,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9024-02_tr_01,ADD,Ranil Wickremesinghe,citizen,Minister of Mixtures,minister,AS_MINISTER,2020-01-01\n" +
+			"9024-02_tr_02,add,Minister of Mixtures,minister,Department of Blends,department,AS_DEPARTMENT,2020-01-01\n" +
+			"9024-02_tr_03,TERMINATE,Minister of Mixtures,minister,Department of Blends,department,AS_DEPARTMENT,2020-03-01\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	ministerIDs := relatedIDs(t, isolated, "ADD/2152-12_tr_01_citizen", api.DirectionOutgoing, "AS_MINISTER", "2020-02-01T00:00:00Z")
+	require.Equal(t, []string{"ADD/9024-02_tr_01_minister"}, ministerIDs)
+	assert.Equal(t, []string{"ADD/9024-02_tr_02_department"}, relatedIDs(t, isolated, "ADD/9024-02_tr_01_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-02-01T00:00:00Z"))
+	assert.Empty(t, relatedIDs(t, isolated, "ADD/9024-02_tr_01_minister", api.DirectionOutgoing, "AS_DEPARTMENT", "2020-04-01T00:00:00Z"))
+
+	// Rows naming a type whose columns the file does not have are rejected where they are
+	dataDir = writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9024-03.csv": "transaction_id,action,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9024-03_tr_01,ADD,Ranil Wickremesinghe,citizen,Minister of Muddles,minister,AS_MINISTER,2020-01-01\n" +
+			"9024-03_tr_02,RENAME,Minister of Muddles,minister,Minister of Order,minister,,2020-01-01\n",
+	})
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, api.ActionColumn, invalid.Field)
+	assert.Equal(t, 3, invalid.Line)
+	assert.Equal(t, 2, invalid.Column)
+	assert.Contains(t, invalid.Reason, "do not fit RENAME transactions")
+}
+
+func TestDocumentFilesNeedNoTypeInTheirName(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"gazettes.csv": "transaction_id,date,url,description,child_type,child,parent_type,parent\n" +
+			"9024-04,2020-01-01,,Ranil Wickremesinghe,extgztorg,9024-04,government,Government of Sri Lanka\n",
+	})
+	require.NoError(t, isolated.ProcessDocumentTransactions(dataDir, "document"))
+
+	results, err := isolated.SearchEntities(&models.SearchCriteria{Kind: &models.Kind{Major: "Document"}, Name: "9024-04"})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestDocumentsAreLoadedInDateOrder(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	isolated.SetJournalMode(api.JournalRecord)
+
+	// The files are read in name order, the rows are applied in date order
+	header := "transaction_id,date,url,description,child_type,child,parent_type,parent\n"
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"a_gazettes.csv": header + "9024-06,2020-02-01,,Ranil Wickremesinghe,extgztorg,9024-06,government,Government of Sri Lanka\n",
+		"b_gazettes.csv": header + "9024-05,2020-01-01,,Ranil Wickremesinghe,extgztorg,9024-05,government,Government of Sri Lanka\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "document"))
+
+	entries, err := api.ReadJournal(dataDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "9024-05", entries[0].TransactionID)
+	assert.Equal(t, "9024-06", entries[1].TransactionID)
+}
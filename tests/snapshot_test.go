@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"bytes"
+	"orgchart_nexoan/api"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrgChart(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Snapshots", "minister", "9016-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Archives", "minister", "9016-01_tr_02", map[string]int{"minister": 1})
+	addCacheEntity(t, isolated, "Minister of Snapshots", "Department of Pictures", "department", "9016-01_tr_03", map[string]int{"department": 0})
+	_, err := isolated.AddPersonEntity(map[string]interface{}{
+		"parent":         "Minister of Snapshots",
+		"child":          "Snapshot Person",
+		"date":           "2020-01-01",
+		"parent_type":    "minister",
+		"child_type":     "citizen",
+		"rel_type":       "AS_APPOINTED",
+		"transaction_id": "9016-02_tr_01",
+		"president":      "Ranil Wickremesinghe",
+	}, map[string]int{"citizen": 1})
+	require.NoError(t, err)
+
+	// The department moves to the other minister a year later
+	require.NoError(t, isolated.MoveDepartment(map[string]interface{}{
+		"old_parent":         "Minister of Snapshots",
+		"new_parent":         "Minister of Archives",
+		"child":              "Department of Pictures",
+		"type":               "AS_DEPARTMENT",
+		"date":               "2021-01-01",
+		"transaction_id":     "9016-03_tr_01",
+		"new_president_name": "Ranil Wickremesinghe",
+	}))
+
+	chart, err := isolated.GetOrgChart("2020-06-01T00:00:00Z", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Government of Sri Lanka", chart.Government.Name)
+	require.Len(t, chart.Presidents, 1)
+	president := chart.Presidents[0]
+	assert.Equal(t, "Ranil Wickremesinghe", president.Name)
+	require.Len(t, president.Ministers, 2)
+
+	// Ministers are sorted by name
+	archives, snapshots := president.Ministers[0], president.Ministers[1]
+	assert.Equal(t, "Minister of Archives", archives.Name)
+	assert.Empty(t, archives.Departments)
+	assert.Equal(t, "Minister of Snapshots", snapshots.Name)
+	assert.Equal(t, "2020-01-01T00:00:00Z", snapshots.Since)
+	require.Len(t, snapshots.Departments, 1)
+	assert.Equal(t, "Department of Pictures", snapshots.Departments[0].Name)
+	require.Len(t, snapshots.People, 1)
+	assert.Equal(t, "Snapshot Person", snapshots.People[0].Name)
+
+	chart, err = isolated.GetOrgChart("2021-06-01T00:00:00Z", &api.SnapshotOptions{President: "Ranil Wickremesinghe", SkipPeople: true})
+	require.NoError(t, err)
+	archives, snapshots = chart.Presidents[0].Ministers[0], chart.Presidents[0].Ministers[1]
+	require.Len(t, archives.Departments, 1)
+	assert.Equal(t, "Department of Pictures", archives.Departments[0].Name)
+	assert.Equal(t, "2021-01-01T00:00:00Z", archives.Departments[0].Since)
+	assert.Empty(t, snapshots.Departments)
+	assert.Empty(t, snapshots.People)
+
+	var text bytes.Buffer
+	require.NoError(t, chart.WriteText(&text))
+	assert.Contains(t, text.String(), "Government of Sri Lanka on 2021-06-01\n")
+	assert.Contains(t, text.String(), "      Department: Department of Pictures [")
+
+	// Before the president took office there is nothing to show
+	chart, err = isolated.GetOrgChart("2019-01-01T00:00:00Z", nil)
+	require.NoError(t, err)
+	assert.Empty(t, chart.Presidents)
+	_, err = isolated.GetOrgChart("2019-01-01T00:00:00Z", &api.SnapshotOptions{President: "Ranil Wickremesinghe"})
+	assert.ErrorIs(t, err, api.ErrEntityNotFound)
+}
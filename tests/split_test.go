@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"fmt"
+	"orgchart_nexoan/api"
+	"orgchart_nexoan/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitMinister(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Transport and Highways", "minister", "9014-01_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Transport and Highways", "Department of Rails", "department", "9014-01_tr_02", map[string]int{"department": 0})
+	addCacheEntity(t, isolated, "Minister of Transport and Highways", "Department of Roads", "department", "9014-01_tr_03", map[string]int{"department": 1})
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9014-02_SPLIT.csv": "transaction_id,old,new,type,date,mapping\n" +
+			"9014-02_tr_01,Minister of Transport and Highways,[Minister of Transport;Minister of Highways],minister,2021-01-01," +
+			"[Department of Rails=Minister of Transport;Department of Roads=Minister of Highways]\n",
+	})
+	require.NoError(t, isolated.ProcessTransactions(dataDir, "organisation"))
+
+	old, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Transport and Highways", "2020-06-01T00:00:00Z")
+	require.NoError(t, err)
+	transport, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Transport", "")
+	require.NoError(t, err)
+	highways, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Highways", "")
+	require.NoError(t, err)
+	rails := departmentID(t, isolated, "Department of Rails")
+	roads := departmentID(t, isolated, "Department of Roads")
+
+	// Each department moves to its mapped successor
+	assert.Empty(t, relatedIDs(t, isolated, old.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2021-06-01T00:00:00Z"))
+	assert.Equal(t, []string{rails}, relatedIDs(t, isolated, transport.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2021-06-01T00:00:00Z"))
+	assert.Equal(t, []string{roads}, relatedIDs(t, isolated, highways.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2021-06-01T00:00:00Z"))
+
+	// The old minister is no longer active and points at its successors
+	_, err = isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Transport and Highways", "2021-06-01T00:00:00Z")
+	assert.Error(t, err)
+	assert.ElementsMatch(t, []string{transport.ID, highways.ID}, relatedIDs(t, isolated, old.ID, api.DirectionOutgoing, "SPLIT_INTO", ""))
+}
+
+func TestSplitDepartment(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Splits", "minister", "9014-03_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Splits", "Department of Land and Sea", "department", "9014-03_tr_02", map[string]int{"department": 0})
+	for i, name := range []string{"Land Person", "Staying Person"} {
+		_, err := isolated.AddPersonEntity(map[string]interface{}{
+			"parent":         "Department of Land and Sea",
+			"child":          name,
+			"date":           "2020-01-01",
+			"parent_type":    "department",
+			"child_type":     "citizen",
+			"rel_type":       "AS_APPOINTED",
+			"transaction_id": fmt.Sprintf("9014-04_tr_%02d", i+1),
+		}, map[string]int{"citizen": i})
+		require.NoError(t, err)
+	}
+
+	_, err := isolated.SplitDepartment(map[string]interface{}{
+		"transaction_id": "9014-05_tr_01",
+		"old":            "Department of Land and Sea",
+		"new":            "[Department of Land;Department of Sea]",
+		"date":           "2021-01-01",
+		"president":      "Ranil Wickremesinghe",
+		"mapping":        "[Land Person=Department of Land]",
+	}, map[string]int{"department": 1})
+	require.NoError(t, err)
+
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Splits", "")
+	require.NoError(t, err)
+	old := departmentID(t, isolated, "Department of Land and Sea")
+	land := departmentID(t, isolated, "Department of Land")
+	sea := departmentID(t, isolated, "Department of Sea")
+
+	assert.ElementsMatch(t, []string{land, sea}, relatedIDs(t, isolated, minister.ID, api.DirectionOutgoing, "AS_DEPARTMENT", "2021-06-01T00:00:00Z"))
+	assert.ElementsMatch(t, []string{land, sea}, relatedIDs(t, isolated, old, api.DirectionOutgoing, "SPLIT_INTO", ""))
+
+	// The mapped person moves, the appointment of the other one ends with the old department
+	assert.Len(t, relatedIDs(t, isolated, land, api.DirectionOutgoing, "AS_APPOINTED", ""), 1)
+	assert.Empty(t, relatedIDs(t, isolated, sea, api.DirectionOutgoing, "AS_APPOINTED", ""))
+	assert.Len(t, relatedIDs(t, isolated, old, api.DirectionOutgoing, "AS_APPOINTED", "2020-06-01T00:00:00Z"), 2)
+	assert.Empty(t, relatedIDs(t, isolated, old, api.DirectionOutgoing, "AS_APPOINTED", "2021-06-01T00:00:00Z"))
+}
+
+func TestSplitRejectsBadMapping(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	addCacheEntity(t, isolated, "Ranil Wickremesinghe", "Minister of Halves", "minister", "9014-06_tr_01", map[string]int{"minister": 0})
+	addCacheEntity(t, isolated, "Minister of Halves", "Department of Left", "department", "9014-06_tr_02", map[string]int{"department": 0})
+	addCacheEntity(t, isolated, "Minister of Halves", "Department of Right", "department", "9014-06_tr_03", map[string]int{"department": 1})
+
+	split := func(mapping string) error {
+		_, err := isolated.SplitMinister(map[string]interface{}{
+			"transaction_id": "9014-07_tr_01",
+			"old":            "Minister of Halves",
+			"new":            "[Minister of Left;Minister of Right]",
+			"date":           "2021-01-01",
+			"president":      "Ranil Wickremesinghe",
+			"mapping":        mapping,
+		}, map[string]int{"minister": 1})
+		return err
+	}
+
+	var invalid *api.InvalidTransactionError
+	// A department that is not mapped
+	require.ErrorAs(t, split("[Department of Left=Minister of Left]"), &invalid)
+	assert.Equal(t, "mapping", invalid.Field)
+	// A mapped name that is not held by the old minister
+	require.ErrorAs(t, split("[Department of Left=Minister of Left;Department of Right=Minister of Right;Department of Nowhere=Minister of Left]"), &invalid)
+	assert.Equal(t, "mapping", invalid.Field)
+	// A mapping to an entity that is not a successor
+	require.ErrorAs(t, split("[Department of Left=Minister of Left;Department of Right=Minister of Halves]"), &invalid)
+	assert.Equal(t, "mapping", invalid.Field)
+
+	// Nothing was written
+	results, err := isolated.SearchEntities(&models.SearchCriteria{
+		Kind: &models.Kind{Major: "Organisation", Minor: "minister"},
+		Name: "Minister of Left",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	minister, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Halves", "")
+	require.NoError(t, err)
+	assert.Len(t, relatedIDs(t, isolated, minister.ID, api.DirectionOutgoing, "AS_DEPARTMENT", ""), 2)
+}
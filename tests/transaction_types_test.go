@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"orgchart_nexoan/api"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeErrorsNameFileLineAndColumn(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+
+	// A MOVE file without its type column is reported, not skipped
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9012-01_MOVE.csv": "transaction_id,old_parent,new_parent,child,date\n" +
+			"9012-01_tr_01,Minister of A,Minister of B,Department of C,2020-01-01\n",
+	})
+	err := isolated.ProcessTransactions(dataDir, "organisation")
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "type", invalid.Field)
+	assert.Equal(t, "9012-01_MOVE.csv", filepath.Base(invalid.File))
+	assert.Equal(t, 2, invalid.Line)
+	assert.Equal(t, 0, invalid.Column)
+	assert.Contains(t, err.Error(), "9012-01_MOVE.csv:2: invalid transaction field 'type': column is missing")
+
+	// A bad date on the second row names its column
+	dataDir = writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9012-02_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9012-02_tr_01,Ranil Wickremesinghe,citizen,Minister of Decoding,minister,AS_MINISTER,2020-01-01\n" +
+			"9012-02_tr_02,Ranil Wickremesinghe,citizen,Minister of Dates,minister,AS_MINISTER,01/02/2020\n",
+	})
+	err = isolated.ProcessTransactions(dataDir, "organisation")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "date", invalid.Field)
+	assert.Equal(t, 3, invalid.Line)
+	assert.Equal(t, 7, invalid.Column)
+	assert.Contains(t, err.Error(), "9012-02_ADD.csv:3:7: invalid transaction field 'date'")
+}
+
+func TestDecodeAddTxAllowedValues(t *testing.T) {
+	row := func(childType, relType string) map[string]interface{} {
+		return map[string]interface{}{
+			"transaction_id": "9012-03_tr_01",
+			"parent":         "Minister of Values",
+			"parent_type":    "minister",
+			"child":          "Department of Values",
+			"child_type":     childType,
+			"rel_type":       relType,
+			"date":           "2020-01-01",
+			"president":      "Ranil Wickremesinghe",
+		}
+	}
+
+	tx, err := api.DecodeAddTx(row("department", "AS_DEPARTMENT"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), tx.Date)
+	assert.Equal(t, "Ranil Wickremesinghe", tx.President)
+
+	var invalid *api.InvalidTransactionError
+	_, err = api.DecodeAddTx(row("agency", "AS_AGENCY"))
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "child_type", invalid.Field)
+
+	_, err = api.DecodeAddTx(row("department", "AS_MINISTER"))
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "rel_type", invalid.Field)
+	// Transactions built in code have no position
+	assert.Empty(t, invalid.File)
+
+	merge, err := api.DecodeMergeTx(map[string]interface{}{
+		"transaction_id": "9012-03_tr_02",
+		"old":            "[Minister of A; Minister of B, C]",
+		"new":            "Minister of D",
+		"date":           "2020-01-01",
+		"president":      "Ranil Wickremesinghe",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Minister of A", "Minister of B, C"}, merge.Old)
+}
+
+func TestTypedClientMethods(t *testing.T) {
+	isolated, _ := newIsolatedClient(t)
+	ctx := context.Background()
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	counter, err := isolated.AddOrgEntityTx(ctx, &api.AddTx{
+		TransactionID: "9012-04_tr_01",
+		Parent:        "Ranil Wickremesinghe",
+		ParentType:    "citizen",
+		Child:         "Minister of Structs",
+		ChildType:     "minister",
+		RelType:       "AS_MINISTER",
+		Date:          date,
+	}, map[string]int{"minister": 0})
+	require.NoError(t, err)
+	assert.Equal(t, 1, counter)
+
+	_, err = isolated.AddOrgEntityTx(ctx, &api.AddTx{
+		TransactionID: "9012-04_tr_02",
+		Parent:        "Minister of Structs",
+		ParentType:    "minister",
+		Child:         "Department of Fields",
+		ChildType:     "department",
+		RelType:       "AS_DEPARTMENT",
+		Date:          date,
+		President:     "Ranil Wickremesinghe",
+	}, map[string]int{"department": 0})
+	require.NoError(t, err)
+
+	_, err = isolated.RenameMinisterTx(ctx, &api.RenameTx{
+		TransactionID: "9012-04_tr_03",
+		Old:           "Minister of Structs",
+		New:           "Minister of Typed Structs",
+		Date:          date.AddDate(0, 1, 0),
+		President:     "Ranil Wickremesinghe",
+	}, map[string]int{"minister": 1})
+	require.NoError(t, err)
+
+	renamed, err := isolated.GetActiveMinisterByPresident("Ranil Wickremesinghe", "Minister of Typed Structs", "")
+	require.NoError(t, err)
+	departments, err := isolated.GetAllRelatedEntities(renamed.ID, &api.RelationFilter{
+		Direction: api.DirectionOutgoing,
+		Names:     []string{"AS_DEPARTMENT"},
+		ActiveAt:  "2020-06-01T00:00:00Z",
+	})
+	require.NoError(t, err)
+	assert.Len(t, departments, 1)
+
+	// Typed methods validate their transaction too
+	err = isolated.MoveDepartmentTx(ctx, &api.MoveTx{
+		NewParent: "Minister of Typed Structs",
+		Child:     "Department of Fields",
+		Date:      date.AddDate(0, 2, 0),
+	})
+	var invalid *api.InvalidTransactionError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "new_president_name", invalid.Field)
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDataDir creates a data directory under orgchart/<president> holding the given CSV files
+func writeDataDir(t *testing.T, president string, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "orgchart", president, "2020-01-01")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestProcessTransactionsStopsWhenCancelled(t *testing.T) {
+	isolated, server := newIsolatedClient(t)
+
+	dataDir := writeDataDir(t, "Ranil Wickremesinghe", map[string]string{
+		"9001-01_ADD.csv": "transaction_id,parent,parent_type,child,child_type,rel_type,date\n" +
+			"9001-01_tr_01,Ranil Wickremesinghe,citizen,Minister of Cancellation,minister,AS_MINISTER,2020-01-01\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := isolated.ProcessTransactionsContext(ctx, dataDir, "organisation")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Contains(t, err.Error(), "9001-01_tr_01")
+
+	// Nothing beyond the seeded government and president was written
+	assert.Equal(t, 2, server.EntityCount())
+}
+
+func TestContextVariantsHonourCancellation(t *testing.T) {
+	client := newTestClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GetPresidentByGovernmentContext(ctx, "Ranil Wickremesinghe")
+	assert.True(t, errors.Is(err, context.Canceled))
+}